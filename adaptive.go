@@ -0,0 +1,34 @@
+package pantry
+
+import "time"
+
+// WithAdaptiveCleanup lets the background cleanup adjust its interval
+// between min and max after every pass, starting from the cleanup interval.
+// It sweeps twice as often while more than a quarter of the entries it looks
+// at have expired, half as often while nothing expires, and never so often
+// that sweeping takes more than a tenth of the time, which keeps large idle
+// pantries cheap and short TTLs prompt. The bounds must satisfy
+// 0 < min <= max.
+func WithAdaptiveCleanup[T any](min, max time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if min <= 0 || max < min {
+			pantry.invalidOption("adaptive cleanup bounds %v and %v must satisfy 0 < min <= max", min, max)
+			return
+		}
+		pantry.cleanupMin, pantry.cleanupMax = min, max
+	}
+}
+
+// nextCleanupInterval adapts the cleanup interval to a pass that removed
+// removed of stored+removed entries in took.
+func (pantry *Pantry[T]) nextCleanupInterval(current time.Duration, removed, stored int, took time.Duration) time.Duration {
+	next := current
+	switch {
+	case removed == 0:
+		next *= 2
+	case removed*4 > stored+removed:
+		next /= 2
+	}
+	next = max(next, 10*took)
+	return min(max(next, pantry.cleanupMin), pantry.cleanupMax)
+}
@@ -0,0 +1,49 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNextCleanupInterval(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithAdaptiveCleanup[int](time.Second, time.Minute))
+	defer p.Close()
+
+	tests := []struct {
+		name     string
+		current  time.Duration
+		removed  int
+		stored   int
+		took     time.Duration
+		expected time.Duration
+	}{
+		{"idle backs off", 4 * time.Second, 0, 1000, time.Millisecond, 8 * time.Second},
+		{"dense expiry speeds up", 4 * time.Second, 500, 500, time.Millisecond, 2 * time.Second},
+		{"sparse expiry keeps", 4 * time.Second, 10, 1000, time.Millisecond, 4 * time.Second},
+		{"bounded below", time.Second, 500, 500, time.Millisecond, time.Second},
+		{"bounded above", time.Minute, 0, 0, 0, time.Minute},
+		{"slow sweeps back off", 2 * time.Second, 500, 500, 500 * time.Millisecond, 5 * time.Second},
+	}
+
+	for _, test := range tests {
+		interval := p.nextCleanupInterval(test.current, test.removed, test.stored, test.took)
+		if interval != test.expected {
+			t.Errorf("%s: got %v, want %v", test.name, interval, test.expected)
+		}
+	}
+}
+
+func TestAdaptiveCleanup(t *testing.T) {
+	p := New(context.Background(), time.Hour,
+		WithCleanupInterval[int](time.Hour),
+		WithAdaptiveCleanup[int](time.Millisecond, 10*time.Millisecond),
+	)
+	defer p.Close()
+
+	p.SetWithTTL("key", 1, time.Millisecond)
+
+	// The interval is clamped to the bounds, so the cleanup runs long
+	// before the configured hour.
+	eventually(t, func() bool { return p.CountRaw() == 0 })
+}
@@ -0,0 +1,179 @@
+package pantry
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type persistQueue struct {
+	mutex   sync.Mutex
+	pending map[string]struct{}
+	// writing is the size of the batch being written, and highWater the
+	// largest depth seen.
+	writing   int
+	highWater int
+	wake      chan struct{}
+	// flushing serializes flushes, so a flush returns only once the batches
+	// queued before it are written.
+	flushing sync.Mutex
+	closed   bool
+	onError  atomic.Pointer[func(err error)]
+
+	attempts     int
+	backoff      time.Duration
+	onDeadLetter atomic.Pointer[func(err *PersistError)]
+}
+
+// WithAutoPersist makes a pantry created by NewPersistent or
+// NewPersistentWithStorage persist explicit writes and removals in the
+// background. Keys written while a batch is being stored are collected into
+// the next batch, so the hot path never waits for the storage.
+func WithAutoPersist[T any]() Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.autoPersist = true
+	}
+}
+
+// OnPersistError registers a hook called when a background persistence batch
+// fails.
+func (pantry *Pantry[T]) OnPersistError(fn func(err error)) {
+	if fn == nil {
+		pantry.persists.onError.Store(nil)
+		return
+	}
+	pantry.persists.onError.Store(&fn)
+}
+
+// Flush writes the queued write-behind writes to the backend and the queued
+// auto persisted keys to the storage, and waits until both are done.
+func (pantry *Pantry[T]) Flush() error {
+	var err error
+	if pantry.backend != nil {
+		err = pantry.flush()
+	}
+	if pantry.autoPersist && pantry.storage != nil {
+		err = errors.Join(err, pantry.flushPersists())
+	}
+	return err
+}
+
+func (pantry *Pantry[T]) startAutoPersist() {
+	pantry.persists.wake = make(chan struct{}, 1)
+
+	go func() {
+		for {
+			select {
+			case <-pantry.persists.wake:
+				if err := pantry.flushPersists(); err != nil {
+					pantry.persistError(err)
+				}
+			case <-pantry.done:
+				return
+			}
+		}
+	}()
+}
+
+func (pantry *Pantry[T]) persistError(err error) {
+	pantry.warn("pantry: persisting failed", err)
+	if onError := pantry.persists.onError.Load(); onError != nil {
+		(*onError)(err)
+	}
+}
+
+// PersistQueueDepth returns the number of keys waiting to be persisted by
+// WithAutoPersist, including the batch being written. A growing depth means
+// the storage cannot keep up.
+func (pantry *Pantry[T]) PersistQueueDepth() int {
+	pantry.persists.mutex.Lock()
+	defer pantry.persists.mutex.Unlock()
+
+	return len(pantry.persists.pending) + pantry.persists.writing
+}
+
+// PersistQueueHighWater returns the largest PersistQueueDepth seen so far.
+func (pantry *Pantry[T]) PersistQueueHighWater() int {
+	pantry.persists.mutex.Lock()
+	defer pantry.persists.mutex.Unlock()
+
+	return pantry.persists.highWater
+}
+
+// queuePersist must be called with the shard's write lock held.
+func (pantry *Pantry[T]) queuePersist(key string) {
+	if pantry.persists.wake == nil {
+		return
+	}
+
+	pantry.persists.mutex.Lock()
+	if pantry.persists.pending == nil {
+		pantry.persists.pending = make(map[string]struct{})
+	}
+	pantry.persists.pending[key] = struct{}{}
+	depth := len(pantry.persists.pending) + pantry.persists.writing
+	pantry.persists.highWater = max(pantry.persists.highWater, depth)
+	pantry.persists.mutex.Unlock()
+
+	select {
+	case pantry.persists.wake <- struct{}{}:
+	default:
+	}
+}
+
+// flushPersists stores the current state of the queued keys in one batch.
+func (pantry *Pantry[T]) flushPersists() error {
+	pantry.persists.flushing.Lock()
+	defer pantry.persists.flushing.Unlock()
+
+	pantry.persists.mutex.Lock()
+	pending := pantry.persists.pending
+	pantry.persists.pending = nil
+	pantry.persists.writing = len(pending)
+	pantry.persists.mutex.Unlock()
+
+	defer func() {
+		pantry.persists.mutex.Lock()
+		pantry.persists.writing = 0
+		pantry.persists.mutex.Unlock()
+	}()
+
+	if len(pending) == 0 || pantry.persists.closed {
+		return nil
+	}
+
+	var failures []*PersistError
+	batch := make(map[string][]byte, len(pending))
+	now := pantry.clock.Now().UnixNano()
+	for key := range pending {
+		item, found := pantry.lookup(key)
+		if !found || pantry.isExpired(key, item, now) {
+			batch[key] = nil
+			continue
+		}
+
+		data, err := pantry.encodePersisted(persisted(key, item))
+		if err != nil {
+			failures = append(failures, &PersistError{Keys: []string{key}, Err: err, Attempts: 1})
+			continue
+		}
+		batch[key] = data
+	}
+
+	if failure := pantry.writeStorage(batch); failure != nil {
+		failures = append(failures, failure)
+	}
+	return pantry.deadLetter(failures)
+}
+
+// closePersists flushes the queue and stops later flushes, so nothing is
+// written after the storage is closed.
+func (pantry *Pantry[T]) closePersists() error {
+	err := pantry.flushPersists()
+
+	pantry.persists.flushing.Lock()
+	pantry.persists.closed = true
+	pantry.persists.flushing.Unlock()
+	return err
+}
@@ -0,0 +1,224 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type failingStorage struct {
+	writes atomic.Int32
+}
+
+func (storage *failingStorage) Load(fn func(key string, data []byte) error) error {
+	return nil
+}
+
+func (storage *failingStorage) Write(batch map[string][]byte) error {
+	storage.writes.Add(1)
+	return errors.New("disk full")
+}
+
+func (storage *failingStorage) Close() error {
+	return nil
+}
+
+func TestAutoPersist(t *testing.T) {
+	dir := t.TempDir()
+
+	p, errs := NewPersistent(context.Background(), time.Hour, dir, WithAutoPersist[string]())
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	defer p.Close()
+
+	p.Set("first", "hello")
+	p.Set("second", "world")
+	p.Remove("second")
+
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, errs := NewPersistent[string](context.Background(), time.Hour, dir)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if value, found := restored.Get("first"); !found || value != "hello" {
+		t.Error("first was not persisted")
+	}
+	if _, found := restored.Get("second"); found {
+		t.Error("removed entry was persisted")
+	}
+}
+
+func TestAutoPersistError(t *testing.T) {
+	storage := &failingStorage{}
+	p, _ := NewPersistentWithStorage(context.Background(), time.Hour, storage, WithAutoPersist[string]())
+
+	failed := make(chan error, 1)
+	p.OnPersistError(func(err error) {
+		select {
+		case failed <- err:
+		default:
+		}
+	})
+
+	p.Set("first", "hello")
+
+	select {
+	case err := <-failed:
+		var failure *PersistError
+		if !errors.As(err, &failure) || failure.Err.Error() != "disk full" || !failure.Transient {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("error hook not called")
+	}
+}
+
+func TestCloseStopsAutoPersist(t *testing.T) {
+	storage := &failingStorage{}
+	p, _ := NewPersistentWithStorage(context.Background(), time.Hour, storage, WithAutoPersist[string]())
+
+	p.Close()
+	writes := storage.writes.Load()
+
+	p.Remove("first")
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if storage.writes.Load() != writes {
+		t.Error("wrote to the storage after Close")
+	}
+}
+
+type flakyStorage struct {
+	failures atomic.Int32
+	err      error
+	writes   atomic.Int32
+}
+
+func (storage *flakyStorage) Load(fn func(key string, data []byte) error) error {
+	return nil
+}
+
+func (storage *flakyStorage) Write(batch map[string][]byte) error {
+	storage.writes.Add(1)
+	if storage.failures.Add(-1) >= 0 {
+		return storage.err
+	}
+	return nil
+}
+
+func (storage *flakyStorage) Close() error {
+	return nil
+}
+
+func TestPersistRetry(t *testing.T) {
+	storage := &flakyStorage{err: errors.New("timeout")}
+	storage.failures.Store(2)
+
+	p, _ := NewPersistentWithStorage(context.Background(), time.Hour, storage, WithPersistRetry[string](3, time.Millisecond))
+	defer p.Close()
+
+	p.Set("key", "value")
+	if err := p.Persist("key"); err != nil {
+		t.Fatal(err)
+	}
+	if writes := storage.writes.Load(); writes != 3 {
+		t.Fatal("unexpected writes", writes)
+	}
+
+	storage.failures.Store(5)
+	var failure *PersistError
+	if err := p.Persist("key"); !errors.As(err, &failure) || failure.Attempts != 3 || !failure.Transient {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestPersistPermanentError(t *testing.T) {
+	storage := &flakyStorage{err: Permanent(errors.New("read-only"))}
+	storage.failures.Store(5)
+
+	p, _ := NewPersistentWithStorage(context.Background(), time.Hour, storage,
+		WithAutoPersist[string](),
+		WithPersistRetry[string](3, time.Millisecond),
+	)
+	defer p.Close()
+
+	dead := make(chan *PersistError, 1)
+	p.OnPersistDeadLetter(func(err *PersistError) {
+		select {
+		case dead <- err:
+		default:
+		}
+	})
+
+	p.Set("key", "value")
+
+	select {
+	case failure := <-dead:
+		if failure.Transient || failure.Attempts != 1 || len(failure.Keys) != 1 || failure.Keys[0] != "key" {
+			t.Fatal("unexpected dead letter", failure)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("dead letter hook not called")
+	}
+}
+
+// blockingStorage holds every write until released, signalling on writing
+// while it does. Once released, writes go through without a signal.
+type blockingStorage struct {
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (storage *blockingStorage) Load(fn func(key string, data []byte) error) error {
+	return nil
+}
+
+func (storage *blockingStorage) Write(batch map[string][]byte) error {
+	select {
+	case storage.writing <- struct{}{}:
+		<-storage.release
+	case <-storage.release:
+	}
+	return nil
+}
+
+func (storage *blockingStorage) Close() error {
+	return nil
+}
+
+func TestPersistQueueDepth(t *testing.T) {
+	storage := &blockingStorage{writing: make(chan struct{}), release: make(chan struct{})}
+	p, _ := NewPersistentWithStorage(context.Background(), time.Hour, storage, WithAutoPersist[int]())
+
+	p.Set("first", 1)
+	<-storage.writing
+
+	// The first batch is stuck in the storage while more keys queue up.
+	p.Set("second", 2)
+	p.Set("third", 3)
+	p.Set("third", 4)
+
+	if depth := p.PersistQueueDepth(); depth != 3 {
+		t.Fatal("unexpected depth", depth)
+	}
+
+	close(storage.release)
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if depth := p.PersistQueueDepth(); depth != 0 {
+		t.Fatal("queue not drained", depth)
+	}
+	if high := p.PersistQueueHighWater(); high != 3 {
+		t.Fatal("unexpected high-water mark", high)
+	}
+	p.Close()
+}
@@ -0,0 +1,156 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Backend is the store a pantry caches when configured with WithWriteThrough
+// or WithWriteBehind.
+type Backend[T any] interface {
+	Load(key string) (T, bool, error)
+	Store(key string, value T) error
+	Delete(key string) error
+}
+
+type pendingWrite[T any] struct {
+	value   T
+	deleted bool
+}
+
+type writeQueue[T any] struct {
+	mutex   sync.Mutex
+	pending map[string]pendingWrite[T]
+}
+
+// OnBackendError registers a hook called when loading from, writing to or
+// deleting from the backend fails.
+func (pantry *Pantry[T]) OnBackendError(fn func(key string, err error)) {
+	if fn == nil {
+		pantry.onBackendError.Store(nil)
+		return
+	}
+	pantry.onBackendError.Store(&fn)
+}
+
+func (pantry *Pantry[T]) backendError(key string, err error) {
+	pantry.warn("pantry: backend failed", err, "key", key)
+	if onBackendError := pantry.onBackendError.Load(); onBackendError != nil {
+		(*onBackendError)(key, err)
+	}
+}
+
+// writeBack is called for every explicit write and removal, and must be
+// called with the shard's write lock held. The change is queued for the
+// replicas and the auto persistence first. Write-behind writes are queued
+// right away so the latest one wins, write-through ones and removals after
+// Close are run with the notifications once the lock is released.
+func (pantry *Pantry[T]) writeBack(evictions []eviction[T], key string, value T, deleted bool) []eviction[T] {
+	pantry.replicate(key, value, deleted)
+	pantry.queuePersist(key)
+
+	if pantry.backend == nil {
+		return evictions
+	}
+
+	if pantry.writeBehind > 0 && !pantry.closed.Load() {
+		pantry.writes.mutex.Lock()
+		if pantry.writes.pending == nil {
+			pantry.writes.pending = make(map[string]pendingWrite[T])
+		}
+		pantry.writes.pending[key] = pendingWrite[T]{value: value, deleted: deleted}
+		pantry.writes.mutex.Unlock()
+		return evictions
+	}
+
+	return append(evictions, eviction[T]{
+		callback: func(key string, value T, _ EvictionReason) {
+			if err := pantry.write(key, pendingWrite[T]{value: value, deleted: deleted}); err != nil {
+				pantry.backendError(key, err)
+			}
+		},
+		key:   key,
+		value: value,
+	})
+}
+
+func (pantry *Pantry[T]) write(key string, write pendingWrite[T]) error {
+	if write.deleted {
+		return pantry.backend.Delete(key)
+	}
+	return pantry.backend.Store(key, write.value)
+}
+
+// flush writes the queued write-behind writes to the backend.
+func (pantry *Pantry[T]) flush() error {
+	pantry.writes.mutex.Lock()
+	pending := pantry.writes.pending
+	pantry.writes.pending = nil
+	pantry.writes.mutex.Unlock()
+
+	var errs []error
+	for key, write := range pending {
+		if err := pantry.write(key, write); err != nil {
+			pantry.backendError(key, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadFromBackend fills a miss from the backend. Concurrent misses for the
+// same key share a single backend load, and those waiting for it give up
+// with ctx.Err() once ctx is done.
+func (pantry *Pantry[T]) loadFromBackend(ctx context.Context, canonical, key string) (item[T], bool, error) {
+	pantry.loads.mutex.Lock()
+	if pending, found := pantry.loads.calls[key]; found {
+		pantry.loads.mutex.Unlock()
+		pantry.counters.coalesced.Add(1)
+		select {
+		case <-pending.done:
+			return pending.item, pending.found, nil
+		case <-ctx.Done():
+			return item[T]{}, false, ctx.Err()
+		}
+	}
+
+	pending := &load[T]{done: make(chan struct{})}
+	if pantry.loads.calls == nil {
+		pantry.loads.calls = make(map[string]*load[T])
+	}
+	pantry.loads.calls[key] = pending
+	pantry.loads.mutex.Unlock()
+
+	defer func() {
+		pantry.loads.mutex.Lock()
+		delete(pantry.loads.calls, key)
+		pantry.loads.mutex.Unlock()
+		close(pending.done)
+	}()
+
+	value, found, err := pantry.backend.Load(key)
+	if err != nil {
+		pantry.backendError(key, err)
+		return item[T]{}, false, nil
+	}
+	if !found {
+		pantry.cacheMiss(key)
+		return item[T]{}, false, nil
+	}
+
+	pending.item, _ = pantry.getOrSet(canonical, key, value, false)
+	pending.found = true
+	return pending.item, true, nil
+}
+
+type load[T any] struct {
+	done  chan struct{}
+	item  item[T]
+	found bool
+}
+
+type loads[T any] struct {
+	mutex sync.Mutex
+	calls map[string]*load[T]
+}
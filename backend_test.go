@@ -0,0 +1,168 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type mapBackend struct {
+	mutex  sync.Mutex
+	values map[string]string
+	loads  atomic.Int32
+	err    error
+}
+
+func newMapBackend() *mapBackend {
+	return &mapBackend{values: make(map[string]string)}
+}
+
+func (backend *mapBackend) Load(key string) (string, bool, error) {
+	backend.loads.Add(1)
+
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	if backend.err != nil {
+		return "", false, backend.err
+	}
+	value, found := backend.values[key]
+	return value, found, nil
+}
+
+func (backend *mapBackend) Store(key string, value string) error {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	if backend.err != nil {
+		return backend.err
+	}
+	backend.values[key] = value
+	return nil
+}
+
+func (backend *mapBackend) Delete(key string) error {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	if backend.err != nil {
+		return backend.err
+	}
+	delete(backend.values, key)
+	return nil
+}
+
+func (backend *mapBackend) get(key string) (string, bool) {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	value, found := backend.values[key]
+	return value, found
+}
+
+func TestWriteThrough(t *testing.T) {
+	backend := newMapBackend()
+	backend.values["stored"] = "from backend"
+
+	p := New(context.Background(), time.Hour, WithWriteThrough[string](backend))
+	defer p.Close()
+
+	if value, found := p.Get("stored"); !found || value != "from backend" {
+		t.Fatal("not loaded", value)
+	}
+
+	p.Get("stored")
+	if backend.loads.Load() != 1 {
+		t.Fatal("loaded again")
+	}
+
+	p.Set("first", "hello")
+	if value, _ := backend.get("first"); value != "hello" {
+		t.Fatal("not written through")
+	}
+
+	p.Remove("first")
+	if _, found := backend.get("first"); found {
+		t.Fatal("not deleted")
+	}
+
+	p.Clear()
+	if _, found := backend.get("stored"); !found {
+		t.Fatal("clear reached the backend")
+	}
+
+	if _, found := p.Get("missing"); found {
+		t.Fatal("found missing")
+	}
+}
+
+func TestWriteBehind(t *testing.T) {
+	backend := newMapBackend()
+
+	p := New(context.Background(), time.Hour, WithWriteBehind[string](backend, time.Hour))
+
+	p.Set("first", "hello")
+	p.Set("first", "world")
+	p.Set("second", "gone")
+	p.Remove("second")
+
+	if _, found := backend.get("first"); found {
+		t.Fatal("written before flush")
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := backend.get("first"); value != "world" {
+		t.Fatal("not flushed", value)
+	}
+
+	if _, found := backend.get("second"); found {
+		t.Fatal("deleted entry flushed")
+	}
+}
+
+func TestBackendError(t *testing.T) {
+	backend := newMapBackend()
+	backend.err = errors.New("unavailable")
+
+	p := New(context.Background(), time.Hour, WithWriteThrough[string](backend))
+	defer p.Close()
+
+	var failures atomic.Int32
+	p.OnBackendError(func(key string, err error) {
+		failures.Add(1)
+	})
+
+	if _, found := p.Get("first"); found {
+		t.Fatal("found")
+	}
+
+	p.Set("first", "hello")
+
+	if failures.Load() != 2 {
+		t.Fatal("unexpected failures", failures.Load())
+	}
+
+	if value, _ := p.Get("first"); value != "hello" {
+		t.Fatal("not cached")
+	}
+}
+
+func TestPopMissingKeepsBackend(t *testing.T) {
+	backend := newMapBackend()
+	backend.values["key"] = "value"
+	p := New(context.Background(), time.Hour, WithWriteThrough[string](backend))
+	defer p.Close()
+
+	if _, found := p.Pop("key"); found {
+		t.Fatal("popped a key missing from the cache")
+	}
+	if _, found := backend.get("key"); !found {
+		t.Fatal("popping a missing key deleted it from the backend")
+	}
+}
@@ -0,0 +1,98 @@
+package pantry
+
+// groupByShard groups normalized keys by the shard they live in.
+func (pantry *Pantry[T]) groupByShard(keys []string) map[*shard[T]][]string {
+	groups := make(map[*shard[T]][]string)
+	for _, key := range keys {
+		shard := pantry.shardFor(key)
+		groups[shard] = append(groups[shard], key)
+	}
+	return groups
+}
+
+// SetMany stores all entries with the default expiration, locking each
+// shard only once.
+func (pantry *Pantry[T]) SetMany(entries map[string]T) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	keys := make([]string, 0, len(entries))
+	values := make(map[string]T, len(entries))
+	canonicals := make(map[string]string, len(entries))
+	for key, value := range entries {
+		canonical := pantry.canonical(key)
+		key = pantry.hash(canonical)
+		keys = append(keys, key)
+		values[key] = value
+		canonicals[key] = canonical
+	}
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	now := pantry.clock.Now()
+	for shard, keys := range pantry.groupByShard(keys) {
+		shard.mutex.Lock()
+		for _, key := range keys {
+			stored := pantry.newItem(canonicals[key], values[key], pantry.expiresAt(now, pantry.defaultTTL(key, values[key])))
+			evictions = pantry.put(evictions, shard, key, stored, now.UnixNano())
+			evictions = pantry.writeBack(evictions, key, values[key], false)
+		}
+		shard.mutex.Unlock()
+	}
+}
+
+// GetMany returns the live values of the requested keys, locking each shard
+// only once. Missing keys are absent from the result.
+func (pantry *Pantry[T]) GetMany(keys []string) map[string]T {
+	originals := make(map[string][]string, len(keys))
+	normalized := make([]string, 0, len(keys))
+	for _, key := range keys {
+		normalizedKey := pantry.normalize(key)
+		if _, found := originals[normalizedKey]; !found {
+			normalized = append(normalized, normalizedKey)
+		}
+		originals[normalizedKey] = append(originals[normalizedKey], key)
+	}
+
+	values := make(map[string]T, len(keys))
+	for shard, keys := range pantry.groupByShard(normalized) {
+		pantry.lockForRead(shard)
+		for _, key := range keys {
+			if item, found := pantry.read(shard, key); found {
+				for _, original := range originals[key] {
+					values[original] = pantry.clone(item.value)
+				}
+			}
+		}
+		pantry.unlockForRead(shard)
+	}
+	return values
+}
+
+// RemoveMany removes all keys, locking each shard only once.
+func (pantry *Pantry[T]) RemoveMany(keys []string) {
+	normalized := make([]string, len(keys))
+	for i, key := range keys {
+		normalized[i] = pantry.normalize(key)
+	}
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	now := pantry.clock.Now().UnixNano()
+	for shard, keys := range pantry.groupByShard(normalized) {
+		shard.mutex.Lock()
+		for _, key := range keys {
+			if item, found := shard.store[key]; found {
+				pantry.drop(shard, key)
+				evictions = pantry.evict(evictions, key, item, now, Removed)
+			}
+			evictions = pantry.writeBack(evictions, key, *new(T), true)
+		}
+		shard.mutex.Unlock()
+	}
+}
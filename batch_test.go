@@ -0,0 +1,77 @@
+package pantry
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSetMany(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	entries := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		entries[strconv.Itoa(i)] = i
+	}
+
+	p.SetMany(entries)
+
+	for key, expected := range entries {
+		if value, found := p.Get(key); !found || value != expected {
+			t.Fatal("not found")
+		}
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.SetWithTTL("expired", 3, time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+
+	values := p.GetMany([]string{"first", "second", "expired", "missing"})
+	if len(values) != 2 || values["first"] != 1 || values["second"] != 2 {
+		t.Log(values)
+		t.Fatal("wrong values")
+	}
+}
+
+func TestRemoveMany(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Set("third", 3)
+
+	p.RemoveMany([]string{"first", "second", "missing"})
+
+	if _, found := p.Get("first"); found {
+		t.Fatal("first found")
+	}
+
+	if _, found := p.Get("second"); found {
+		t.Fatal("second found")
+	}
+
+	if _, found := p.Get("third"); !found {
+		t.Fatal("third not found")
+	}
+}
+
+func BenchmarkSetMany(b *testing.B) {
+	p := New[int](context.Background(), time.Hour)
+
+	entries := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		entries[strconv.Itoa(i)] = i
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.SetMany(entries)
+	}
+}
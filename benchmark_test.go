@@ -0,0 +1,248 @@
+package pantry
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var benchmarkSizes = []int{1_000, 100_000, 1_000_000}
+
+var benchmarkWorkloads = []struct {
+	name   string
+	writes int // percentage of operations that write
+}{
+	{"read-heavy", 10},
+	{"mixed", 50},
+	{"write-heavy", 90},
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func benchmarkPantry(b *testing.B, keys []string) *Pantry[int] {
+	b.Helper()
+
+	p := New[int](context.Background(), time.Hour)
+	b.Cleanup(func() { p.Close() })
+
+	for i, key := range keys {
+		p.Set(key, i)
+	}
+	return p
+}
+
+func BenchmarkGet(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run("size="+strconv.Itoa(size), func(b *testing.B) {
+			keys := benchmarkKeys(size)
+			p := benchmarkPantry(b, keys)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					p.Get(keys[rand.IntN(len(keys))])
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run("size="+strconv.Itoa(size), func(b *testing.B) {
+			keys := benchmarkKeys(size)
+			p := benchmarkPantry(b, keys)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					p.Set(keys[rand.IntN(len(keys))], i)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkWorkload(b *testing.B) {
+	for _, workload := range benchmarkWorkloads {
+		for _, size := range benchmarkSizes {
+			b.Run(workload.name+"/size="+strconv.Itoa(size), func(b *testing.B) {
+				keys := benchmarkKeys(size)
+				p := benchmarkPantry(b, keys)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for i := 0; pb.Next(); i++ {
+						key := keys[rand.IntN(len(keys))]
+						if rand.IntN(100) < workload.writes {
+							p.Set(key, i)
+						} else {
+							p.Get(key)
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkGetExpired reads entries that expired but were not cleaned up.
+// By default the first Get takes the write lock to delete each one, after
+// which reads are plain misses; WithNoLazyExpiry keeps serving them under the
+// read lock and leaves them in memory.
+func BenchmarkGetExpired(b *testing.B) {
+	modes := []struct {
+		name    string
+		options []Option[int]
+	}{
+		{"lazy-deletion", nil},
+		{"no-lazy-expiry", []Option[int]{WithNoLazyExpiry[int]()}},
+	}
+
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			keys := benchmarkKeys(100_000)
+			p := New(context.Background(), time.Hour, append(mode.options, WithoutBackgroundCleanup[int]())...)
+			b.Cleanup(func() { p.Close() })
+
+			for i, key := range keys {
+				p.SetWithTTL(key, i, time.Millisecond)
+			}
+			time.Sleep(2 * time.Millisecond)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					p.Get(keys[rand.IntN(len(keys))])
+				}
+			})
+			b.StopTimer()
+
+			b.ReportMetric(float64(p.CountRaw()), "stored")
+		})
+	}
+}
+
+// BenchmarkReadOptimizedStore compares the default store with
+// WithReadOptimizedStore at growing write ratios. The published copies pay
+// off when reads dominate and cost a copy of the shard per write burst
+// otherwise.
+func BenchmarkReadOptimizedStore(b *testing.B) {
+	modes := []struct {
+		name    string
+		options []Option[int]
+	}{
+		{"default", nil},
+		{"read-optimized", []Option[int]{WithReadOptimizedStore[int]()}},
+	}
+
+	for _, mode := range modes {
+		for _, writes := range []int{0, 1, 10, 50} {
+			b.Run(mode.name+"/writes="+strconv.Itoa(writes)+"%", func(b *testing.B) {
+				keys := benchmarkKeys(100_000)
+				p := New(context.Background(), time.Hour, mode.options...)
+				b.Cleanup(func() { p.Close() })
+
+				for i, key := range keys {
+					p.Set(key, i)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for i := 0; pb.Next(); i++ {
+						key := keys[rand.IntN(len(keys))]
+						if rand.IntN(100) < writes {
+							p.Set(key, i)
+						} else {
+							p.Get(key)
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkHotPath runs the single-key operations one at a time to report
+// their allocations, which should stay at zero.
+func BenchmarkHotPath(b *testing.B) {
+	operations := []struct {
+		name string
+		run  func(p *Pantry[int], key string, i int)
+	}{
+		{"get", func(p *Pantry[int], key string, _ int) { p.Get(key) }},
+		{"get-miss", func(p *Pantry[int], _ string, _ int) { p.Get("missing") }},
+		{"contains", func(p *Pantry[int], key string, _ int) { p.Contains(key) }},
+		{"set", func(p *Pantry[int], key string, i int) { p.Set(key, i) }},
+	}
+
+	for _, operation := range operations {
+		b.Run(operation.name, func(b *testing.B) {
+			keys := benchmarkKeys(1_000)
+			p := benchmarkPantry(b, keys)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				operation.run(p, keys[i%len(keys)], i)
+			}
+		})
+	}
+}
+
+// BenchmarkAll reports the cost of iterating, which copies the live entries
+// of every shard before yielding them.
+func BenchmarkAll(b *testing.B) {
+	p := benchmarkPantry(b, benchmarkKeys(1_000))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		for range p.All() {
+		}
+	}
+}
+
+func BenchmarkGetPolicy(b *testing.B) {
+	policies := []struct {
+		name   string
+		policy EvictionPolicy
+	}{
+		{"lru", PolicyLRU},
+		{"lfu", PolicyLFU},
+		{"clock", PolicyClock},
+		{"random", PolicyRandom},
+	}
+
+	keys := benchmarkKeys(10_000)
+	for _, policy := range policies {
+		b.Run(policy.name, func(b *testing.B) {
+			p := New(context.Background(), time.Hour, WithMaxItems[int](len(keys)), WithEvictionPolicy[int](policy.policy))
+			b.Cleanup(func() { p.Close() })
+			for i, key := range keys {
+				p.Set(key, i)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					p.Get(keys[rand.IntN(len(keys))])
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+)
+
+// ErrChangesTruncated is yielded by CDC when the changes following the
+// requested sequence number are no longer retained, or the number is ahead
+// of the log, e.g. because the pantry was restarted. The consumer has to
+// resync from a copy of the entries.
+var ErrChangesTruncated = errors.New("pantry: changes no longer retained")
+
+var errNoChangeLog = errors.New("pantry: CDC needs WithChangeLog")
+
+// ChangeEvent is an event of the change log with its sequence number. The
+// numbers start at 1 and increase by one with every change.
+type ChangeEvent[T any] struct {
+	Seq uint64
+	Event[T]
+}
+
+// changeLog keeps the latest changes in a ring buffer. The position of a
+// change follows from its sequence number.
+type changeLog[T any] struct {
+	mutex  sync.Mutex
+	events []ChangeEvent[T]
+	count  int
+	last   uint64
+	wake   chan struct{}
+}
+
+// WithChangeLog records every change, i.e. the events delivered by
+// Subscribe, in a log of the latest capacity changes that CDC streams from.
+// Changes are numbered while the shard is locked, so the log orders the
+// changes of a key as they happened. The capacity must be positive.
+func WithChangeLog[T any](capacity int) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if capacity <= 0 {
+			pantry.invalidOption("change log capacity %d must be positive", capacity)
+			return
+		}
+		pantry.changes = &changeLog[T]{events: make([]ChangeEvent[T], capacity)}
+	}
+}
+
+// LastChange returns the sequence number of the latest change, 0 if there
+// was none. To mirror the pantry, read LastChange, copy the entries with All
+// and then apply CDC from that number. Changes racing with the copy are
+// applied again, in order, so the mirror converges.
+func (pantry *Pantry[T]) LastChange() uint64 {
+	if pantry.changes == nil {
+		return 0
+	}
+
+	pantry.changes.mutex.Lock()
+	defer pantry.changes.mutex.Unlock()
+
+	return pantry.changes.last
+}
+
+// CDC streams the changes with a sequence number after the given one in
+// order, waiting for new changes until ctx is done or the pantry is closed.
+// Resuming with the number of the last applied change continues without gaps
+// as long as the log still retains it; otherwise ErrChangesTruncated is
+// yielded and the stream ends. Expired entries are streamed when they are
+// removed, lazily or by the cleanup, and Clear streams a removal per key
+// followed by EventCleared.
+func (pantry *Pantry[T]) CDC(ctx context.Context, after uint64) iter.Seq2[ChangeEvent[T], error] {
+	return func(yield func(ChangeEvent[T], error) bool) {
+		if pantry.changes == nil {
+			yield(ChangeEvent[T]{}, errNoChangeLog)
+			return
+		}
+
+		for {
+			events, wake, err := pantry.changes.since(after)
+			if err != nil {
+				yield(ChangeEvent[T]{}, err)
+				return
+			}
+
+			for _, event := range events {
+				event.Value = pantry.clone(event.Value)
+				if !yield(event, nil) {
+					return
+				}
+				after = event.Seq
+			}
+			if wake == nil {
+				continue
+			}
+
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return
+			case <-pantry.done:
+				return
+			}
+		}
+	}
+}
+
+// record must be called with the lock of the key's shard held, so the
+// changes of a key are numbered in order.
+func (changes *changeLog[T]) record(event Event[T]) {
+	changes.mutex.Lock()
+	defer changes.mutex.Unlock()
+
+	changes.last++
+	changes.events[(changes.last-1)%uint64(len(changes.events))] = ChangeEvent[T]{Seq: changes.last, Event: event}
+	changes.count = min(changes.count+1, len(changes.events))
+
+	if changes.wake != nil {
+		close(changes.wake)
+		changes.wake = nil
+	}
+}
+
+// since returns the retained changes after the given sequence number, or a
+// channel closed on the next change if there are none yet.
+func (changes *changeLog[T]) since(after uint64) ([]ChangeEvent[T], <-chan struct{}, error) {
+	changes.mutex.Lock()
+	defer changes.mutex.Unlock()
+
+	if after < changes.last-uint64(changes.count) || after > changes.last {
+		return nil, nil, ErrChangesTruncated
+	}
+
+	if after == changes.last {
+		if changes.wake == nil {
+			changes.wake = make(chan struct{})
+		}
+		return nil, changes.wake, nil
+	}
+
+	events := make([]ChangeEvent[T], 0, changes.last-after)
+	for seq := after + 1; seq <= changes.last; seq++ {
+		events = append(events, changes.events[(seq-1)%uint64(len(changes.events))])
+	}
+	return events, nil, nil
+}
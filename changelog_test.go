@@ -0,0 +1,125 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCDC(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithChangeLog[string](16))
+	defer p.Close()
+
+	p.Set("first", "1")
+	p.Set("second", "2")
+	p.Remove("first")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var received []ChangeEvent[string]
+	for event, err := range p.CDC(ctx, 0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, event)
+		if len(received) == 3 {
+			break
+		}
+	}
+
+	expected := []struct {
+		kind EventKind
+		key  string
+	}{{EventSet, "first"}, {EventSet, "second"}, {EventRemoved, "first"}}
+	for i, event := range received {
+		if event.Seq != uint64(i+1) || event.Kind != expected[i].kind || event.Key != expected[i].key {
+			t.Fatal("unexpected change", i, event)
+		}
+	}
+
+	// Resuming waits for the next change.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.Set("third", "3")
+	}()
+	for event, err := range p.CDC(ctx, p.LastChange()) {
+		if err != nil || event.Seq != 4 || event.Key != "third" || event.Value != "3" {
+			t.Fatal("unexpected change", event, err)
+		}
+		break
+	}
+
+	cancel()
+	for range p.CDC(ctx, p.LastChange()) {
+		t.Fatal("change after cancellation")
+	}
+}
+
+func TestCDCTruncated(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithChangeLog[int](2))
+	defer p.Close()
+
+	for i := range 5 {
+		p.Set("key", i)
+	}
+
+	for _, after := range []uint64{1, 10} {
+		for _, err := range p.CDC(context.Background(), after) {
+			if !errors.Is(err, ErrChangesTruncated) {
+				t.Fatal("unexpected error", after, err)
+			}
+		}
+	}
+
+	for event, err := range p.CDC(context.Background(), 3) {
+		if err != nil || event.Seq != 4 || event.Value != 3 {
+			t.Fatal("unexpected change", event, err)
+		}
+		break
+	}
+
+	for _, err := range New[int](context.Background(), time.Hour).CDC(context.Background(), 0) {
+		if err == nil {
+			t.Fatal("streamed without a change log")
+		}
+	}
+}
+
+func TestCDCMirror(t *testing.T) {
+	source := New(context.Background(), time.Hour, WithChangeLog[string](1024))
+	defer source.Close()
+	mirror := New[string](context.Background(), time.Hour)
+
+	source.Set("existing", "value")
+	seq := source.LastChange()
+	for key, value := range source.All() {
+		mirror.Set(key, value)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event, err := range source.CDC(ctx, seq) {
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			switch event.Kind {
+			case EventSet:
+				mirror.Set(event.Key, event.Value)
+			case EventRemoved, EventExpired:
+				mirror.Remove(event.Key)
+			}
+		}
+	}()
+
+	source.Set("added", "value")
+	source.Remove("existing")
+	eventually(t, func() bool { return mirror.Contains("added") && !mirror.Contains("existing") })
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,50 @@
+package pantry
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// WithChecksum keeps a running checksum of the stored entries, read with
+// RunningChecksum, for cheap divergence detection between replicas. The
+// fingerprint of an entry combines the hash of its key with fingerprint of
+// its value, which must return the same result for equal values on every
+// replica. Fingerprints are combined by addition, so the checksum does not
+// depend on the order of the writes.
+func WithChecksum[T any](fingerprint func(value T) uint64) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.fingerprintFn = fingerprint
+	}
+}
+
+// RunningChecksum returns the checksum kept up to date by WithChecksum on
+// every write and removal, zero without it. Reading it is O(1). It covers
+// every stored entry, including expired ones not removed yet, so replicas
+// only agree once both have removed the same expired entries.
+func (pantry *Pantry[T]) RunningChecksum() uint64 {
+	return pantry.checksum.Load()
+}
+
+// Checksum computes the checksum of the stored entries from scratch, the
+// same value RunningChecksum returns, zero without WithChecksum.
+func (pantry *Pantry[T]) Checksum() uint64 {
+	if pantry.fingerprintFn == nil {
+		return 0
+	}
+
+	pantry.rlockAll()
+	defer pantry.runlockAll()
+
+	var checksum uint64
+	for key, item := range pantry.items() {
+		checksum += pantry.fingerprint(key, item.value)
+	}
+	return checksum
+}
+
+func (pantry *Pantry[T]) fingerprint(key string, value T) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	hash.Write(binary.LittleEndian.AppendUint64(nil, pantry.fingerprintFn(value)))
+	return hash.Sum64()
+}
@@ -0,0 +1,59 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunningChecksum(t *testing.T) {
+	fingerprint := func(value int) uint64 { return uint64(value) }
+	p := New(context.Background(), time.Hour, WithChecksum(fingerprint), WithoutBackgroundCleanup[int]())
+	defer p.Close()
+
+	if p.RunningChecksum() != 0 || p.Checksum() != 0 {
+		t.Fatal("empty pantry has a checksum")
+	}
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Set("third", 3)
+	p.Set("second", 20)
+	p.Remove("third")
+	p.SetWithTTL("expiring", 4, time.Millisecond)
+	p.Update("first", func(old int, _ bool) (int, bool) { return old + 10, true })
+
+	if running, full := p.RunningChecksum(), p.Checksum(); running != full {
+		t.Fatal("running checksum diverged", running, full)
+	}
+
+	// The same entries written in another order give the same checksum.
+	other := New(context.Background(), time.Hour, WithChecksum(fingerprint))
+	defer other.Close()
+	other.Set("expiring", 4)
+	other.Set("second", 20)
+	other.Set("first", 11)
+	if p.RunningChecksum() != other.RunningChecksum() {
+		t.Fatal("checksum depends on the order")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	before := p.RunningChecksum()
+	p.removeExpired()
+	if running, full := p.RunningChecksum(), p.Checksum(); running == before || running != full {
+		t.Fatal("expiration not reflected", before, running, full)
+	}
+
+	// Moving a value to another key changes the checksum.
+	before = p.RunningChecksum()
+	p.Remove("first")
+	p.Set("moved", 11)
+	if p.RunningChecksum() == before {
+		t.Fatal("checksum ignores the keys")
+	}
+
+	p.Clear()
+	if p.RunningChecksum() != 0 {
+		t.Fatal("checksum not reset", p.RunningChecksum())
+	}
+}
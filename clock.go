@@ -0,0 +1,37 @@
+package pantry
+
+import "time"
+
+// Clock is the source of time for expiration and the background cleanup.
+// WithClock replaces the wall clock, e.g. with the fake clock in pantrytest.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (ticker realTicker) C() <-chan time.Time {
+	return ticker.ticker.C
+}
+
+func (ticker realTicker) Stop() {
+	ticker.ticker.Stop()
+}
@@ -0,0 +1,27 @@
+package pantry
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestCloner(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithCloner(maps.Clone[map[string]int]))
+
+	p.Set("key", map[string]int{"count": 1})
+
+	value, _ := p.Get("key")
+	value["count"] = 2
+
+	for _, value := range p.All() {
+		value["count"] = 3
+	}
+
+	p.GetMany([]string{"key"})["key"]["count"] = 4
+
+	if value, _ := p.Get("key"); value["count"] != 1 {
+		t.Fatal("cached value modified", value)
+	}
+}
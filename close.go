@@ -0,0 +1,113 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrClosed = errors.New("pantry: closed")
+
+// Close drains the pantry like Drain without a deadline. Afterwards writes
+// that add or change entries are ignored or fail with ErrClosed, while reads
+// and removals keep working. Subscriptions are closed as well. Closing more
+// than once is a no-op.
+func (pantry *Pantry[T]) Close() error {
+	return pantry.Drain(context.Background())
+}
+
+// Drain shuts the pantry down in order. It stops accepting writes first, so
+// nothing written during shutdown is lost, then flushes the write-behind
+// queue, writes every live entry to the storage of a persistent pantry and
+// syncs the write-ahead log. With WithAutoSnapshot a final snapshot is
+// uploaded within ctx. Finally the background work is stopped and the storage
+// and log are closed. When the context passed to New is done, the pantry is
+// drained the same way before its entries are dropped, and a later Drain or
+// Close only releases the resources.
+func (pantry *Pantry[T]) Drain(ctx context.Context) error {
+	var err error
+
+	pantry.closeOnce.Do(func() {
+		err = pantry.drain(ctx)
+
+		close(pantry.done)
+		if pantry.stopWatching != nil {
+			pantry.stopWatching()
+		}
+		<-pantry.stopped
+
+		if pantry.sweeper != nil {
+			pantry.sweeper.remove(pantry)
+		}
+
+		// Removals are still accepted and written back after the drain.
+		if pantry.backend != nil {
+			err = errors.Join(err, pantry.flush())
+		}
+
+		if pantry.storage != nil {
+			err = errors.Join(err, pantry.storage.Close())
+		}
+
+		if pantry.wal != nil {
+			err = errors.Join(err, pantry.wal.close())
+		}
+	})
+
+	return err
+}
+
+// drain stops writes and flushes the pending work once, either on Drain or
+// when the context passed to New is done.
+func (pantry *Pantry[T]) drain(ctx context.Context) error {
+	pantry.drainOnce.Do(func() {
+		pantry.closed.Store(true)
+
+		var errs []error
+		if pantry.backend != nil {
+			errs = append(errs, pantry.flush())
+		}
+		if pantry.storage != nil {
+			errs = append(errs, pantry.closePersists(), pantry.persistAll())
+		}
+		if pantry.wal != nil {
+			errs = append(errs, pantry.wal.sync())
+		}
+		if pantry.snapshotStore != nil && ctx.Err() == nil {
+			errs = append(errs, pantry.autoSnapshot(ctx))
+		}
+		pantry.drainErr = errors.Join(errs...)
+	})
+	return pantry.drainErr
+}
+
+func (pantry *Pantry[T]) persistAll() error {
+	var failures []*PersistError
+	batch := make(map[string][]byte)
+	now := pantry.clock.Now().UnixNano()
+
+	for _, shard := range pantry.shards {
+		shard.mutex.RLock()
+		entries := make([]persistedItem[T], 0, len(shard.store))
+		for key, item := range shard.store {
+			if pantry.isExpired(key, item, now) {
+				continue
+			}
+			entries = append(entries, persisted(key, item))
+		}
+		shard.mutex.RUnlock()
+
+		for _, entry := range entries {
+			data, err := pantry.encodePersisted(entry)
+			if err != nil {
+				failures = append(failures, &PersistError{Keys: []string{entry.Key}, Err: err, Attempts: 1})
+				continue
+			}
+			batch[entry.Key] = data
+		}
+	}
+
+	if failure := pantry.writeStorage(batch); failure != nil {
+		failures = append(failures, failure)
+	}
+	return pantry.deadLetter(failures)
+}
@@ -0,0 +1,139 @@
+package pantry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-p.stopped:
+	default:
+		t.Fatal("cleanup not stopped")
+	}
+
+	p.Set("second", 2)
+
+	if _, found := p.Get("second"); found {
+		t.Fatal("write after close")
+	}
+
+	if value, _ := p.Get("first"); value != 1 {
+		t.Fatal("not readable after close")
+	}
+
+	if err := p.Load(&bytes.Buffer{}); !errors.Is(err, ErrClosed) {
+		t.Fatal("not ErrClosed")
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal("second close failed")
+	}
+}
+
+func TestCloseFlushesPersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	p, _ := NewPersistent[string](context.Background(), time.Hour, dir)
+
+	p.Set("first", "hello")
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, errs := NewPersistent[string](context.Background(), time.Hour, dir)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	if value, _ := restored.Get("first"); value != "hello" {
+		t.Fatal("not flushed")
+	}
+}
+
+func TestCloseAfterContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p := New[int](ctx, time.Hour)
+	cancel()
+
+	<-p.stopped
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	store, err := NewFileSnapshotStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := newMapBackend()
+	p := New(context.Background(), time.Hour,
+		WithWriteBehind[string](backend, time.Hour),
+		WithAutoSnapshot[string](time.Hour, store, 1),
+	)
+	p.Set("key", "value")
+
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Set("late", "value")
+	if p.Contains("late") {
+		t.Fatal("write accepted after drain")
+	}
+	if value, found, _ := backend.Load("key"); !found || value != "value" {
+		t.Fatal("write-behind queue not flushed")
+	}
+
+	restored := New[string](context.Background(), time.Hour)
+	if err := restored.RestoreLatest(context.Background(), store); err != nil || !restored.Contains("key") {
+		t.Fatal("final snapshot not uploaded", err)
+	}
+}
+
+func TestDrainOnContextDone(t *testing.T) {
+	store, err := NewFileSnapshotStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx, time.Hour, WithAutoSnapshot[string](time.Hour, store, 1))
+	p.Set("key", "value")
+
+	cancel()
+	<-p.stopped
+
+	if p.Contains("key") {
+		t.Fatal("entries kept after the context is done")
+	}
+
+	restored := New[string](context.Background(), time.Hour)
+	if err := restored.RestoreLatest(context.Background(), store); err != nil || !restored.Contains("key") {
+		t.Fatal("final snapshot not uploaded", err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	restored.Clear()
+	if err := restored.RestoreLatest(context.Background(), store); err != nil || !restored.Contains("key") {
+		t.Fatal("snapshot replaced on close", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Pantryctl inspects and changes a pantry served by pantryhttp.
+//
+//	pantryctl [-addr url] get <key>
+//	pantryctl [-addr url] set [-ttl duration] <key> <value>
+//	pantryctl [-addr url] del <key>
+//	pantryctl [-addr url] keys
+//	pantryctl [-addr url] stats
+//	pantryctl [-addr url] dump
+//
+// Values are sent as JSON. A value that is not valid JSON is sent as a
+// string, so set name alice works as well as set name '"alice"'.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/webermarci/pantry/pantryhttp"
+)
+
+var errUsage = errors.New("usage: pantryctl [-addr url] get|set|del|keys|stats|dump [arguments]")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "pantryctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("pantryctl", flag.ContinueOnError)
+	addr := flags.String("addr", "http://localhost:8080", "address of the pantry server")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	client := &client{addr: strings.TrimSuffix(*addr, "/"), http: &http.Client{Timeout: 10 * time.Second}}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch command, args := args[0], args[1:]; command {
+	case "get":
+		if len(args) != 1 {
+			return errors.New("usage: pantryctl get <key>")
+		}
+		return client.do(stdout, http.MethodGet, keyPath(args[0]), nil, nil)
+
+	case "set":
+		flags := flag.NewFlagSet("set", flag.ContinueOnError)
+		ttl := flags.Duration("ttl", 0, "lifetime of the entry, the server default if zero")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() != 2 {
+			return errors.New("usage: pantryctl set [-ttl duration] <key> <value>")
+		}
+
+		header := http.Header{}
+		if *ttl > 0 {
+			header.Set(pantryhttp.TTLHeader, ttl.String())
+		}
+		return client.do(stdout, http.MethodPut, keyPath(flags.Arg(0)), header, jsonValue(flags.Arg(1)))
+
+	case "del":
+		if len(args) != 1 {
+			return errors.New("usage: pantryctl del <key>")
+		}
+		return client.do(stdout, http.MethodDelete, keyPath(args[0]), nil, nil)
+
+	case "keys":
+		return client.do(stdout, http.MethodGet, "/keys", nil, nil)
+
+	case "stats":
+		return client.do(stdout, http.MethodGet, "/stats", nil, nil)
+
+	case "dump":
+		return client.do(stdout, http.MethodGet, "/dump", nil, nil)
+
+	default:
+		return errUsage
+	}
+}
+
+func keyPath(key string) string {
+	return "/keys/" + url.PathEscape(key)
+}
+
+func jsonValue(value string) []byte {
+	if json.Valid([]byte(value)) {
+		return []byte(value)
+	}
+	data, _ := json.Marshal(value)
+	return data
+}
+
+type client struct {
+	addr string
+	http *http.Client
+}
+
+// do sends the request and copies the response body to stdout. Responses
+// other than 2xx are returned as errors.
+func (client *client) do(stdout io.Writer, method, path string, header http.Header, body []byte) error {
+	request, err := http.NewRequest(method, client.addr+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key := range header {
+		request.Header.Set(key, header.Get(key))
+	}
+
+	response, err := client.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+
+	_, err = io.Copy(stdout, response.Body)
+	return err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+	"github.com/webermarci/pantry/pantryhttp"
+)
+
+func TestRun(t *testing.T) {
+	p := pantry.New[any](context.Background(), time.Hour)
+	defer p.Close()
+
+	server := httptest.NewServer(pantryhttp.NewHandler(p))
+	defer server.Close()
+
+	ctl := func(args ...string) (string, error) {
+		var stdout bytes.Buffer
+		err := run(append([]string{"-addr", server.URL}, args...), &stdout)
+		return strings.TrimSpace(stdout.String()), err
+	}
+
+	if _, err := ctl("set", "-ttl", "1m", "users/1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if output, err := ctl("get", "users/1"); err != nil || output != `"alice"` {
+		t.Fatal("unexpected get", output, err)
+	}
+
+	if output, err := ctl("keys"); err != nil || output != `["users/1"]` {
+		t.Fatal("unexpected keys", output, err)
+	}
+
+	if output, err := ctl("dump"); err != nil || !strings.Contains(output, `"expires_at"`) {
+		t.Fatal("unexpected dump", output, err)
+	}
+
+	if _, err := ctl("del", "users/1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ctl("get", "users/1"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatal("expected not found, got", err)
+	}
+
+	if _, err := ctl("unknown"); err != errUsage {
+		t.Fatal("expected usage error, got", err)
+	}
+}
@@ -0,0 +1,83 @@
+package pantry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJSONCodec(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithCodec[int](JSONCodec{}))
+	defer p.Close()
+
+	p.Set("first", 1)
+
+	var buffer bytes.Buffer
+	if err := p.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []map[string]any
+	if err := json.Unmarshal(buffer.Bytes(), &entries); err != nil {
+		t.Fatal("not json", err)
+	}
+
+	restored := New(context.Background(), time.Hour, WithCodec[int](JSONCodec{}))
+	defer restored.Close()
+
+	if err := restored.Load(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != 1 {
+		t.Fatal("not restored")
+	}
+}
+
+func TestJSONCodecSnapshot(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithCodec[string](JSONCodec{}))
+	defer p.Close()
+
+	p.Set("first", "hello")
+
+	var buffer bytes.Buffer
+	if err := p.ExportSnapshot(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New(context.Background(), time.Hour, WithCodec[string](JSONCodec{}))
+	defer restored.Close()
+
+	if err := restored.ImportSnapshot(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != "hello" {
+		t.Fatal("not restored")
+	}
+}
+
+func TestJSONCodecPersist(t *testing.T) {
+	dir := t.TempDir()
+
+	p, _ := NewPersistent(context.Background(), time.Hour, dir, WithCodec[string](JSONCodec{}))
+	defer p.Close()
+
+	p.Set("first", "hello")
+	if err := p.Persist("first"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "first.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !json.Valid(data) {
+		t.Fatal("not json", string(data))
+	}
+}
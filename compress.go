@@ -0,0 +1,92 @@
+package pantry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// Compression compresses the data written by Save and Persist. Compressed
+// data must start with Magic, by which it is recognized on read.
+// Implementations outside this package, such as zstdcodec, make themselves
+// readable with RegisterCompression.
+type Compression interface {
+	Magic() []byte
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+var (
+	NoCompression   Compression
+	GzipCompression Compression = gzipCompression{}
+)
+
+// compressions holds the compressions recognized on read.
+var compressions = struct {
+	mutex sync.RWMutex
+	all   []Compression
+}{all: []Compression{GzipCompression}}
+
+// RegisterCompression makes data compressed by compression readable by
+// every pantry, whatever its own setting is. Compressions set with
+// WithCompression are registered when a pantry is created with them.
+func RegisterCompression(compression Compression) {
+	compressions.mutex.Lock()
+	defer compressions.mutex.Unlock()
+
+	for _, registered := range compressions.all {
+		if bytes.Equal(registered.Magic(), compression.Magic()) {
+			return
+		}
+	}
+	compressions.all = append(compressions.all, compression)
+}
+
+func (pantry *Pantry[T]) compress(data []byte) ([]byte, error) {
+	if pantry.compression == nil {
+		return data, nil
+	}
+	return pantry.compression.Compress(data)
+}
+
+// decompress detects the compression from its magic number, so data written
+// with any registered compression can be read back.
+func decompress(data []byte) ([]byte, error) {
+	compressions.mutex.RLock()
+	defer compressions.mutex.RUnlock()
+
+	for _, compression := range compressions.all {
+		if bytes.HasPrefix(data, compression.Magic()) {
+			return compression.Decompress(data)
+		}
+	}
+	return data, nil
+}
+
+type gzipCompression struct{}
+
+func (gzipCompression) Magic() []byte {
+	return []byte{0x1f, 0x8b}
+}
+
+func (gzipCompression) Compress(data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+func (gzipCompression) Decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,99 @@
+package pantry
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompression(t *testing.T) {
+	value := strings.Repeat("compressible ", 1000)
+
+	for _, compression := range []Compression{NoCompression, GzipCompression} {
+		p := New(context.Background(), time.Hour, WithCompression[string](compression))
+		p.Set("first", value)
+
+		var buffer bytes.Buffer
+		if err := p.Save(&buffer); err != nil {
+			t.Fatal(err)
+		}
+		p.Close()
+
+		if compression != NoCompression && buffer.Len() >= len(value) {
+			t.Fatal("not compressed", compression, buffer.Len())
+		}
+
+		// Reading detects the compression regardless of the setting.
+		restored := New[string](context.Background(), time.Hour)
+		if err := restored.Load(&buffer); err != nil {
+			t.Fatal(compression, err)
+		}
+
+		if stored, _ := restored.Get("first"); stored != value {
+			t.Fatal("not restored", compression)
+		}
+		restored.Close()
+	}
+}
+
+func TestCompressionPersist(t *testing.T) {
+	dir := t.TempDir()
+
+	p, _ := NewPersistent(context.Background(), time.Hour, dir, WithCompression[string](GzipCompression))
+	p.Set("first", "hello")
+	if err := p.Persist("first"); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+
+	restored, errs := NewPersistent[string](context.Background(), time.Hour, dir)
+	defer restored.Close()
+
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	if value, _ := restored.Get("first"); value != "hello" {
+		t.Fatal("not restored")
+	}
+}
+
+// reversedCompression stores the data reversed behind its magic.
+type reversedCompression struct{}
+
+func (reversedCompression) Magic() []byte { return []byte("REV\x00") }
+
+func (compression reversedCompression) Compress(data []byte) ([]byte, error) {
+	reversed := slices.Clone(data)
+	slices.Reverse(reversed)
+	return append(compression.Magic(), reversed...), nil
+}
+
+func (compression reversedCompression) Decompress(data []byte) ([]byte, error) {
+	reversed := slices.Clone(bytes.TrimPrefix(data, compression.Magic()))
+	slices.Reverse(reversed)
+	return reversed, nil
+}
+
+func TestCompressionRegisteredByNew(t *testing.T) {
+	registered := func() bool {
+		compressions.mutex.RLock()
+		defer compressions.mutex.RUnlock()
+		return slices.ContainsFunc(compressions.all, func(compression Compression) bool {
+			return bytes.Equal(compression.Magic(), reversedCompression{}.Magic())
+		})
+	}
+
+	option := WithCompression[string](reversedCompression{})
+	if registered() {
+		t.Fatal("registered before a pantry uses it")
+	}
+
+	New(context.Background(), time.Hour, option).Close()
+	if !registered() {
+		t.Fatal("not registered by New")
+	}
+}
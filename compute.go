@@ -0,0 +1,228 @@
+package pantry
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+)
+
+type call[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// PanicError is what the callers waiting for a loader panic with when the
+// loader panicked, instead of receiving a zero value. Value is what it
+// panicked with and Stack where.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("pantry: loader panicked: %v\n\n%s", err.Value, err.Stack)
+}
+
+func (err *PanicError) Unwrap() error {
+	wrapped, _ := err.Value.(error)
+	return wrapped
+}
+
+// GetOrSet returns the existing value and true if the key is present,
+// otherwise it stores value and returns it with false.
+func (pantry *Pantry[T]) GetOrSet(key string, value T) (T, bool) {
+	canonical := pantry.canonical(key)
+	stored, loaded := pantry.getOrSet(canonical, pantry.hash(canonical), value, true)
+	return pantry.clone(stored.value), loaded
+}
+
+// getOrSet takes the canonical and the normalized key. Values loaded from
+// the backend are not written back to it.
+func (pantry *Pantry[T]) getOrSet(canonical, key string, value T, writeBack bool) (item[T], bool) {
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	if existing, found := shard.store[key]; found && !pantry.isExpired(key, existing, now.UnixNano()) {
+		return existing, true
+	}
+
+	stored := pantry.newItem(canonical, value, pantry.expiresAt(now, pantry.defaultTTL(key, value)))
+
+	if pantry.closed.Load() {
+		return stored, false
+	}
+
+	evictions = pantry.put(evictions, shard, key, stored, now.UnixNano())
+	if writeBack {
+		evictions = pantry.writeBack(evictions, key, value, false)
+	}
+	return stored, false
+}
+
+// GetOrCompute returns the cached value or runs loader to produce and store
+// it. Concurrent callers for the same key share a single loader run. A value
+// set while the loader is running takes precedence over the loaded one. The
+// loader runs holding the lock of key, so it must not call Lock for the same
+// key.
+func (pantry *Pantry[T]) GetOrCompute(key string, loader func() (T, error)) (T, error) {
+	if value, found := pantry.Get(key); found {
+		return value, nil
+	}
+	return pantry.compute(context.Background(), key, loader)
+}
+
+// compute runs loader for a miss, sharing the run with concurrent callers.
+// When ctx can be canceled, the loader runs in its own goroutine so the
+// caller can stop waiting with ctx.Err() while the result is still stored
+// for the others.
+func (pantry *Pantry[T]) compute(ctx context.Context, key string, loader func() (T, error)) (T, error) {
+	normalized := pantry.normalize(key)
+
+	pantry.callsMutex.Lock()
+	pending, found := pantry.calls[normalized]
+	if !found {
+		pending = &call[T]{done: make(chan struct{})}
+		if pantry.calls == nil {
+			pantry.calls = make(map[string]*call[T])
+		}
+		pantry.calls[normalized] = pending
+	}
+	pantry.callsMutex.Unlock()
+
+	if found {
+		pantry.counters.coalesced.Add(1)
+	} else if ctx.Done() == nil {
+		pantry.runCall(key, normalized, pending, loader)
+	} else {
+		go pantry.runCall(key, normalized, pending, loader)
+	}
+
+	select {
+	case <-pending.done:
+		if panicked, ok := pending.err.(*PanicError); ok {
+			panic(panicked)
+		}
+		return pantry.clone(pending.value), pending.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// runCall recovers a panic of the loader, so it reaches the callers rather
+// than crashing the process when the loader runs in its own goroutine.
+func (pantry *Pantry[T]) runCall(key, normalized string, pending *call[T], loader func() (T, error)) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			pending.value, pending.err = *new(T), &PanicError{Value: recovered, Stack: debug.Stack()}
+		}
+		pantry.callsMutex.Lock()
+		delete(pantry.calls, normalized)
+		pantry.callsMutex.Unlock()
+		close(pending.done)
+	}()
+
+	pantry.DoWithLock(key, func() {
+		pending.value, pending.err = loader()
+	})
+	if pending.err == nil {
+		stored, _ := pantry.getOrSet(pantry.canonical(key), normalized, pending.value, true)
+		pending.value = stored.value
+	}
+}
+
+// SetIfAbsent stores value only if the key is not present and reports
+// whether it did.
+func (pantry *Pantry[T]) SetIfAbsent(key string, value T) bool {
+	_, loaded := pantry.GetOrSet(key, value)
+	return !loaded
+}
+
+// CompareAndSwapFunc replaces the value of a live entry with new if equal
+// reports it matches old. The entry keeps its expiration.
+func (pantry *Pantry[T]) CompareAndSwapFunc(key string, old, new T, equal func(a, b T) bool) bool {
+	if pantry.closed.Load() {
+		return false
+	}
+
+	canonical := pantry.canonical(key)
+	key = pantry.hash(canonical)
+	shard := pantry.shardFor(key)
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now().UnixNano()
+	current, found := shard.store[key]
+	if !found || pantry.isExpired(key, current, now) || !equal(current.value, old) {
+		return false
+	}
+
+	evictions = pantry.put(evictions, shard, key, pantry.newItem(canonical, new, current.expires), now)
+	evictions = pantry.writeBack(evictions, key, new, false)
+	return true
+}
+
+// CompareAndSwap replaces the value of a live entry with new if it equals old,
+// compared with the function of WithEqual if there is one.
+func CompareAndSwap[T comparable](p *Pantry[T], key string, old, new T) bool {
+	if p.equal != nil {
+		return p.CompareAndSwapFunc(key, old, new, p.equal)
+	}
+	return p.CompareAndSwapFunc(key, old, new, func(a, b T) bool {
+		return a == b
+	})
+}
+
+// SetIfChanged stores value with the default expiration unless the live
+// entry already holds an equal one, compared with the function of WithEqual
+// or reflect.DeepEqual. It reports whether it wrote; a skipped write keeps the
+// expiration and reaches neither the backend, the persistence nor the
+// replicas.
+func (pantry *Pantry[T]) SetIfChanged(key string, value T) bool {
+	if pantry.closed.Load() {
+		return false
+	}
+
+	canonical := pantry.canonical(key)
+	key = pantry.hash(canonical)
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	current, found := shard.store[key]
+	if found && !pantry.isExpired(key, current, now.UnixNano()) && pantry.equals(current.value, value) {
+		return false
+	}
+
+	evictions = pantry.put(evictions, shard, key, pantry.newItem(canonical, value, pantry.expiresAt(now, pantry.defaultTTL(key, value))), now.UnixNano())
+	evictions = pantry.writeBack(evictions, key, value, false)
+	return true
+}
+
+func (pantry *Pantry[T]) equals(a, b T) bool {
+	if pantry.equal != nil {
+		return pantry.equal(a, b)
+	}
+	return reflect.DeepEqual(a, b)
+}
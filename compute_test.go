@@ -0,0 +1,262 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrSet(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	value, loaded := p.GetOrSet(t.Name(), 1)
+	if loaded || value != 1 {
+		t.Fatal("not stored")
+	}
+
+	value, loaded = p.GetOrSet(t.Name(), 2)
+	if !loaded || value != 1 {
+		t.Fatal("overwritten")
+	}
+}
+
+func TestGetOrCompute(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	var calls atomic.Int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			value, err := p.GetOrCompute(t.Name(), func() (int, error) {
+				calls.Add(1)
+				time.Sleep(20 * time.Millisecond)
+				return 42, nil
+			})
+			if err != nil || value != 42 {
+				t.Error("wrong result")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatal("loader not deduplicated")
+	}
+
+	if value, _ := p.Get(t.Name()); value != 42 {
+		t.Fatal("not stored")
+	}
+}
+
+func TestGetOrComputeError(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	failure := errors.New("failure")
+
+	if _, err := p.GetOrCompute(t.Name(), func() (int, error) {
+		return 0, failure
+	}); !errors.Is(err, failure) {
+		t.Fatal("error not returned")
+	}
+
+	if _, found := p.Get(t.Name()); found {
+		t.Fatal("stored")
+	}
+}
+
+func TestGetOrComputeSetWins(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan int)
+
+	go func() {
+		value, _ := p.GetOrCompute(t.Name(), func() (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+		done <- value
+	}()
+
+	<-started
+	p.Set(t.Name(), 2)
+	close(release)
+
+	if value := <-done; value != 2 {
+		t.Fatal("loaded value returned")
+	}
+
+	if value, _ := p.Get(t.Name()); value != 2 {
+		t.Fatal("set overwritten")
+	}
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	if !p.SetIfAbsent(t.Name(), 1) {
+		t.Fatal("not set")
+	}
+
+	if p.SetIfAbsent(t.Name(), 2) {
+		t.Fatal("overwritten")
+	}
+
+	if value, _ := p.Get(t.Name()); value != 1 {
+		t.Fatal("wrong value")
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	if CompareAndSwap(p, t.Name(), 0, 1) {
+		t.Fatal("swapped missing")
+	}
+
+	p.Set(t.Name(), 1)
+
+	if CompareAndSwap(p, t.Name(), 2, 3) {
+		t.Fatal("swapped mismatch")
+	}
+
+	if !CompareAndSwap(p, t.Name(), 1, 3) {
+		t.Fatal("not swapped")
+	}
+
+	if value, _ := p.Get(t.Name()); value != 3 {
+		t.Fatal("wrong value")
+	}
+}
+
+func TestCompareAndSwapFunc(t *testing.T) {
+	p := New[[]int](context.Background(), time.Hour)
+
+	p.Set(t.Name(), []int{1, 2})
+
+	equal := func(a, b []int) bool {
+		return slices.Equal(a, b)
+	}
+
+	if !p.CompareAndSwapFunc(t.Name(), []int{1, 2}, []int{3}, equal) {
+		t.Fatal("not swapped")
+	}
+
+	if value, _ := p.Get(t.Name()); !slices.Equal(value, []int{3}) {
+		t.Fatal("wrong value")
+	}
+}
+
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set(t.Name(), 0)
+
+	var swapped atomic.Int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if CompareAndSwap(p, t.Name(), 0, 1) {
+				swapped.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if swapped.Load() != 1 {
+		t.Fatal("not exactly one swap")
+	}
+}
+
+func TestSetIfChanged(t *testing.T) {
+	p := New[[]int](context.Background(), time.Hour)
+
+	var replaced atomic.Int32
+	p.OnEvict(func(_ string, _ []int, reason EvictionReason) {
+		if reason == Replaced {
+			replaced.Add(1)
+		}
+	})
+
+	if !p.SetIfChanged("key", []int{1, 2}) {
+		t.Fatal("first write skipped")
+	}
+	if p.SetIfChanged("key", []int{1, 2}) {
+		t.Fatal("unchanged write not skipped")
+	}
+	if !p.SetIfChanged("key", []int{1, 3}) {
+		t.Fatal("changed write skipped")
+	}
+	if replaced.Load() != 1 {
+		t.Fatal("unexpected replacements", replaced.Load())
+	}
+}
+
+func TestWithEqual(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithEqual(func(a, b string) bool {
+		return strings.EqualFold(a, b)
+	}))
+
+	p.Set("key", "Hello")
+
+	if !CompareAndSwap(p, "key", "HELLO", "world") {
+		t.Fatal("not swapped with custom equality")
+	}
+	if p.SetIfChanged("key", "WORLD") {
+		t.Fatal("equal value written")
+	}
+	if value, _ := p.Get("key"); value != "world" {
+		t.Fatal("unexpected value", value)
+	}
+}
+
+func TestGetOrComputePanic(t *testing.T) {
+	recovered := func(fn func()) (value any) {
+		defer func() { value = recover() }()
+		fn()
+		return nil
+	}
+
+	p := New[string](context.Background(), time.Hour)
+	defer p.Close()
+
+	failure := errors.New("boom")
+	value := recovered(func() {
+		p.GetOrCompute("key", func() (string, error) { panic(failure) })
+	})
+	panicked, ok := value.(*PanicError)
+	if !ok || !errors.Is(panicked, failure) {
+		t.Fatal("panic not propagated", value)
+	}
+	if p.Contains("key") {
+		t.Fatal("value stored after a panic")
+	}
+
+	// With a cancelable context the loader runs in its own goroutine, and
+	// the panic still reaches the caller.
+	loading := New(context.Background(), time.Hour, WithLoader(func(context.Context, string) (string, error) {
+		panic("boom")
+	}))
+	defer loading.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	value = recovered(func() { loading.GetContext(ctx, "key") })
+	if panicked, ok := value.(*PanicError); !ok || panicked.Value != "boom" {
+		t.Fatal("panic not propagated", value)
+	}
+}
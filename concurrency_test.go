@@ -0,0 +1,294 @@
+package pantry
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClock is a fake clock moved by the tests, while its tickers run in real
+// time so the background cleanup keeps racing with the workers.
+type testClock struct {
+	now atomic.Int64
+}
+
+func (clock *testClock) Now() time.Time {
+	return time.Unix(0, clock.now.Load())
+}
+
+func (clock *testClock) NewTicker(d time.Duration) Ticker {
+	return realClock{}.NewTicker(d)
+}
+
+func (clock *testClock) advance(d time.Duration) {
+	clock.now.Add(int64(d))
+}
+
+type stressValue struct {
+	Key string
+	Seq int
+}
+
+// stressEntry is what a worker knows about one of its keys: the value it set
+// last and the range its expiration falls into, as the clock may move during
+// the call.
+type stressEntry struct {
+	value   stressValue
+	present bool
+	earlier int64
+	later   int64
+}
+
+// stressTTLs are the TTLs the workers pick from, in clock ticks.
+var stressTTLs = []time.Duration{time.Millisecond, 5 * time.Millisecond, 20 * time.Millisecond, NoExpiration}
+
+func TestConcurrentInvariants(t *testing.T) {
+	for name, opts := range map[string][]Option[stressValue]{
+		"default":        nil,
+		"single shard":   {WithShards[stressValue](1)},
+		"read optimized": {WithReadOptimizedStore[stressValue]()},
+		"max items":      {WithMaxItems[stressValue](1 << 20)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			stress(t, 8, 2000, opts...)
+		})
+	}
+}
+
+// stress runs workers that each own a set of keys and check every read of
+// them against what they wrote, while readers iterate and count, the clock
+// moves and the store is cleared now and then. The clearing takes the
+// epoch lock exclusively, so the workers know when their keys are gone.
+func stress(t *testing.T, workers, operations int, opts ...Option[stressValue]) {
+	clock := &testClock{}
+	clock.now.Store(time.Now().UnixNano())
+
+	opts = append([]Option[stressValue]{
+		WithClock[stressValue](clock),
+		WithCleanupInterval[stressValue](time.Millisecond),
+	}, opts...)
+	p := New(context.Background(), time.Hour, opts...)
+	defer p.Close()
+
+	const keysPerWorker = 16
+	var epoch sync.RWMutex
+	var cleared atomic.Int64
+	var stop atomic.Bool
+	var background sync.WaitGroup
+
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		for !stop.Load() {
+			clock.advance(time.Millisecond)
+			time.Sleep(50 * time.Microsecond)
+		}
+	}()
+
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		for !stop.Load() {
+			for key, value := range p.All() {
+				if value.Key != key {
+					t.Errorf("iteration returned %v for %s", value, key)
+					return
+				}
+			}
+			if count := p.Count(); count < 0 || count > workers*keysPerWorker {
+				t.Errorf("count %d out of range", count)
+				return
+			}
+			for range p.Keys() {
+				break
+			}
+		}
+	}()
+
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		for !stop.Load() {
+			time.Sleep(time.Millisecond)
+			epoch.Lock()
+			p.Clear()
+			cleared.Add(1)
+			epoch.Unlock()
+		}
+	}()
+
+	models := make([]map[string]*stressEntry, workers)
+	epochs := make([]int64, workers)
+	var wg sync.WaitGroup
+	for worker := range workers {
+		models[worker] = make(map[string]*stressEntry, keysPerWorker)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			model := models[worker]
+			random := rand.New(rand.NewPCG(uint64(worker), 0))
+			seen := cleared.Load()
+
+			for seq := range operations {
+				key := fmt.Sprintf("w%d:%d", worker, random.IntN(keysPerWorker))
+
+				epoch.RLock()
+				if current := cleared.Load(); current != seen {
+					clear(model)
+					seen = current
+				}
+				if err := stressStep(p, clock, model, random, key, seq); err != nil {
+					epoch.RUnlock()
+					t.Error(err)
+					return
+				}
+				epoch.RUnlock()
+			}
+			epochs[worker] = seen
+		}()
+	}
+	wg.Wait()
+
+	stop.Store(true)
+	background.Wait()
+	if t.Failed() {
+		return
+	}
+
+	// With everything stopped, Count and the iterators must agree with the
+	// models: entries that surely live are counted, and nothing else may be.
+	now := clock.Now().UnixNano()
+	live, possible := 0, make(map[string]bool)
+	for worker, model := range models {
+		if epochs[worker] != cleared.Load() {
+			continue
+		}
+		for key, entry := range model {
+			if !entry.present {
+				continue
+			}
+			if now <= entry.earlier {
+				live++
+			}
+			if now <= entry.later {
+				possible[key] = true
+			}
+		}
+	}
+	count, iterated := p.Count(), 0
+	for key := range p.Keys() {
+		if !possible[key] {
+			t.Fatal("iteration returned dead key", key)
+		}
+		iterated++
+	}
+	if count != iterated || count < live || count > len(possible) {
+		t.Fatalf("count %d, iterated %d, expected between %d and %d", count, iterated, live, len(possible))
+	}
+}
+
+// stressStep applies one random operation to key and checks the result
+// against the model of the worker owning it.
+func stressStep(p *Pantry[stressValue], clock *testClock, model map[string]*stressEntry, random *rand.Rand, key string, seq int) error {
+	entry := model[key]
+
+	switch random.IntN(4) {
+	case 0, 1:
+		ttl := stressTTLs[random.IntN(len(stressTTLs))]
+		value := stressValue{Key: key, Seq: seq}
+		before := clock.Now().UnixNano()
+		p.SetWithTTL(key, value, ttl)
+		after := clock.Now().UnixNano()
+		model[key] = &stressEntry{
+			value:   value,
+			present: true,
+			earlier: expiresAfter(before, ttl),
+			later:   expiresAfter(after, ttl),
+		}
+	case 2:
+		p.Remove(key)
+		model[key] = &stressEntry{}
+	default:
+		before := clock.Now().UnixNano()
+		value, found := p.Get(key)
+		after := clock.Now().UnixNano()
+
+		switch {
+		case found && (entry == nil || !entry.present):
+			return fmt.Errorf("removed key %s resurrected as %v", key, value)
+		case found && value != entry.value:
+			return fmt.Errorf("key %s returned %v instead of %v", key, value, entry.value)
+		case found && before > entry.later:
+			return fmt.Errorf("key %s returned after expiring", key)
+		case !found && entry != nil && entry.present && after <= entry.earlier:
+			return fmt.Errorf("live key %s not found", key)
+		}
+	}
+	return nil
+}
+
+func FuzzOperations(f *testing.F) {
+	f.Add([]byte{0, 1, 3, 1, 2, 1, 3, 1})
+	f.Add([]byte{0, 0, 1, 4, 0, 3, 0, 5, 1, 0, 2, 0, 1, 0})
+	f.Add([]byte{4, 0, 0, 2, 1, 2, 5, 0, 1, 2, 4, 0, 3, 2})
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		clock := &testClock{}
+		p := New(context.Background(), time.Hour, WithClock[int](clock), WithoutBackgroundCleanup[int]())
+		defer p.Close()
+
+		type modelEntry struct {
+			value   int
+			expires int64
+		}
+		model := make(map[string]modelEntry)
+
+		for i := 0; i+1 < len(ops); i += 2 {
+			op, arg := ops[i], ops[i+1]
+			key := fmt.Sprint("key", arg%8)
+			now := clock.Now().UnixNano()
+
+			switch op % 6 {
+			case 0:
+				ttl := time.Duration(arg/8%4) * time.Millisecond
+				if ttl == 0 {
+					ttl = NoExpiration
+				}
+				p.SetWithTTL(key, i, ttl)
+				model[key] = modelEntry{value: i, expires: expiresAfter(now, ttl)}
+			case 1:
+				value, found := p.Get(key)
+				expected, ok := model[key]
+				ok = ok && now <= expected.expires
+				if found != ok || found && value != expected.value {
+					t.Fatalf("get %s: %d, %t, expected %d, %t", key, value, found, expected.value, ok)
+				}
+			case 2:
+				p.Remove(key)
+				delete(model, key)
+			case 3:
+				clock.advance(time.Duration(arg%4) * time.Millisecond)
+			case 4:
+				p.PurgeExpired()
+			case 5:
+				p.Clear()
+				clear(model)
+			}
+
+			now = clock.Now().UnixNano()
+			live := 0
+			for _, entry := range model {
+				if now <= entry.expires {
+					live++
+				}
+			}
+			if count := p.Count(); count != live {
+				t.Fatalf("count %d, expected %d", count, live)
+			}
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package pantry
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LockStats describes the sampled lock acquisitions of a shard, or of all of
+// them, as measured with WithContentionStats.
+type LockStats struct {
+	// Samples is the number of acquisitions measured.
+	Samples uint64 `json:"samples"`
+	// Contended is the number of measured acquisitions that had to wait.
+	Contended uint64 `json:"contended"`
+	// WaitTime is the total time the contended acquisitions waited.
+	WaitTime time.Duration `json:"wait_time"`
+}
+
+func (stats *LockStats) add(other LockStats) {
+	stats.Samples += other.Samples
+	stats.Contended += other.Contended
+	stats.WaitTime += other.WaitTime
+}
+
+// ShardStats describes a single shard.
+type ShardStats struct {
+	Items int       `json:"items"`
+	Locks LockStats `json:"locks"`
+}
+
+// WithContentionStats measures every sampleEvery-th lock acquisition of each
+// shard, counting how often it had to wait and for how long, and reports the
+// results in Stats. Many contended acquisitions on all shards suggest
+// raising the shard count with WithShards, while contention on a few shards
+// points at hot keys. sampleEvery must be at least 1.
+func WithContentionStats[T any](sampleEvery int) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if sampleEvery < 1 {
+			pantry.invalidOption("contention sampling every %d acquisitions needs sampleEvery >= 1", sampleEvery)
+			return
+		}
+		pantry.contentionSample = sampleEvery
+	}
+}
+
+// contention holds the lock measurements of a shard.
+type contention struct {
+	every        uint64
+	acquisitions atomic.Uint64
+	samples      atomic.Uint64
+	contended    atomic.Uint64
+	waitTime     atomic.Int64
+}
+
+func (contention *contention) stats() LockStats {
+	if contention == nil {
+		return LockStats{}
+	}
+	return LockStats{
+		Samples:   contention.samples.Load(),
+		Contended: contention.contended.Load(),
+		WaitTime:  time.Duration(contention.waitTime.Load()),
+	}
+}
+
+func (contention *contention) reset() {
+	if contention == nil {
+		return
+	}
+	contention.samples.Store(0)
+	contention.contended.Store(0)
+	contention.waitTime.Store(0)
+}
+
+// sample reports whether the next acquisition is measured.
+func (contention *contention) sample() bool {
+	return contention != nil && contention.acquisitions.Add(1)%contention.every == 0
+}
+
+// waited records a measured acquisition that waited since start, or did not
+// have to wait if start is zero.
+func (contention *contention) waited(start time.Time) {
+	contention.samples.Add(1)
+	if !start.IsZero() {
+		contention.contended.Add(1)
+		contention.waitTime.Add(int64(time.Since(start)))
+	}
+}
+
+// shardMutex is the lock of a shard, measuring contention when enabled.
+type shardMutex struct {
+	sync.RWMutex
+	contention *contention
+}
+
+func (mutex *shardMutex) Lock() {
+	if !mutex.contention.sample() {
+		mutex.RWMutex.Lock()
+		return
+	}
+	if mutex.TryLock() {
+		mutex.contention.waited(time.Time{})
+		return
+	}
+	start := time.Now()
+	mutex.RWMutex.Lock()
+	mutex.contention.waited(start)
+}
+
+func (mutex *shardMutex) RLock() {
+	if !mutex.contention.sample() {
+		mutex.RWMutex.RLock()
+		return
+	}
+	if mutex.TryRLock() {
+		mutex.contention.waited(time.Time{})
+		return
+	}
+	start := time.Now()
+	mutex.RWMutex.RLock()
+	mutex.contention.waited(start)
+}
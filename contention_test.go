@@ -0,0 +1,53 @@
+package pantry
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestContentionStats(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithShards[int](2), WithContentionStats[int](1))
+	defer p.Close()
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				p.Set(strconv.Itoa(worker*1000+i), i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := p.Stats()
+	if len(stats.Shards) != 2 || stats.Shards[0].Items+stats.Shards[1].Items != 8000 {
+		t.Fatal("unexpected shards", stats.Shards)
+	}
+	if stats.Locks.Samples < 8000 || stats.Locks.Samples != stats.Shards[0].Locks.Samples+stats.Shards[1].Locks.Samples {
+		t.Fatal("unexpected samples", stats.Locks)
+	}
+	if stats.Locks.Contended > 0 && stats.Locks.WaitTime <= 0 {
+		t.Fatal("contention without wait time", stats.Locks)
+	}
+
+	// Stats itself takes the lock of every shard.
+	p.ResetStats()
+	if locks := p.Stats().Locks; locks.Samples != 2 {
+		t.Fatal("not reset", locks)
+	}
+}
+
+func TestContentionStatsDisabled(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.Set("key", 1)
+	if locks := p.Stats().Locks; locks != (LockStats{}) {
+		t.Fatal("measured without the option", locks)
+	}
+}
@@ -0,0 +1,129 @@
+package pantry
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrDecryption = errors.New("pantry: decryption failed")
+
+// encryptionHeader is authenticated with every sealed blob and log frame, so
+// data written without encryption or by another format is rejected.
+var encryptionHeader = []byte("pantry-aes-gcm-v1")
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts data as the nonce followed by the ciphertext. Without an
+// encryption key data is returned as is.
+func (pantry *Pantry[T]) seal(data []byte) ([]byte, error) {
+	if pantry.aead == nil {
+		return data, nil
+	}
+	return sealWith(pantry.aead, data, encryptionHeader)
+}
+
+func (pantry *Pantry[T]) open(data []byte) ([]byte, error) {
+	if pantry.aead == nil {
+		return data, nil
+	}
+	return openWith(pantry.aead, data, encryptionHeader)
+}
+
+func sealWith(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, additional), nil
+}
+
+func openWith(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, ErrDecryption
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+// frameAdditional binds a log frame to its position so frames cannot be
+// dropped or reordered without detection.
+func frameAdditional(sequence uint64) []byte {
+	return binary.BigEndian.AppendUint64(bytes.Clone(encryptionHeader), sequence)
+}
+
+// sealingWriter encrypts every write into a length prefixed frame.
+type sealingWriter struct {
+	w        io.Writer
+	aead     cipher.AEAD
+	sequence uint64
+}
+
+func (writer *sealingWriter) Write(p []byte) (int, error) {
+	sealed, err := sealWith(writer.aead, p, frameAdditional(writer.sequence))
+	if err != nil {
+		return 0, err
+	}
+
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(sealed)))
+	if _, err := writer.w.Write(append(frame, sealed...)); err != nil {
+		return 0, err
+	}
+
+	writer.sequence++
+	return len(p), nil
+}
+
+// openingReader decrypts the frames written by sealingWriter. A frame cut
+// short is reported as io.ErrUnexpectedEOF.
+type openingReader struct {
+	r        io.Reader
+	aead     cipher.AEAD
+	sequence uint64
+	buffered []byte
+}
+
+func (reader *openingReader) Read(p []byte) (int, error) {
+	for len(reader.buffered) == 0 {
+		var length [4]byte
+		if _, err := io.ReadFull(reader.r, length[:]); err != nil {
+			return 0, err
+		}
+
+		sealed := make([]byte, binary.BigEndian.Uint32(length[:]))
+		if _, err := io.ReadFull(reader.r, sealed); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+
+		plaintext, err := openWith(reader.aead, sealed, frameAdditional(reader.sequence))
+		if err != nil {
+			return 0, fmt.Errorf("%w: log frame %d", err, reader.sequence)
+		}
+
+		reader.sequence++
+		reader.buffered = plaintext
+	}
+
+	n := copy(p, reader.buffered)
+	reader.buffered = reader.buffered[n:]
+	return n, nil
+}
@@ -0,0 +1,121 @@
+package pantry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func TestEncryptedSave(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithEncryption[string](testKey))
+	defer p.Close()
+
+	p.Set("first", "secret token")
+
+	var buffer bytes.Buffer
+	if err := p.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(buffer.Bytes(), []byte("secret token")) {
+		t.Fatal("plaintext written")
+	}
+
+	saved := buffer.Bytes()
+
+	restored := New(context.Background(), time.Hour, WithEncryption[string](testKey))
+	defer restored.Close()
+
+	if err := restored.Load(bytes.NewReader(saved)); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != "secret token" {
+		t.Fatal("not restored")
+	}
+
+	other := New(context.Background(), time.Hour, WithEncryption[string](bytes.Repeat([]byte{8}, 32)))
+	defer other.Close()
+
+	if err := other.Load(bytes.NewReader(saved)); !errors.Is(err, ErrDecryption) {
+		t.Fatal("loaded with wrong key", err)
+	}
+
+	tampered := bytes.Clone(saved)
+	tampered[len(tampered)-1] ^= 1
+
+	if err := restored.Load(bytes.NewReader(tampered)); !errors.Is(err, ErrDecryption) {
+		t.Fatal("loaded tampered data", err)
+	}
+}
+
+func TestEncryptedSnapshot(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithEncryption[string](testKey))
+	defer p.Close()
+
+	p.Set("first", "secret token")
+
+	var buffer bytes.Buffer
+	if err := p.ExportSnapshot(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	plain := New[string](context.Background(), time.Hour)
+	defer plain.Close()
+
+	if err := plain.ImportSnapshot(bytes.NewReader(buffer.Bytes())); err == nil {
+		t.Fatal("imported without key")
+	}
+
+	restored := New(context.Background(), time.Hour, WithEncryption[string](testKey))
+	defer restored.Close()
+
+	if err := restored.ImportSnapshot(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != "secret token" {
+		t.Fatal("not restored")
+	}
+}
+
+func TestEncryptedWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pantry.wal")
+
+	p, err := NewWithWAL(context.Background(), time.Hour, path, WithEncryption[string](testKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Set("first", "secret token")
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("secret token")) {
+		t.Fatal("plaintext logged")
+	}
+
+	restored := New(context.Background(), time.Hour, WithEncryption[string](testKey))
+	defer restored.Close()
+
+	if err := restored.Recover(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != "secret token" {
+		t.Fatal("not recovered")
+	}
+
+	p.Close()
+}
+
+func TestEncryptionInvalidKey(t *testing.T) {
+	if _, err := NewValidated(context.Background(), time.Hour, WithEncryption[string]([]byte("short"))); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatal("invalid key accepted", err)
+	}
+}
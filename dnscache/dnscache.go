@@ -0,0 +1,91 @@
+// Package dnscache caches host lookups of a net.Resolver in a pantry. Names
+// that are looked up while their addresses are about to expire are resolved
+// again in the background, so busy hosts never wait on DNS.
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+// refreshFraction is the final share of the TTL during which a lookup
+// triggers a background refresh.
+const refreshFraction = 0.2
+
+// Resolver resolves host names through a cache.
+type Resolver struct {
+	addrs *pantry.Pantry[[]string]
+}
+
+// New creates a resolver caching the lookups of resolver for ttl. A nil
+// resolver uses net.DefaultResolver. Options are passed to the underlying
+// pantry, e.g. to inject a clock.
+func New(ctx context.Context, resolver *net.Resolver, ttl time.Duration, opts ...pantry.Option[[]string]) *Resolver {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return newResolver(ctx, resolver.LookupHost, ttl, opts...)
+}
+
+func newResolver(ctx context.Context, lookup func(context.Context, string) ([]string, error), ttl time.Duration, opts ...pantry.Option[[]string]) *Resolver {
+	opts = append([]pantry.Option[[]string]{
+		pantry.WithLoader(lookup),
+		pantry.WithRefreshAhead[[]string](refreshFraction),
+		pantry.WithCloner(slices.Clone[[]string]),
+	}, opts...)
+
+	return &Resolver{addrs: pantry.New(ctx, ttl, opts...)}
+}
+
+// LookupHost returns the addresses of host like net.Resolver.LookupHost.
+// Failed lookups are not cached.
+func (resolver *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return resolver.addrs.GetContext(ctx, host)
+}
+
+// DialContext resolves the host of address through the cache and dials its
+// addresses in order until one connects. It fits http.Transport.DialContext.
+func (resolver *Resolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Forget drops the cached addresses of host.
+func (resolver *Resolver) Forget(host string) {
+	resolver.addrs.Remove(host)
+}
+
+// Close stops the underlying pantry.
+func (resolver *Resolver) Close() error {
+	return resolver.addrs.Close()
+}
@@ -0,0 +1,68 @@
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResolver(t *testing.T) {
+	var lookups atomic.Int32
+	resolver := newResolver(context.Background(), func(_ context.Context, host string) ([]string, error) {
+		lookups.Add(1)
+		if host == "missing" {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return []string{"127.0.0.1"}, nil
+	}, time.Hour)
+	defer resolver.Close()
+
+	for range 3 {
+		addrs, err := resolver.LookupHost(context.Background(), "example")
+		if err != nil || len(addrs) != 1 || addrs[0] != "127.0.0.1" {
+			t.Fatal("unexpected addresses", addrs, err)
+		}
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Fatal("lookup not cached", n)
+	}
+
+	resolver.Forget("example")
+	resolver.LookupHost(context.Background(), "example")
+	if n := lookups.Load(); n != 2 {
+		t.Fatal("lookup not repeated after forgetting", n)
+	}
+
+	var dnsErr *net.DNSError
+	if _, err := resolver.LookupHost(context.Background(), "missing"); !errors.As(err, &dnsErr) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestResolverDialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	resolver := newResolver(context.Background(), func(context.Context, string) ([]string, error) {
+		return []string{"127.0.0.1"}, nil
+	}, time.Hour)
+	defer resolver.Close()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	conn, err := resolver.DialContext(context.Background(), "tcp", net.JoinHostPort("example", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,144 @@
+package pantry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type EventKind int
+
+const (
+	EventSet EventKind = iota
+	EventRemoved
+	EventExpired
+	EventCleared
+)
+
+func (kind EventKind) String() string {
+	switch kind {
+	case EventSet:
+		return "set"
+	case EventRemoved:
+		return "removed"
+	case EventExpired:
+		return "expired"
+	case EventCleared:
+		return "cleared"
+	default:
+		return "unknown"
+	}
+}
+
+type Event[T any] struct {
+	Kind  EventKind
+	Key   string
+	Value T
+	Time  time.Time
+}
+
+const watchBuffer = 16
+
+// subscribers maps every channel to the filter its events must pass, nil for
+// all events.
+type subscribers[T any] struct {
+	mutex    sync.RWMutex
+	channels map[chan Event[T]]func(key string) bool
+}
+
+// Subscribe returns a channel receiving an event for every write, removal and
+// expiration, and a single EventCleared on Clear after the per-key removals.
+// Events are dropped rather than blocking writers when the buffer is full.
+// The channel is closed when ctx is cancelled or the pantry is closed.
+func (pantry *Pantry[T]) Subscribe(ctx context.Context, buffer int) <-chan Event[T] {
+	return pantry.subscribe(ctx, buffer, nil)
+}
+
+// Watch returns a channel receiving the events of a single key: it being set,
+// removed, or expiring. Like with Subscribe, events are dropped when the
+// receiver falls behind, and the channel is closed when ctx is cancelled or
+// the pantry is closed.
+func (pantry *Pantry[T]) Watch(ctx context.Context, key string) <-chan Event[T] {
+	key = pantry.normalize(key)
+	return pantry.subscribe(ctx, watchBuffer, func(event string) bool {
+		return event == key
+	})
+}
+
+func (pantry *Pantry[T]) subscribe(ctx context.Context, buffer int, filter func(key string) bool) <-chan Event[T] {
+	events := make(chan Event[T], buffer)
+
+	pantry.subscribers.mutex.Lock()
+	defer pantry.subscribers.mutex.Unlock()
+
+	if pantry.closed.Load() {
+		close(events)
+		return events
+	}
+
+	if pantry.subscribers.channels == nil {
+		pantry.subscribers.channels = make(map[chan Event[T]]func(key string) bool)
+	}
+	pantry.subscribers.channels[events] = filter
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-pantry.done:
+		}
+		pantry.unsubscribe(events)
+	}()
+
+	return events
+}
+
+func (pantry *Pantry[T]) unsubscribe(events chan Event[T]) {
+	pantry.subscribers.mutex.Lock()
+	defer pantry.subscribers.mutex.Unlock()
+
+	if _, found := pantry.subscribers.channels[events]; found {
+		delete(pantry.subscribers.channels, events)
+		close(events)
+	}
+}
+
+func (pantry *Pantry[T]) publish(event Event[T]) {
+	pantry.subscribers.mutex.RLock()
+	defer pantry.subscribers.mutex.RUnlock()
+
+	for events, filter := range pantry.subscribers.channels {
+		if filter != nil && !filter(event.Key) {
+			continue
+		}
+
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// event queues an event to be published together with the evictions, after
+// the lock is released.
+func (pantry *Pantry[T]) event(evictions []eviction[T], kind EventKind, key string, value T, now int64) []eviction[T] {
+	if pantry.changes != nil {
+		pantry.changes.record(Event[T]{Kind: kind, Key: key, Value: value, Time: time.Unix(0, now)})
+	}
+
+	pantry.subscribers.mutex.RLock()
+	subscribed := len(pantry.subscribers.channels) > 0
+	pantry.subscribers.mutex.RUnlock()
+
+	if !subscribed {
+		return evictions
+	}
+
+	at := time.Unix(0, now)
+	return append(evictions, eviction[T]{
+		callback: func(key string, value T, _ EvictionReason) {
+			pantry.publish(Event[T]{Kind: kind, Key: key, Value: value, Time: at})
+		},
+		key:   key,
+		value: value,
+	})
+}
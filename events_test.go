@@ -0,0 +1,108 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+	defer p.Close()
+
+	events := p.Subscribe(context.Background(), 8)
+
+	p.Set("key", "value")
+	p.Remove("key")
+	p.SetWithTTL("short", "value", time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	p.removeExpired()
+	p.Clear()
+
+	expected := []EventKind{EventSet, EventRemoved, EventSet, EventExpired, EventCleared}
+	for _, kind := range expected {
+		event := <-events
+		if event.Kind != kind {
+			t.Fatalf("expected %s, got %s", kind, event.Kind)
+		}
+		if event.Time.IsZero() {
+			t.Fatal("missing timestamp")
+		}
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := p.Subscribe(ctx, 1)
+	cancel()
+
+	for range events {
+	}
+
+	p.Set("key", "value")
+}
+
+func TestSubscribeDropsWhenFull(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	events := p.Subscribe(context.Background(), 1)
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+
+	if event := <-events; event.Key != "first" {
+		t.Fatal("unexpected event", event)
+	}
+
+	select {
+	case event := <-events:
+		t.Fatal("unexpected event", event)
+	default:
+	}
+}
+
+func TestSubscribeClosedOnClose(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	events := p.Subscribe(context.Background(), 1)
+	p.Close()
+
+	for range events {
+	}
+}
+
+func TestWatch(t *testing.T) {
+	p := New[string](context.Background(), time.Hour, WithoutBackgroundCleanup[string]())
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := p.Watch(ctx, "config")
+
+	p.Set("other", "ignored")
+	p.Set("config", "v1")
+	p.SetWithTTL("config", "v2", time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	p.PurgeExpired()
+
+	expected := []Event[string]{
+		{Kind: EventSet, Value: "v1"},
+		{Kind: EventSet, Value: "v2"},
+		{Kind: EventExpired, Value: "v2"},
+	}
+	for _, want := range expected {
+		event := <-events
+		if event.Kind != want.Kind || event.Key != "config" || event.Value != want.Value {
+			t.Fatalf("expected %s %s, got %s %s %s", want.Kind, want.Value, event.Kind, event.Key, event.Value)
+		}
+	}
+
+	cancel()
+
+	if _, open := <-events; open {
+		t.Fatal("unexpected event")
+	}
+}
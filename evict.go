@@ -0,0 +1,179 @@
+package pantry
+
+import "time"
+
+type EvictionReason int
+
+const (
+	Expired EvictionReason = iota
+	Removed
+	Replaced
+	Evicted
+)
+
+func (reason EvictionReason) String() string {
+	switch reason {
+	case Expired:
+		return "expired"
+	case Removed:
+		return "removed"
+	case Replaced:
+		return "replaced"
+	case Evicted:
+		return "evicted"
+	default:
+		return "unknown"
+	}
+}
+
+type eviction[T any] struct {
+	callback func(key string, value T, reason EvictionReason)
+	key      string
+	value    T
+	reason   EvictionReason
+}
+
+// OnEvict registers a hook called whenever an entry leaves the pantry because
+// it expired, was removed or was replaced. The hook runs outside the lock.
+func (pantry *Pantry[T]) OnEvict(fn func(key string, value T, reason EvictionReason)) {
+	if fn == nil {
+		pantry.onEvict.Store(nil)
+		return
+	}
+	pantry.onEvict.Store(&fn)
+}
+
+// OnExpireRenew registers a hook called whenever an entry expires. If it
+// returns true, the returned value is stored for the returned TTL, unless the
+// key was set again in the meantime, which keeps expensive entries warm. The
+// hook runs outside the lock after the expiration has been reported, so
+// readers may miss the entry until it returns.
+func (pantry *Pantry[T]) OnExpireRenew(fn func(key string, value T) (T, time.Duration, bool)) {
+	if fn == nil {
+		pantry.onExpireRenew.Store(nil)
+		return
+	}
+	pantry.onExpireRenew.Store(&fn)
+}
+
+// OnExpireEntry registers a hook called with the full entry, including its
+// timestamps and labels, whenever an entry expires, for example to archive
+// expired data. The hook runs outside the lock.
+func (pantry *Pantry[T]) OnExpireEntry(fn func(entry Entry[T])) {
+	if fn == nil {
+		pantry.onExpireEntry.Store(nil)
+		return
+	}
+	pantry.onExpireEntry.Store(&fn)
+}
+
+// renew stores the value renewed by the expiration hook if the key has no
+// live entry.
+func (pantry *Pantry[T]) renew(key string, value T, ttl time.Duration) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	if current, found := shard.store[key]; found && !pantry.isExpired(key, current, now.UnixNano()) {
+		return
+	}
+
+	evictions = pantry.put(evictions, shard, key, item[T]{
+		value:    value,
+		expires:  pantry.expiresAt(now, ttl),
+		accessed: pantry.nextAccess(),
+	}, now.UnixNano())
+	evictions = pantry.writeBack(evictions, key, value, false)
+}
+
+// evict must be called with the shard's write lock held. Entries that already expired
+// are always reported as such.
+func (pantry *Pantry[T]) evict(evictions []eviction[T], key string, item item[T], now int64, reason EvictionReason) []eviction[T] {
+	if pantry.isExpired(key, item, now) {
+		reason = Expired
+	}
+
+	switch reason {
+	case Expired:
+		pantry.counters.expirations.Add(1)
+	case Evicted:
+		pantry.counters.evictions.Add(1)
+	}
+
+	if reason == Expired && item.onExpire != nil {
+		onExpire := item.onExpire
+		evictions = append(evictions, eviction[T]{
+			callback: func(key string, value T, _ EvictionReason) { onExpire(key, value) },
+			key:      key,
+			value:    item.value,
+			reason:   reason,
+		})
+	}
+
+	if reason == Expired {
+		if archive := pantry.onExpireEntry.Load(); archive != nil {
+			entry := pantry.entry(key, item)
+			evictions = append(evictions, eviction[T]{
+				callback: func(string, T, EvictionReason) { (*archive)(entry) },
+				key:      key,
+				value:    item.value,
+			})
+		}
+
+		if renew := pantry.onExpireRenew.Load(); renew != nil {
+			evictions = append(evictions, eviction[T]{
+				callback: func(key string, value T, _ EvictionReason) {
+					if value, ttl, ok := (*renew)(key, value); ok {
+						pantry.renew(key, value, ttl)
+					}
+				},
+				key:   key,
+				value: item.value,
+			})
+		}
+	}
+
+	if onEvict := pantry.onEvict.Load(); onEvict != nil {
+		evictions = append(evictions, eviction[T]{
+			callback: *onEvict,
+			key:      key,
+			value:    item.value,
+			reason:   reason,
+		})
+	}
+
+	if reason == Evicted && pantry.logger != nil {
+		evictions = append(evictions, eviction[T]{
+			callback: func(key string, _ T, _ EvictionReason) {
+				pantry.logger.Debug("pantry: evicted", "key", key)
+			},
+			key:   key,
+			value: item.value,
+		})
+	}
+
+	switch reason {
+	case Expired:
+		evictions = pantry.event(evictions, EventExpired, key, item.value, now)
+	case Removed, Evicted:
+		evictions = pantry.event(evictions, EventRemoved, key, item.value, now)
+	}
+	return evictions
+}
+
+func notify[T any](evictions []eviction[T]) {
+	for _, eviction := range evictions {
+		eviction.callback(eviction.key, eviction.value, eviction.reason)
+	}
+}
@@ -0,0 +1,119 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOnEvict(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	reasons := make(map[string]EvictionReason)
+	p.OnEvict(func(key string, value int, reason EvictionReason) {
+		reasons[key] = reason
+	})
+
+	p.Set("removed", 1)
+	p.Remove("removed")
+
+	p.Set("replaced", 1)
+	p.Set("replaced", 2)
+
+	p.SetWithTTL("expired", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	p.removeExpired()
+
+	expected := map[string]EvictionReason{
+		"removed":  Removed,
+		"replaced": Replaced,
+		"expired":  Expired,
+	}
+
+	for key, reason := range expected {
+		if reasons[key] != reason {
+			t.Log(reasons)
+			t.Fatalf("%s not %s", key, reason)
+		}
+	}
+}
+
+func TestOnEvictClear(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	counter := 0
+	p.OnEvict(func(key string, value int, reason EvictionReason) {
+		if reason != Removed {
+			t.Fatal("not removed")
+		}
+		counter++
+	})
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Clear()
+
+	if counter != 2 {
+		t.Fatal("not 2 evictions")
+	}
+
+	if !p.IsEmpty() {
+		t.Fatal("not empty")
+	}
+}
+
+func TestOnEvictReentrant(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.OnEvict(func(key string, value int, reason EvictionReason) {
+		p.Get(key)
+	})
+
+	p.Set("first", 1)
+	p.Remove("first")
+}
+
+func TestOnExpireRenew(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.OnExpireRenew(func(key string, value int) (int, time.Duration, bool) {
+		return value + 1, time.Hour, key == "warm"
+	})
+
+	p.SetWithTTL("warm", 1, time.Millisecond)
+	p.SetWithTTL("cold", 1, time.Millisecond)
+
+	time.Sleep(2 * time.Millisecond)
+	p.PurgeExpired()
+
+	if value, found := p.Get("warm"); !found || value != 2 {
+		t.Fatal("warm entry not renewed", value, found)
+	}
+	if ttl, _ := p.TTL("warm"); ttl < time.Minute {
+		t.Fatal("renewed with the wrong TTL", ttl)
+	}
+	if _, found := p.Get("cold"); found {
+		t.Fatal("cold entry renewed")
+	}
+}
+
+func TestOnExpireEntry(t *testing.T) {
+	p := New[string](context.Background(), time.Hour, WithoutBackgroundCleanup[string]())
+
+	var archived []Entry[string]
+	p.OnExpireEntry(func(entry Entry[string]) { archived = append(archived, entry) })
+
+	p.SetWithMeta("old", "value", map[string]string{"source": "primary"})
+	p.SetWithTTL("expiring", "value", time.Millisecond)
+	p.Remove("old")
+	time.Sleep(2 * time.Millisecond)
+	p.PurgeExpired()
+
+	if len(archived) != 1 {
+		t.Fatal("unexpected archived entries", archived)
+	}
+	entry := archived[0]
+	if entry.Key != "expiring" || entry.Value != "value" || entry.CreatedAt.IsZero() || entry.ExpiresAt.After(time.Now()) {
+		t.Fatal("unexpected entry", entry)
+	}
+}
@@ -0,0 +1,142 @@
+package pantry
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"time"
+)
+
+// NoExpiration, used as the expiration of New or the TTL of SetWithTTL, keeps
+// entries until they are removed. The cleanup skips them and TTL reports
+// NoExpiration for them.
+const NoExpiration time.Duration = -1
+
+const neverExpires = math.MaxInt64
+
+// ErrInvalidConfig is wrapped by the errors of NewValidated.
+var ErrInvalidConfig = errors.New("pantry: invalid configuration")
+
+// expiresAfter returns the deadline ttl after now, saturating instead of
+// overflowing.
+func expiresAfter(now int64, ttl time.Duration) int64 {
+	if ttl == NoExpiration || ttl > 0 && now > neverExpires-int64(ttl) {
+		return neverExpires
+	}
+	return now + int64(ttl)
+}
+
+type expirationEntry struct {
+	key     string
+	expires int64
+}
+
+// expirationHeap orders keys by their deadline. Entries are never updated in
+// place: a new deadline pushes a new entry and outdated ones are discarded
+// when they reach the top.
+type expirationHeap []expirationEntry
+
+func (h expirationHeap) Len() int           { return len(h) }
+func (h expirationHeap) Less(i, j int) bool { return h[i].expires < h[j].expires }
+func (h expirationHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *expirationHeap) Push(x any) {
+	*h = append(*h, x.(expirationEntry))
+}
+
+func (h *expirationHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// push and pop are heap.Push and heap.Pop without boxing the entry in an
+// interface, which would allocate on every write.
+func (h *expirationHeap) push(entry expirationEntry) {
+	*h = append(*h, entry)
+	heap.Fix(h, len(*h)-1)
+}
+
+func (h *expirationHeap) pop() expirationEntry {
+	last := len(*h) - 1
+	h.Swap(0, last)
+	entry := (*h)[last]
+	(*h)[last] = expirationEntry{}
+	*h = (*h)[:last]
+	if last > 0 {
+		heap.Fix(h, 0)
+	}
+	return entry
+}
+
+func expirationTime(expires int64) time.Time {
+	if expires == neverExpires {
+		return time.Time{}
+	}
+	return time.Unix(0, expires)
+}
+
+// schedule must be called with the shard's write lock held. Rewriting a key
+// leaves its previous deadline behind, so the heap is compacted here as well
+// as by the cleanup, keeping it bounded when the cleanup does not run.
+func (shard *shard[T]) schedule(key string, expires int64) {
+	if expires == neverExpires {
+		return
+	}
+	shard.expirations.push(expirationEntry{key: key, expires: expires})
+	shard.compact()
+}
+
+// current reports whether the heap entry still matches the stored item.
+func (shard *shard[T]) current(entry expirationEntry) bool {
+	item, found := shard.store[entry.key]
+	return found && item.expires == entry.expires
+}
+
+// discardOutdated must be called with the shard's write lock held.
+func (shard *shard[T]) discardOutdated() {
+	for len(shard.expirations) > 0 && !shard.current(shard.expirations[0]) {
+		shard.expirations.pop()
+	}
+}
+
+// compact must be called with the shard's write lock held. It rebuilds the
+// heap once outdated entries dominate it.
+func (shard *shard[T]) compact() {
+	if len(shard.expirations) <= 2*len(shard.store)+64 {
+		return
+	}
+
+	expirations := make(expirationHeap, 0, len(shard.store))
+	for key, item := range shard.store {
+		if item.expires != neverExpires {
+			expirations = append(expirations, expirationEntry{key: key, expires: item.expires})
+		}
+	}
+	heap.Init(&expirations)
+	shard.expirations = expirations
+}
+
+// NextExpiration returns the earliest deadline among the stored entries.
+func (pantry *Pantry[T]) NextExpiration() (time.Time, bool) {
+	var next int64
+	found := false
+
+	for _, shard := range pantry.shards {
+		shard.mutex.Lock()
+		shard.discardOutdated()
+		if len(shard.expirations) > 0 {
+			expires := shard.expirations[0].expires
+			if !found || expires < next {
+				next, found = expires, true
+			}
+		}
+		shard.mutex.Unlock()
+	}
+
+	if !found {
+		return time.Time{}, false
+	}
+	return time.Unix(0, next), true
+}
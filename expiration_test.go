@@ -0,0 +1,343 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNextExpiration(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	if _, found := p.NextExpiration(); found {
+		t.Fatal("found on empty")
+	}
+
+	p.Set("first", 1)
+	p.SetWithTTL("second", 2, time.Minute)
+
+	_, expires, _ := p.GetWithExpiration("second")
+
+	next, found := p.NextExpiration()
+	if !found || !next.Equal(expires) {
+		t.Fatal("wrong next expiration")
+	}
+
+	p.Remove("second")
+
+	_, expires, _ = p.GetWithExpiration("first")
+
+	if next, _ := p.NextExpiration(); !next.Equal(expires) {
+		t.Fatal("removed entry reported")
+	}
+}
+
+func TestExpirationHeapOverwrite(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.SetWithTTL(t.Name(), 1, time.Millisecond)
+	p.Set(t.Name(), 2)
+
+	time.Sleep(5 * time.Millisecond)
+	p.removeExpired()
+
+	if value, found := p.Get(t.Name()); !found || value != 2 {
+		t.Fatal("overwritten entry expired")
+	}
+}
+
+func TestExpirationHeapExtend(t *testing.T) {
+	p := New[int](context.Background(), 5*time.Millisecond)
+
+	p.Set(t.Name(), 1)
+	p.Extend(t.Name(), time.Hour)
+
+	time.Sleep(10 * time.Millisecond)
+	p.removeExpired()
+
+	if _, found := p.Get(t.Name()); !found {
+		t.Fatal("extended entry expired")
+	}
+}
+
+func TestExpirationHeapCompaction(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithShards[int](1))
+
+	for i := 0; i < 1000; i++ {
+		p.Set("key", i)
+	}
+
+	p.removeExpired()
+
+	if len(p.shards[0].expirations) > 100 {
+		t.Fatal("not compacted")
+	}
+}
+
+func TestExpirationHeapBoundedWithoutCleanup(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithShards[int](1), WithoutBackgroundCleanup[int]())
+
+	p.SetWithTTL("forever", 0, NoExpiration)
+	for i := range 100_000 {
+		p.Set("key", i)
+		p.Extend("key", time.Minute)
+	}
+
+	shard := p.shards[0]
+	if n := len(shard.expirations); n > 2*len(shard.store)+64 {
+		t.Fatal("heap not compacted", n)
+	}
+	for _, entry := range shard.expirations {
+		if entry.key == "forever" {
+			t.Fatal("never expiring entry scheduled")
+		}
+	}
+}
+
+func BenchmarkRemoveExpired(b *testing.B) {
+	p := New[int](context.Background(), time.Hour)
+
+	for i := 0; i < 100_000; i++ {
+		p.Set(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.removeExpired()
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithoutBackgroundCleanup[int]())
+
+	p.SetWithTTL("first", 1, time.Millisecond)
+	p.SetWithTTL("second", 2, time.Millisecond)
+	p.Set("third", 3)
+
+	time.Sleep(2 * time.Millisecond)
+
+	if removed := p.PurgeExpired(); removed != 2 {
+		t.Fatal("unexpected removed count", removed)
+	}
+
+	if items := p.Stats().Items; items != 1 {
+		t.Fatal("expired entries kept", items)
+	}
+
+	if removed := p.PurgeExpired(); removed != 0 {
+		t.Fatal("removed twice", removed)
+	}
+}
+
+func TestSetWithExpireAt(t *testing.T) {
+	p := New[int](context.Background(), time.Hour, WithoutBackgroundCleanup[int]())
+
+	at := time.Now().Add(20 * time.Millisecond)
+	p.SetWithExpireAt("key", 1, at)
+
+	if _, expires, found := p.GetWithExpiration("key"); !found || !expires.Equal(at) {
+		t.Fatal("unexpected expiration", expires, found)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+
+	if removed := p.PurgeExpired(); removed != 1 {
+		t.Fatal("not removed by cleanup", removed)
+	}
+
+	p.SetWithExpireAt("past", 1, time.Now().Add(-time.Second))
+
+	if _, found := p.Get("past"); found {
+		t.Fatal("found entry expiring in the past")
+	}
+}
+
+func TestSetFromContext(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := p.SetFromContext(ctx, "short", 1); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, _ := p.TTL("short"); ttl > time.Minute || ttl < 59*time.Second {
+		t.Fatal("deadline not applied", ttl)
+	}
+
+	long, cancelLong := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancelLong()
+
+	p.SetFromContext(long, "capped", 1)
+	if ttl, _ := p.TTL("capped"); ttl > time.Hour {
+		t.Fatal("not capped by the default TTL", ttl)
+	}
+
+	p.SetFromContext(context.Background(), "default", 1)
+	if ttl, _ := p.TTL("default"); ttl < 59*time.Minute {
+		t.Fatal("default TTL not applied", ttl)
+	}
+
+	cancel()
+	if err := p.SetFromContext(ctx, "canceled", 1); err == nil || p.Contains("canceled") {
+		t.Fatal("stored with a canceled context", err)
+	}
+}
+
+func TestNoExpiration(t *testing.T) {
+	p := New[int](context.Background(), NoExpiration, WithoutBackgroundCleanup[int]())
+
+	p.Set("default", 1)
+	p.SetWithTTL("short", 2, time.Millisecond)
+	p.SetWithTTL("forever", 3, NoExpiration)
+
+	time.Sleep(2 * time.Millisecond)
+
+	if removed := p.PurgeExpired(); removed != 1 {
+		t.Fatal("unexpected removed count", removed)
+	}
+
+	for _, key := range []string{"default", "forever"} {
+		if ttl, found := p.TTL(key); !found || ttl != NoExpiration {
+			t.Fatal("unexpected ttl", key, ttl, found)
+		}
+
+		if _, expires, _ := p.GetWithExpiration(key); !expires.IsZero() {
+			t.Fatal("unexpected expiration", key, expires)
+		}
+	}
+
+	if !p.Extend("forever", time.Hour) {
+		t.Fatal("not extended")
+	}
+
+	if ttl, _ := p.TTL("forever"); ttl != NoExpiration {
+		t.Fatal("extend overflowed", ttl)
+	}
+}
+
+func TestTTLBounds(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithTTLBounds[int](time.Minute, 2*time.Hour))
+
+	p.SetWithTTL("short", 1, time.Millisecond)
+	p.SetWithTTL("long", 2, 100*time.Hour)
+	p.SetWithTTL("forever", 3, NoExpiration)
+	p.Set("default", 4)
+
+	for key, want := range map[string]time.Duration{
+		"short":   time.Minute,
+		"long":    2 * time.Hour,
+		"forever": 2 * time.Hour,
+		"default": time.Hour,
+	} {
+		if ttl, _ := p.TTL(key); ttl > want || ttl < want-time.Second {
+			t.Errorf("%s: expected a TTL of %v, got %v", key, want, ttl)
+		}
+	}
+}
+
+func TestNewValidated(t *testing.T) {
+	for _, expiration := range []time.Duration{0, -time.Second} {
+		if _, err := NewValidated[int](context.Background(), expiration); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected an error for %v, got %v", expiration, err)
+		}
+
+		// New keeps accepting it.
+		New[int](context.Background(), expiration).Close()
+	}
+
+	if _, err := NewValidated(context.Background(), time.Hour, WithTTLBounds[int](time.Hour, time.Minute)); !errors.Is(err, ErrInvalidConfig) {
+		t.Error("invalid bounds accepted", err)
+	}
+
+	p, err := NewValidated(context.Background(), NoExpiration, WithTTLBounds[int](time.Minute, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+}
+
+func TestInvalidOptions(t *testing.T) {
+	sweeper := NewSweeper(context.Background(), 0)
+	defer sweeper.Close()
+
+	options := map[string]Option[int]{
+		"AdaptiveCleanup":         WithAdaptiveCleanup[int](time.Minute, time.Second),
+		"AutoSnapshot":            WithAutoSnapshot[int](time.Minute, nil, 0),
+		"ChangeLog":               WithChangeLog[int](0),
+		"ContentionStats":         WithContentionStats[int](0),
+		"Encryption":              WithEncryption[int](nil),
+		"HotKeys":                 WithHotKeys[int](0, 1),
+		"MaxConcurrentIterations": WithMaxConcurrentIterations[int](0),
+		"MemoryPressure":          WithMemoryPressure[int](0, 2),
+		"MissFilter":              WithMissFilter[int](10, 1),
+		"PersistRetry":            WithPersistRetry[int](0, time.Second),
+		"RefreshAhead":            WithRefreshAhead[int](1),
+		"Shards":                  WithShards[int](0),
+		"SoftTTL":                 WithSoftTTL[int](0),
+		"Sweeper":                 WithSweeper[int](sweeper),
+		"TTLBounds":               WithTTLBounds[int](time.Hour, time.Minute),
+		"TTLJitter":               WithTTLJitter[int](1.5),
+	}
+
+	for name, option := range options {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewValidated(context.Background(), time.Hour, option); !errors.Is(err, ErrInvalidConfig) {
+				t.Fatal("invalid option accepted", err)
+			}
+
+			logger, buffer := newTestLogger()
+			p := New(context.Background(), time.Hour, WithLogger[int](logger), option)
+			defer p.Close()
+
+			p.Set("key", 1)
+			if value, _ := p.Get("key"); value != 1 {
+				t.Fatal("pantry unusable", value)
+			}
+			if !strings.Contains(buffer.String(), "option ignored") {
+				t.Fatal("not reported", buffer.String())
+			}
+		})
+	}
+}
+
+func TestExtendTTLBounds(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithTTLBounds[int](time.Minute, 2*time.Hour))
+	defer p.Close()
+
+	p.Set("long", 1)
+	p.Extend("long", 10*time.Hour)
+	if ttl, _ := p.TTL("long"); ttl > 2*time.Hour {
+		t.Fatal("extended past the maximum TTL", ttl)
+	}
+
+	p.Set("short", 2)
+	p.Extend("short", -time.Hour+time.Second)
+	if ttl, _ := p.TTL("short"); ttl < time.Minute-time.Second {
+		t.Fatal("shortened below the minimum TTL", ttl)
+	}
+}
+
+func TestTTLPolicy(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithTTLPolicy(func(key string, value time.Duration) time.Duration {
+		return value
+	}))
+
+	p.Set("short", time.Minute)
+	p.GetOrSet("long", 2*time.Hour)
+	p.SetWithTTL("explicit", time.Minute, 3*time.Hour)
+
+	for key, want := range map[string]time.Duration{
+		"short":    time.Minute,
+		"long":     2 * time.Hour,
+		"explicit": 3 * time.Hour,
+	} {
+		if ttl, _ := p.TTL(key); ttl > want || ttl < want-time.Second {
+			t.Errorf("%s: expected a TTL of %v, got %v", key, want, ttl)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package pantry
+
+import (
+	"cmp"
+	"iter"
+	"math"
+	"slices"
+	"time"
+)
+
+// OldestN returns the n live entries closest to expiring, soonest first.
+// Entries that never expire are left out. It reads the expiration heaps, so
+// it costs a copy of them rather than a sort of every entry.
+func (pantry *Pantry[T]) OldestN(n int) []Entry[T] {
+	if n <= 0 {
+		return nil
+	}
+
+	pantry.rlockAll()
+	defer pantry.runlockAll()
+
+	entries := pantry.soonestExpiring(n, neverExpires-1)
+	for i := range entries {
+		entries[i].Value = pantry.clone(entries[i].Value)
+	}
+	return entries
+}
+
+// ExpiringWithin yields the live entries expiring within d from now, soonest
+// first, for refreshing them ahead of time. The entries are collected before
+// the first one is yielded.
+func (pantry *Pantry[T]) ExpiringWithin(d time.Duration) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		pantry.rlockAll()
+		deadline := expiresAfter(pantry.clock.Now().UnixNano(), d)
+		entries := pantry.soonestExpiring(math.MaxInt, min(deadline, neverExpires-1))
+		pantry.runlockAll()
+
+		for _, entry := range entries {
+			if !yield(entry.Key, pantry.clone(entry.Value)) {
+				return
+			}
+		}
+	}
+}
+
+// Permanent yields the live entries stored with NoExpiration, which the
+// janitor never reaps, so they can be audited and removed by hand. Like All,
+// it iterates over a snapshot.
+func (pantry *Pantry[T]) Permanent() iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		release := pantry.acquireIteration()
+
+		pantry.rlockAll()
+		now := pantry.clock.Now().UnixNano()
+		var entries []Entry[T]
+		for key, item := range pantry.items() {
+			if item.expires == neverExpires && !pantry.isExpired(key, item, now) {
+				entries = append(entries, Entry[T]{Key: key, Value: item.value})
+			}
+		}
+		pantry.runlockAll()
+		release()
+
+		if pantry.stableOrder {
+			slices.SortFunc(entries, func(a, b Entry[T]) int {
+				return cmp.Compare(a.Key, b.Key)
+			})
+		}
+
+		for _, entry := range entries {
+			if !yield(entry.Key, pantry.clone(entry.Value)) {
+				return
+			}
+		}
+	}
+}
+
+// soonestExpiring returns up to n live entries expiring no later than
+// deadline, soonest first, and must be called with all shards locked. Each
+// shard's heap is copied and popped, so only the entries taken are ordered.
+func (pantry *Pantry[T]) soonestExpiring(n int, deadline int64) []Entry[T] {
+	type expiring struct {
+		entry   Entry[T]
+		expires int64
+	}
+
+	now := pantry.clock.Now().UnixNano()
+	var candidates []expiring
+	for _, shard := range pantry.shards {
+		expirations := slices.Clone(shard.expirations)
+		seen := make(map[string]struct{})
+		for taken := 0; taken < n && len(expirations) > 0 && expirations[0].expires <= deadline; {
+			next := expirations.pop()
+			if !shard.current(next) {
+				continue
+			}
+			if _, found := seen[next.key]; found {
+				continue
+			}
+			seen[next.key] = struct{}{}
+
+			item := shard.store[next.key]
+			if pantry.isExpired(next.key, item, now) {
+				continue
+			}
+
+			candidates = append(candidates, expiring{entry: pantry.entry(next.key, item), expires: item.expires})
+			taken++
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b expiring) int {
+		return cmp.Compare(a.expires, b.expires)
+	})
+
+	entries := make([]Entry[T], 0, min(n, len(candidates)))
+	for _, candidate := range candidates[:min(n, len(candidates))] {
+		entries = append(entries, candidate.entry)
+	}
+	return entries
+}
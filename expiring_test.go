@@ -0,0 +1,79 @@
+package pantry
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOldestN(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.SetWithTTL("late", 3, 3*time.Minute)
+	p.SetWithTTL("soon", 1, time.Minute)
+	p.SetWithTTL("forever", 0, NoExpiration)
+	p.SetWithTTL("middle", 2, 2*time.Minute)
+	p.SetWithTTL("soon", 1, 90*time.Second)
+
+	var keys []string
+	for _, entry := range p.OldestN(2) {
+		keys = append(keys, entry.Key)
+	}
+	if !slices.Equal(keys, []string{"soon", "middle"}) {
+		t.Fatal("unexpected order", keys)
+	}
+
+	if entries := p.OldestN(10); len(entries) != 3 {
+		t.Fatal("unexpected entries", entries)
+	}
+}
+
+func TestExpiringWithin(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.SetWithTTL("second", 2, 2*time.Minute)
+	p.SetWithTTL("first", 1, time.Minute)
+	p.SetWithTTL("later", 3, 10*time.Minute)
+	p.SetWithTTL("forever", 0, NoExpiration)
+
+	var keys []string
+	for key := range p.ExpiringWithin(5 * time.Minute) {
+		keys = append(keys, key)
+	}
+	if !slices.Equal(keys, []string{"first", "second"}) {
+		t.Fatal("unexpected keys", keys)
+	}
+
+	count := 0
+	for range p.ExpiringWithin(NoExpiration) {
+		count++
+	}
+	if count != 3 {
+		t.Fatal("unexpected count", count)
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("default", 1)
+	p.SetWithTTL("short", 2, time.Minute)
+	p.SetWithTTL("forever", 3, NoExpiration)
+	p.SetWithTTL("always", 4, NoExpiration)
+
+	permanent := make(map[string]int)
+	for key, value := range p.Permanent() {
+		permanent[key] = value
+	}
+	if len(permanent) != 2 || permanent["forever"] != 3 || permanent["always"] != 4 {
+		t.Fatal("unexpected entries", permanent)
+	}
+
+	p.SetWithTTL("forever", 3, time.Minute)
+	for key := range p.Permanent() {
+		if key != "always" {
+			t.Fatal("unexpected entry", key)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package pantry
+
+import (
+	"hash/maphash"
+	"math"
+	"sync/atomic"
+)
+
+// WithMissFilter puts a bloom filter sized for expectedItems with a false
+// positive rate of fpRate in front of each shard, so Get and GetE can reject
+// keys that were never set without taking the lock. The filter only ever
+// grows, so the cleanup rebuilds it once enough entries have left or the
+// pantry outgrew it. Pays off when most reads miss. The arguments must
+// satisfy expectedItems > 0 and 0 < fpRate < 1.
+func WithMissFilter[T any](expectedItems int, fpRate float64) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if expectedItems <= 0 || fpRate <= 0 || fpRate >= 1 {
+			pantry.invalidOption("miss filter for %d items at %v needs expectedItems > 0 and 0 < fpRate < 1", expectedItems, fpRate)
+			return
+		}
+		pantry.filterItems, pantry.filterRate = expectedItems, fpRate
+	}
+}
+
+type bloomFilter struct {
+	bits     []atomic.Uint64
+	hashes   int
+	capacity int
+	seed     maphash.Seed
+}
+
+func newBloomFilter(capacity int, fpRate float64) *bloomFilter {
+	capacity = max(capacity, 1)
+	size := int(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	words := (size + 63) / 64
+	hashes := int(math.Round(float64(words*64) / float64(capacity) * math.Ln2))
+
+	return &bloomFilter{
+		bits:     make([]atomic.Uint64, words),
+		hashes:   max(hashes, 1),
+		capacity: capacity,
+		seed:     maphash.MakeSeed(),
+	}
+}
+
+// positions derives the bit positions from a single hash by double hashing.
+func (filter *bloomFilter) positions(key string, fn func(word int, bit uint64) bool) {
+	hash := maphash.String(filter.seed, key)
+	h1, h2 := hash&math.MaxUint32, hash>>32|1
+	size := uint64(len(filter.bits) * 64)
+
+	for i := range uint64(filter.hashes) {
+		position := (h1 + i*h2) % size
+		if !fn(int(position/64), 1<<(position%64)) {
+			return
+		}
+	}
+}
+
+func (filter *bloomFilter) add(key string) {
+	filter.positions(key, func(word int, bit uint64) bool {
+		if filter.bits[word].Load()&bit == 0 {
+			filter.bits[word].Or(bit)
+		}
+		return true
+	})
+}
+
+func (filter *bloomFilter) contains(key string) bool {
+	found := true
+	filter.positions(key, func(word int, bit uint64) bool {
+		found = filter.bits[word].Load()&bit != 0
+		return found
+	})
+	return found
+}
+
+// mayContain reports false only for keys that are neither stored in the shard
+// nor recorded as missing. It is safe to call without the lock.
+func (shard *shard[T]) mayContain(key string) bool {
+	filter := shard.filter.Load()
+	return filter == nil || filter.contains(key)
+}
+
+// admit must be called with the shard's write lock held.
+func (shard *shard[T]) admit(key string) {
+	if filter := shard.filter.Load(); filter != nil {
+		filter.add(key)
+	}
+}
+
+// rebuildFilter must be called with the shard's write lock held. It starts
+// a new filter when a tenth of its capacity went stale or the shard no
+// longer fits.
+func (pantry *Pantry[T]) rebuildFilter(shard *shard[T], force bool) {
+	if pantry.filterItems == 0 {
+		return
+	}
+
+	capacity := pantry.filterItems / len(pantry.shards)
+	if filter := shard.filter.Load(); filter != nil && !force {
+		size := len(shard.store) + len(shard.misses)
+		if shard.filterStale <= filter.capacity/10 && size <= filter.capacity {
+			return
+		}
+		capacity = max(capacity, size)
+	}
+
+	filter := newBloomFilter(capacity, pantry.filterRate)
+	for key := range shard.store {
+		filter.add(key)
+	}
+	for key := range shard.misses {
+		filter.add(key)
+	}
+	shard.filter.Store(filter)
+	shard.filterStale = 0
+}
@@ -0,0 +1,82 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBloomFilter(t *testing.T) {
+	filter := newBloomFilter(1000, 0.01)
+	for i := range 1000 {
+		filter.add(strconv.Itoa(i))
+	}
+
+	falsePositives := 0
+	for i := range 1000 {
+		if !filter.contains(strconv.Itoa(i)) {
+			t.Fatal("false negative", i)
+		}
+		if filter.contains("missing" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+
+	if falsePositives > 30 {
+		t.Fatal("too many false positives", falsePositives)
+	}
+}
+
+func TestMissFilter(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithMissFilter[string](64, 0.01))
+	defer p.Close()
+
+	for i := range 200 {
+		p.Set(strconv.Itoa(i), "value")
+	}
+	for i := range 200 {
+		if _, found := p.Get(strconv.Itoa(i)); !found {
+			t.Fatal("rejected stored key", i)
+		}
+	}
+
+	for i := range 150 {
+		p.Remove(strconv.Itoa(i))
+	}
+	p.PurgeExpired()
+
+	for i := range 200 {
+		_, found := p.Get(strconv.Itoa(i))
+		if found != (i >= 150) {
+			t.Fatal("unexpected result after rebuild", i, found)
+		}
+	}
+
+	if _, err := p.GetE("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("unexpected error", err)
+	}
+	if stats := p.Stats(); stats.Misses != 151 {
+		t.Fatal("misses not counted", stats.Misses)
+	}
+
+	p.Clear()
+	p.Set("key", "value")
+	if _, found := p.Get("key"); !found {
+		t.Fatal("rejected key after clear")
+	}
+}
+
+func TestMissFilterNegativeTTL(t *testing.T) {
+	p := New(context.Background(), time.Hour,
+		WithMissFilter[string](64, 0.01),
+		WithNegativeTTL[string](time.Hour),
+	)
+	defer p.Close()
+
+	p.SetNegative("key")
+	if _, err := p.GetE("key"); !errors.Is(err, ErrCachedMiss) {
+		t.Fatal("cached miss rejected by the filter", err)
+	}
+}
@@ -0,0 +1,105 @@
+package pantry
+
+import "strings"
+
+// Match reports whether key matches the glob pattern used by RemoveMatch,
+// which supports *, ?, [abc], [^abc], [a-z] and backslash escapes. Unlike
+// path.Match, * also matches separators.
+func Match(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if Match(pattern, key[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			rest, matched := matchClass(pattern[1:], key[0])
+			if !matched {
+				return false
+			}
+			pattern, key = rest, key[1:]
+
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		}
+	}
+	return len(key) == 0
+}
+
+// matchClass matches c against the class following an opening bracket and
+// returns the pattern after the closing bracket.
+func matchClass(pattern string, c byte) (string, bool) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		low := pattern[0]
+		if low == '\\' && len(pattern) > 1 {
+			pattern = pattern[1:]
+			low = pattern[0]
+		}
+		pattern = pattern[1:]
+
+		high := low
+		if len(pattern) > 1 && pattern[0] == '-' && pattern[1] != ']' {
+			high = pattern[1]
+			pattern = pattern[2:]
+		}
+
+		if low > high {
+			low, high = high, low
+		}
+		if low <= c && c <= high {
+			matched = true
+		}
+	}
+
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return pattern, matched != negate
+}
+
+// RemovePrefix removes every entry whose key starts with prefix under a
+// single write lock and returns how many live entries were removed.
+func (pantry *Pantry[T]) RemovePrefix(prefix string) int {
+	return pantry.RemoveWhere(func(key string, _ T) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// RemoveMatch removes every entry whose key matches the glob pattern under a
+// single write lock and returns how many live entries were removed.
+func (pantry *Pantry[T]) RemoveMatch(pattern string) int {
+	return pantry.RemoveWhere(func(key string, _ T) bool {
+		return Match(pattern, key)
+	})
+}
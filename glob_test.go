@@ -0,0 +1,67 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		key     string
+		matches bool
+	}{
+		{"*", "anything", true},
+		{"user:*", "user:1", true},
+		{"user:*", "session:1", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"a/*", "a/b/c", true},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+	}
+
+	for _, c := range cases {
+		if Match(c.pattern, c.key) != c.matches {
+			t.Errorf("match(%q, %q) != %v", c.pattern, c.key, c.matches)
+		}
+	}
+}
+
+func TestRemovePrefix(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.Set("user:123:name", 1)
+	p.Set("user:123:email", 2)
+	p.Set("user:456:name", 3)
+
+	if removed := p.RemovePrefix("user:123:"); removed != 2 {
+		t.Fatal("unexpected removed count", removed)
+	}
+
+	if _, found := p.Get("user:456:name"); !found {
+		t.Fatal("removed outside prefix")
+	}
+}
+
+func TestRemoveMatch(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.Set("user:123:name", 1)
+	p.Set("user:456:name", 2)
+	p.Set("user:456:email", 3)
+
+	if removed := p.RemoveMatch("user:*:name"); removed != 2 {
+		t.Fatal("unexpected removed count", removed)
+	}
+
+	if _, found := p.Get("user:456:email"); !found {
+		t.Fatal("removed outside pattern")
+	}
+}
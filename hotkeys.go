@@ -0,0 +1,151 @@
+package pantry
+
+import (
+	"cmp"
+	"hash/maphash"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+)
+
+// KeyStat is the estimated number of reads of a key.
+type KeyStat struct {
+	Key   string
+	Count uint64
+}
+
+const sketchDepth = 4
+
+// WithHotKeys samples one in sampleEvery reads into a count-min sketch and
+// keeps the capacity most read keys for TopKeys. Counts are halved now and
+// then, so keys that cooled down make room for new hot spots. Both arguments
+// must be positive.
+func WithHotKeys[T any](capacity, sampleEvery int) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if capacity <= 0 || sampleEvery <= 0 {
+			pantry.invalidOption("hot keys need capacity %d > 0 and sampleEvery %d > 0", capacity, sampleEvery)
+			return
+		}
+		pantry.hotKeys = newHotKeys(capacity, sampleEvery)
+	}
+}
+
+// TopKeys returns up to n of the most read keys, most read first, with
+// their read counts estimated from the samples. Reads are counted by Get,
+// GetE, GetContext and GetWithExpiration, hits and misses alike. It returns
+// nil without WithHotKeys.
+func (pantry *Pantry[T]) TopKeys(n int) []KeyStat {
+	if pantry.hotKeys == nil || n <= 0 {
+		return nil
+	}
+	return pantry.hotKeys.top(n)
+}
+
+type hotKeys struct {
+	mutex       sync.Mutex
+	sketch      [sketchDepth][]uint32
+	seed        maphash.Seed
+	samples     int
+	capacity    int
+	sampleEvery int
+	candidates  map[string]uint32
+}
+
+func newHotKeys(capacity, sampleEvery int) *hotKeys {
+	hot := &hotKeys{
+		seed:        maphash.MakeSeed(),
+		capacity:    capacity,
+		sampleEvery: sampleEvery,
+		candidates:  make(map[string]uint32, capacity),
+	}
+
+	// Sixteen counters per tracked key keep collisions among the hot keys
+	// rare.
+	width := 1 << max(8, bitsFor(capacity*16))
+	for row := range hot.sketch {
+		hot.sketch[row] = make([]uint32, width)
+	}
+	return hot
+}
+
+func bitsFor(n int) int {
+	bits := 0
+	for 1<<bits < n {
+		bits++
+	}
+	return bits
+}
+
+func (hot *hotKeys) record(key string) {
+	if hot.sampleEvery > 1 && rand.IntN(hot.sampleEvery) != 0 {
+		return
+	}
+
+	hot.mutex.Lock()
+	defer hot.mutex.Unlock()
+
+	hash := maphash.String(hot.seed, key)
+	h1, h2 := hash&math.MaxUint32, hash>>32|1
+	width := uint64(len(hot.sketch[0]))
+
+	count := uint32(math.MaxUint32)
+	for row := range hot.sketch {
+		counter := &hot.sketch[row][(h1+uint64(row)*h2)%width]
+		if *counter < math.MaxUint32 {
+			*counter++
+		}
+		count = min(count, *counter)
+	}
+
+	hot.samples++
+	if hot.samples >= 10*len(hot.sketch[0]) {
+		hot.age()
+	}
+
+	if _, found := hot.candidates[key]; found || len(hot.candidates) < hot.capacity {
+		hot.candidates[key] = count
+		return
+	}
+
+	coldest, coldestCount := "", uint32(math.MaxUint32)
+	for candidate, candidateCount := range hot.candidates {
+		if candidateCount < coldestCount {
+			coldest, coldestCount = candidate, candidateCount
+		}
+	}
+	if count > coldestCount {
+		delete(hot.candidates, coldest)
+		hot.candidates[key] = count
+	}
+}
+
+// age must be called with the mutex held.
+func (hot *hotKeys) age() {
+	for row := range hot.sketch {
+		for i := range hot.sketch[row] {
+			hot.sketch[row][i] /= 2
+		}
+	}
+	for key, count := range hot.candidates {
+		hot.candidates[key] = count / 2
+	}
+	hot.samples /= 2
+}
+
+func (hot *hotKeys) top(n int) []KeyStat {
+	hot.mutex.Lock()
+	stats := make([]KeyStat, 0, len(hot.candidates))
+	for key, count := range hot.candidates {
+		stats = append(stats, KeyStat{Key: key, Count: uint64(count) * uint64(hot.sampleEvery)})
+	}
+	hot.mutex.Unlock()
+
+	slices.SortFunc(stats, func(a, b KeyStat) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return stats[:min(n, len(stats))]
+}
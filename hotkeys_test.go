@@ -0,0 +1,58 @@
+package pantry
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTopKeys(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithHotKeys[string](4, 1))
+	defer p.Close()
+
+	p.Set("hot", "value")
+	for i := range 1000 {
+		p.Get("hot")
+		if i%2 == 0 {
+			p.Get("warm")
+		}
+		p.Get("cold" + strconv.Itoa(i))
+	}
+
+	top := p.TopKeys(2)
+	if len(top) != 2 || top[0].Key != "hot" || top[1].Key != "warm" {
+		t.Fatal("unexpected top keys", top)
+	}
+	if top[0].Count < 1000 || top[1].Count < 500 {
+		t.Fatal("counts underestimated", top)
+	}
+
+	if len(p.TopKeys(10)) != 4 {
+		t.Fatal("more keys than the capacity tracked")
+	}
+}
+
+func TestTopKeysSampled(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithHotKeys[string](4, 10))
+	defer p.Close()
+
+	for range 10000 {
+		p.Get("hot")
+	}
+
+	top := p.TopKeys(1)
+	if len(top) != 1 || top[0].Key != "hot" || top[0].Count < 5000 || top[0].Count > 15000 {
+		t.Fatal("unexpected estimate", top)
+	}
+}
+
+func TestTopKeysDisabled(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.Get("key")
+	if p.TopKeys(1) != nil {
+		t.Fatal("keys tracked without WithHotKeys")
+	}
+}
@@ -0,0 +1,303 @@
+// Package httpcache caches GET responses in a pantry, both in front of an
+// http.RoundTripper and in front of an http.Handler.
+//
+// Responses are cached for their Cache-Control s-maxage or max-age and keyed
+// by method, URL and the request headers named by their Vary header.
+// Responses marked no-store, no-cache or private, without a max age or with
+// Vary: * are passed on without being cached, as are requests carrying an
+// Authorization or Cookie header. Once the Vary header of a URL is known,
+// concurrent misses for the same key share a single request to the origin;
+// if its response turns out not to be storable, the others send their own.
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+type call struct {
+	done     chan struct{}
+	data     []byte
+	storable bool
+	err      error
+}
+
+// Cache stores serialized responses in a pantry.
+type Cache struct {
+	responses *pantry.Pantry[[]byte]
+	mutex     sync.Mutex
+	calls     map[string]*call
+}
+
+// New creates a cache storing responses in the given pantry, which should
+// not be shared with other data.
+func New(responses *pantry.Pantry[[]byte]) *Cache {
+	return &Cache{responses: responses}
+}
+
+// Transport returns a round tripper answering from the cache and sending
+// misses to next, or http.DefaultTransport if next is nil.
+func (cache *Cache) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripper(func(r *http.Request) (*http.Response, error) {
+		if !cacheableRequest(r) {
+			return next.RoundTrip(r)
+		}
+		return cache.serve(r, func() (*http.Response, error) {
+			return next.RoundTrip(r)
+		})
+	})
+}
+
+// Middleware answers requests from the cache and passes misses to next.
+func (cache *Cache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cacheableRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		response, err := cache.serve(r, func() (*http.Response, error) {
+			recorder := &recorder{header: make(http.Header)}
+			next.ServeHTTP(recorder, r)
+			return recorder.response(), nil
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer response.Body.Close()
+
+		for name, values := range response.Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(response.StatusCode)
+		io.Copy(w, response.Body)
+	})
+}
+
+func (cache *Cache) serve(r *http.Request, fetch func() (*http.Response, error)) (*http.Response, error) {
+	base := r.Method + " " + r.URL.String()
+
+	var vary []string
+	names, known := cache.responses.Get("vary " + base)
+	if len(names) > 0 {
+		vary = strings.Split(string(names), ",")
+	}
+
+	key := responseKey(base, r, vary)
+	if data, found := cache.responses.Get(key); found {
+		return readResponse(data, r)
+	}
+
+	store := func() ([]byte, bool, error) {
+		response, err := fetch()
+		if err != nil {
+			return nil, false, err
+		}
+		defer response.Body.Close()
+
+		data, err := httputil.DumpResponse(response, true)
+		if err != nil {
+			return nil, false, err
+		}
+
+		ttl, storable := cacheableResponse(response)
+		if storable {
+			vary := varyNames(response.Header)
+			cache.responses.SetWithTTL("vary "+base, []byte(strings.Join(vary, ",")), ttl)
+			cache.responses.SetWithTTL(responseKey(base, r, vary), data, ttl)
+		}
+		return data, storable, nil
+	}
+
+	// Until the Vary header is known, requests differing in a varied header
+	// would share a key, so they are not coalesced.
+	var data []byte
+	var err error
+	if known {
+		data, err = cache.coalesce(key, store)
+	} else {
+		data, _, err = store()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readResponse(data, r)
+}
+
+// coalesce runs fetch once for concurrent misses of key. Responses that are
+// not storable may depend on more than the key, so they are not shared and
+// the waiters fetch their own.
+func (cache *Cache) coalesce(key string, fetch func() ([]byte, bool, error)) ([]byte, error) {
+	cache.mutex.Lock()
+	if pending, found := cache.calls[key]; found {
+		cache.mutex.Unlock()
+		<-pending.done
+		if pending.err != nil || pending.storable {
+			return pending.data, pending.err
+		}
+		data, _, err := fetch()
+		return data, err
+	}
+
+	pending := &call{done: make(chan struct{})}
+	if cache.calls == nil {
+		cache.calls = make(map[string]*call)
+	}
+	cache.calls[key] = pending
+	cache.mutex.Unlock()
+
+	defer func() {
+		cache.mutex.Lock()
+		delete(cache.calls, key)
+		cache.mutex.Unlock()
+		close(pending.done)
+	}()
+
+	pending.data, pending.storable, pending.err = fetch()
+	return pending.data, pending.err
+}
+
+func responseKey(base string, r *http.Request, vary []string) string {
+	var key strings.Builder
+	key.WriteString(base)
+	for _, name := range vary {
+		key.WriteString("\n")
+		key.WriteString(name)
+		key.WriteString(": ")
+		key.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return key.String()
+}
+
+func readResponse(data []byte, r *http.Request) (*http.Response, error) {
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), r)
+}
+
+func cacheableRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet && r.Header.Get("Authorization") == "" && r.Header.Get("Cookie") == ""
+}
+
+// cacheableStatus holds the status codes cacheable by default, see RFC 9110.
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// cacheableResponse returns how long the response may be cached.
+func cacheableResponse(response *http.Response) (time.Duration, bool) {
+	if !cacheableStatus[response.StatusCode] {
+		return 0, false
+	}
+
+	for _, name := range varyNames(response.Header) {
+		if name == "*" {
+			return 0, false
+		}
+	}
+
+	maxAge, sharedMaxAge := -1, -1
+	for _, directive := range strings.Split(strings.Join(response.Header.Values("Cache-Control"), ","), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			return 0, false
+		case "max-age":
+			maxAge = parseSeconds(value)
+		case "s-maxage":
+			sharedMaxAge = parseSeconds(value)
+		}
+	}
+
+	if sharedMaxAge >= 0 {
+		maxAge = sharedMaxAge
+	}
+	if maxAge <= 0 {
+		return 0, false
+	}
+	return time.Duration(maxAge) * time.Second, true
+}
+
+func parseSeconds(value string) int {
+	seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+	if err != nil || seconds < 0 {
+		return -1
+	}
+	return seconds
+}
+
+func varyNames(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+type roundTripper func(r *http.Request) (*http.Response, error)
+
+func (fn roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+// recorder captures the response of a handler.
+type recorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (recorder *recorder) Header() http.Header {
+	return recorder.header
+}
+
+func (recorder *recorder) WriteHeader(status int) {
+	if recorder.wroteHeader {
+		return
+	}
+	recorder.status = status
+	recorder.wroteHeader = true
+}
+
+func (recorder *recorder) Write(data []byte) (int, error) {
+	recorder.WriteHeader(http.StatusOK)
+	return recorder.body.Write(data)
+}
+
+func (recorder *recorder) response() *http.Response {
+	recorder.WriteHeader(http.StatusOK)
+	return &http.Response{
+		StatusCode:    recorder.status,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorder.header,
+		Body:          io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+		ContentLength: int64(recorder.body.Len()),
+	}
+}
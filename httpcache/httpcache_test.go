@@ -0,0 +1,229 @@
+package httpcache
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func TestTransport(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		io.WriteString(w, "hello "+r.Header.Get("Accept-Language"))
+	}))
+	defer server.Close()
+
+	cache := New(pantry.New[[]byte](context.Background(), time.Hour))
+	client := &http.Client{Transport: cache.Transport(nil)}
+
+	get := func(language string) string {
+		request, _ := http.NewRequest("GET", server.URL, nil)
+		request.Header.Set("Accept-Language", language)
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	if body := get("en"); body != "hello en" {
+		t.Fatal("unexpected body", body)
+	}
+	if body := get("en"); body != "hello en" {
+		t.Fatal("unexpected cached body", body)
+	}
+	if body := get("hu"); body != "hello hu" {
+		t.Fatal("vary header ignored", body)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatal("expected 2 requests to the origin, got", n)
+	}
+}
+
+func TestTransportCoalesces(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Accept-Language") != "en" {
+			<-release
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		io.WriteString(w, "hello")
+	}))
+	defer server.Close()
+
+	cache := New(pantry.New[[]byte](context.Background(), time.Hour))
+	client := &http.Client{Transport: cache.Transport(nil)}
+
+	get := func(language string) (string, error) {
+		request, _ := http.NewRequest("GET", server.URL, nil)
+		request.Header.Set("Accept-Language", language)
+		response, err := client.Do(request)
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		return string(body), err
+	}
+
+	// The first response tells which headers the URL varies on.
+	if _, err := get("en"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if body, err := get("hu"); err != nil || body != "hello" {
+				t.Error("unexpected body", body, err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := requests.Load(); n != 2 {
+		t.Fatal("expected 2 requests to the origin, got", n)
+	}
+}
+
+func TestTransportPrivateNotShared(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Vary", "Accept-Language")
+		if user := r.Header.Get("X-User"); user != "" {
+			time.Sleep(20 * time.Millisecond)
+			w.Header().Set("Cache-Control", "private")
+			io.WriteString(w, "hello "+user)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, "hello")
+	}))
+	defer server.Close()
+
+	cache := New(pantry.New[[]byte](context.Background(), time.Hour))
+	client := &http.Client{Transport: cache.Transport(nil)}
+
+	get := func(user, cookie string) (string, error) {
+		request, _ := http.NewRequest("GET", server.URL, nil)
+		request.Header.Set("Accept-Language", "hu")
+		if user != "" {
+			request.Header.Set("X-User", user)
+		}
+		if cookie != "" {
+			request.Header.Set("Cookie", cookie)
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		return string(body), err
+	}
+
+	request, _ := http.NewRequest("GET", server.URL, nil)
+	request.Header.Set("Accept-Language", "en")
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	var wg sync.WaitGroup
+	for _, user := range []string{"alice", "bob"} {
+		for _, cookie := range []string{"", "session=" + user} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if body, err := get(user, cookie); err != nil || body != "hello "+user {
+					t.Error("response of another user", user, body, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if n := requests.Load(); n != 5 {
+		t.Fatal("expected 5 requests to the origin, got", n)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var requests atomic.Int32
+	handler := New(pantry.New[[]byte](context.Background(), time.Hour)).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		}
+		w.WriteHeader(http.StatusNonAuthoritativeInfo)
+		io.WriteString(w, r.URL.Path)
+	}))
+
+	for _, path := range []string{"/public", "/public", "/private", "/private"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		if recorder.Body.String() != path || recorder.Code != http.StatusNonAuthoritativeInfo {
+			t.Fatal("unexpected response", recorder.Code, recorder.Body.String())
+		}
+	}
+
+	if n := requests.Load(); n != 3 {
+		t.Fatal("expected 3 requests to the handler, got", n)
+	}
+}
+
+func TestCacheableResponse(t *testing.T) {
+	tests := []struct {
+		status       int
+		cacheControl string
+		vary         string
+		ttl          time.Duration
+	}{
+		{http.StatusOK, "max-age=30", "", 30 * time.Second},
+		{http.StatusOK, "max-age=30, s-maxage=10", "", 10 * time.Second},
+		{http.StatusOK, "", "", 0},
+		{http.StatusOK, "max-age=0", "", 0},
+		{http.StatusOK, "no-store, max-age=30", "", 0},
+		{http.StatusOK, "max-age=30", "*", 0},
+		{http.StatusInternalServerError, "max-age=30", "", 0},
+	}
+
+	for _, test := range tests {
+		response := &http.Response{StatusCode: test.status, Header: http.Header{}}
+		if test.cacheControl != "" {
+			response.Header.Set("Cache-Control", test.cacheControl)
+		}
+		if test.vary != "" {
+			response.Header.Set("Vary", test.vary)
+		}
+
+		ttl, ok := cacheableResponse(response)
+		if ttl != test.ttl || ok != (test.ttl > 0) {
+			t.Error(test.cacheControl, test.vary, "got", ttl, ok)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pantry
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type jsonEntry[T any] struct {
+	Key       string     `json:"key"`
+	Value     T          `json:"value"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Hashed    bool       `json:"hashed,omitempty"`
+}
+
+// ExportJSON writes the live entries as an indented JSON array of objects
+// with the key, the value encoded by encoding/json and the expiration time,
+// which is left out for entries that never expire. It is meant for
+// inspection and fixtures; Save and ExportSnapshot are more compact.
+//
+// With WithKeyHasher, entries that remember their original key are written
+// with it, the others with the hashed key and marked as hashed.
+func (pantry *Pantry[T]) ExportJSON(w io.Writer) error {
+	pantry.rlockAll()
+	entries := []jsonEntry[T]{}
+	for _, entry := range pantry.snapshotEntries() {
+		exported := jsonEntry[T]{Key: entry.Key, Value: entry.Value}
+		if pantry.keyHasher != nil {
+			if entry.Original != "" {
+				exported.Key = entry.Original
+			} else {
+				exported.Hashed = true
+			}
+		}
+		if entry.Expires != neverExpires {
+			expiresAt := time.Unix(0, entry.Expires).UTC()
+			exported.ExpiresAt = &expiresAt
+		}
+		entries = append(entries, exported)
+	}
+	pantry.runlockAll()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// ImportJSON loads entries written by ExportJSON. Entries keep their
+// expiration time, already expired ones are skipped and those without one
+// never expire. Keys are normalized and hashed like on Set, unless marked as
+// hashed. Like Load and ImportSnapshot, it only fills the pantry: nothing is
+// written back to the backend, the replicas or the persistent storage.
+func (pantry *Pantry[T]) ImportJSON(r io.Reader) error {
+	if pantry.closed.Load() {
+		return ErrClosed
+	}
+
+	var entries []jsonEntry[T]
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	pantry.lockAll()
+	defer pantry.unlockAll()
+
+	now := pantry.clock.Now().UnixNano()
+	for _, entry := range entries {
+		expires := int64(neverExpires)
+		if entry.ExpiresAt != nil {
+			expires = entry.ExpiresAt.UnixNano()
+		}
+		if now > expires {
+			continue
+		}
+
+		original := entry.Key
+		if entry.Hashed {
+			original = ""
+		}
+		key, canonical := pantry.restoredKey(entry.Key, original)
+		evictions = pantry.put(evictions, pantry.shardFor(key), key, pantry.newItem(canonical, entry.Value, expires), now)
+	}
+	return nil
+}
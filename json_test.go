@@ -0,0 +1,99 @@
+package pantry
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+	p.SetWithTTL("permanent", 2, NoExpiration)
+
+	var buffer bytes.Buffer
+	if err := p.ExportJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buffer.String(), `"key": "first"`) {
+		t.Fatalf("unexpected output: %s", buffer.String())
+	}
+
+	restored := New[int](context.Background(), time.Hour)
+	if err := restored.ImportJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != 1 {
+		t.Fatal("first not restored")
+	}
+
+	if ttl, _ := restored.TTL("first"); ttl <= 0 || ttl > time.Hour {
+		t.Fatalf("unexpected ttl %v", ttl)
+	}
+
+	if ttl, _ := restored.TTL("permanent"); ttl != NoExpiration {
+		t.Fatalf("expected no expiration, got %v", ttl)
+	}
+}
+
+func TestImportJSONSkipsExpired(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	fixture := `[
+		{"key": "expired", "value": 1, "expires_at": "2000-01-01T00:00:00Z"},
+		{"key": "live", "value": 2}
+	]`
+	if err := p.ImportJSON(strings.NewReader(fixture)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := p.Get("expired"); found {
+		t.Error("expired entry imported")
+	}
+	if value, _ := p.Get("live"); value != 2 {
+		t.Error("live entry not imported")
+	}
+}
+
+func TestImportJSONWithKeyHasher(t *testing.T) {
+	p := New(context.Background(), time.Hour,
+		WithKeyNormalizer[int](strings.ToLower),
+		WithKeyHasher[int](func(key string) string { return "#" + key }, false),
+	)
+
+	fixture := `[
+		{"key": "First", "value": 1},
+		{"key": "#second", "value": 2, "hashed": true}
+	]`
+	if err := p.ImportJSON(strings.NewReader(fixture)); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := p.Get("first"); value != 1 {
+		t.Error("fixture key not hashed")
+	}
+	if value, _ := p.Get("second"); value != 2 {
+		t.Error("hashed key hashed again")
+	}
+}
+
+func TestImportJSONSkipsBackend(t *testing.T) {
+	backend := newMapBackend()
+	p := New(context.Background(), time.Hour, WithWriteThrough[string](backend))
+
+	if err := p.ImportJSON(strings.NewReader(`[{"key": "first", "value": "one"}]`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := p.Get("first"); value != "one" {
+		t.Fatal("not imported")
+	}
+	if _, found := backend.values["first"]; found {
+		t.Fatal("import written to the backend")
+	}
+}
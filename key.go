@@ -0,0 +1,97 @@
+package pantry
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeySeparator separates the components of keys built with K.
+const KeySeparator = ':'
+
+var ErrInvalidKey = errors.New("pantry: invalid composite key")
+
+// K builds a composite key from parts, such as K("user", id, "profile") for
+// "user:42:profile". Strings, integers, floats, booleans, byte slices and
+// fmt.Stringers are formatted canonically, anything else with fmt.Sprint.
+// Separators and backslashes inside a part are escaped with a backslash, so
+// different parts never produce the same key, and ParseKey returns them
+// again. All keys below a prefix can be removed with
+// RemovePrefix(K(parts...) + ":").
+func K(parts ...any) string {
+	var builder strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			builder.WriteByte(KeySeparator)
+		}
+		for _, c := range []byte(formatKeyPart(part)) {
+			if c == KeySeparator || c == '\\' {
+				builder.WriteByte('\\')
+			}
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// ParseKey splits a key built with K back into its parts. It returns
+// ErrInvalidKey if the key ends in an unfinished escape.
+func ParseKey(key string) ([]string, error) {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; c {
+		case '\\':
+			if i++; i == len(key) {
+				return nil, fmt.Errorf("%w: %q ends in an escape", ErrInvalidKey, key)
+			}
+			part.WriteByte(key[i])
+		case KeySeparator:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(c)
+		}
+	}
+	return append(parts, part.String()), nil
+}
+
+func formatKeyPart(part any) string {
+	switch part := part.(type) {
+	case string:
+		return part
+	case []byte:
+		return string(part)
+	case int:
+		return strconv.Itoa(part)
+	case int8:
+		return strconv.FormatInt(int64(part), 10)
+	case int16:
+		return strconv.FormatInt(int64(part), 10)
+	case int32:
+		return strconv.FormatInt(int64(part), 10)
+	case int64:
+		return strconv.FormatInt(part, 10)
+	case uint:
+		return strconv.FormatUint(uint64(part), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(part), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(part), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(part), 10)
+	case uint64:
+		return strconv.FormatUint(part, 10)
+	case float32:
+		return strconv.FormatFloat(float64(part), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(part, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(part)
+	case fmt.Stringer:
+		return part.String()
+	default:
+		return fmt.Sprint(part)
+	}
+}
@@ -0,0 +1,61 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestK(t *testing.T) {
+	tests := []struct {
+		parts []any
+		key   string
+	}{
+		{[]any{"user", 42, "profile"}, "user:42:profile"},
+		{[]any{"a:b", "c"}, `a\:b:c`},
+		{[]any{"a", "b:c"}, `a:b\:c`},
+		{[]any{`back\slash`, uint8(7), 1.5, true}, `back\\slash:7:1.5:true`},
+		{[]any{time.Second}, "1s"},
+		{[]any{""}, ""},
+	}
+
+	for _, test := range tests {
+		key := K(test.parts...)
+		if key != test.key {
+			t.Fatalf("K(%v) = %q, want %q", test.parts, key, test.key)
+		}
+
+		parts, err := ParseKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(parts) != len(test.parts) || parts[0] != formatKeyPart(test.parts[0]) {
+			t.Fatalf("ParseKey(%q) = %q", key, parts)
+		}
+	}
+
+	if parts, _ := ParseKey(K("a:b", "c")); !slices.Equal(parts, []string{"a:b", "c"}) {
+		t.Fatal("unexpected parts", parts)
+	}
+	if _, err := ParseKey(`dangling\`); !errors.Is(err, ErrInvalidKey) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestKPrefix(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.Set(K("user", 1, "profile"), 1)
+	p.Set(K("user", 1, "orders"), 2)
+	p.Set(K("user", 12, "profile"), 3)
+
+	if removed := p.RemovePrefix(K("user", 1) + ":"); removed != 2 {
+		t.Fatal("unexpected removals", removed)
+	}
+	if !p.Contains(K("user", 12, "profile")) {
+		t.Fatal("removed another user")
+	}
+}
@@ -0,0 +1,78 @@
+package pantry
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// ListPantry keeps a list of values per key, such as the recent events of a
+// user. Appends happen under the lock of the key's shard, so concurrent
+// appends are never lost, and lists longer than the maximum length drop
+// their oldest values.
+type ListPantry[T any] struct {
+	lists     *Pantry[[]T]
+	maxLength int
+}
+
+// NewList creates a pantry of lists expiring after expiration, keeping at
+// most maxLength values per key. A non-positive maxLength keeps all values.
+// The options configure the underlying pantry.
+func NewList[T any](ctx context.Context, expiration time.Duration, maxLength int, options ...Option[[]T]) *ListPantry[T] {
+	return &ListPantry[T]{
+		lists:     New(ctx, expiration, options...),
+		maxLength: maxLength,
+	}
+}
+
+// Append adds values to the end of the list of key. An existing list keeps
+// its expiration and a new one gets the default TTL.
+func (list *ListPantry[T]) Append(key string, values ...T) {
+	list.lists.Update(key, func(old []T, _ bool) ([]T, bool) {
+		return list.append(old, values), true
+	})
+}
+
+// AppendWithTTL adds values to the end of the list of key and lets the whole
+// list expire after ttl.
+func (list *ListPantry[T]) AppendWithTTL(key string, ttl time.Duration, values ...T) {
+	list.lists.UpdateWithExpiry(key, func(old []T, _ time.Duration, _ bool) ([]T, time.Duration, bool) {
+		return list.append(old, values), ttl, true
+	})
+}
+
+// append only ever writes past the end of old, so the lists handed out by
+// GetAll are never changed afterwards.
+func (list *ListPantry[T]) append(old, values []T) []T {
+	values = append(old, values...)
+	if list.maxLength > 0 && len(values) > list.maxLength {
+		values = values[len(values)-list.maxLength:]
+	}
+	return values
+}
+
+// GetAll returns the values of key from oldest to newest, nil if there are
+// none. The caller may append to the returned slice.
+func (list *ListPantry[T]) GetAll(key string) []T {
+	values, _ := list.lists.Get(key)
+	return slices.Clip(values)
+}
+
+// Len returns the number of values of key.
+func (list *ListPantry[T]) Len(key string) int {
+	values, _ := list.lists.Get(key)
+	return len(values)
+}
+
+func (list *ListPantry[T]) Remove(key string) {
+	list.lists.Remove(key)
+}
+
+// TTL returns the remaining lifetime of the list of key.
+func (list *ListPantry[T]) TTL(key string) (time.Duration, bool) {
+	return list.lists.TTL(key)
+}
+
+func (list *ListPantry[T]) Close() error {
+	return list.lists.Close()
+}
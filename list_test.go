@@ -0,0 +1,77 @@
+package pantry
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestListPantry(t *testing.T) {
+	list := NewList[int](context.Background(), time.Hour, 3)
+	defer list.Close()
+
+	if values := list.GetAll("key"); values != nil {
+		t.Fatal("unexpected values", values)
+	}
+
+	list.Append("key", 1, 2)
+	list.Append("key", 3)
+	values := list.GetAll("key")
+	if !slices.Equal(values, []int{1, 2, 3}) {
+		t.Fatal("unexpected values", values)
+	}
+
+	list.Append("key", 4)
+	if values := list.GetAll("key"); !slices.Equal(values, []int{2, 3, 4}) || list.Len("key") != 3 {
+		t.Fatal("oldest value not dropped", values)
+	}
+
+	// Appending to a returned list leaves the stored one alone.
+	_ = append(values, 100)
+	list.Append("key", 5)
+	if values := list.GetAll("key"); !slices.Equal(values, []int{3, 4, 5}) {
+		t.Fatal("stored list changed", values)
+	}
+
+	list.Remove("key")
+	if list.Len("key") != 0 {
+		t.Fatal("list not removed")
+	}
+}
+
+func TestListPantryTTL(t *testing.T) {
+	list := NewList[string](context.Background(), time.Hour, 0)
+	defer list.Close()
+
+	list.AppendWithTTL("key", 10*time.Millisecond, "first")
+	list.Append("key", "second")
+	if ttl, _ := list.TTL("key"); ttl > 10*time.Millisecond {
+		t.Fatal("append changed the expiration", ttl)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if values := list.GetAll("key"); values != nil {
+		t.Fatal("list not expired", values)
+	}
+}
+
+func TestListPantryConcurrentAppend(t *testing.T) {
+	list := NewList[int](context.Background(), time.Hour, 0)
+	defer list.Close()
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			list.Append("key", i)
+		}()
+	}
+	wg.Wait()
+
+	if n := list.Len("key"); n != 100 {
+		t.Fatal("appends lost", n)
+	}
+}
@@ -0,0 +1,55 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// loadFromLoader fills a miss of the stored key with the loader, which gets
+// the canonical one. Concurrent misses for the same key share a single load,
+// and a loader error is returned as is.
+func (pantry *Pantry[T]) loadFromLoader(ctx context.Context, canonical, key string) (item[T], error) {
+	value, err := pantry.compute(ctx, canonical, func() (T, error) {
+		return pantry.loader(pantry.ctx, canonical)
+	})
+	if errors.Is(err, ErrNotFound) {
+		pantry.cacheMiss(key)
+	}
+	if err != nil {
+		return item[T]{}, err
+	}
+
+	if stored, found := pantry.lookup(key); found {
+		return stored, nil
+	}
+	return item[T]{value: value}, nil
+}
+
+// refreshAhead reloads an entry read close to its expiration, or past its soft
+// TTL, in the background. It takes the canonical key.
+func (pantry *Pantry[T]) refreshAhead(key string, stored item[T]) {
+	if pantry.isStale(stored, pantry.clock.Now().UnixNano()) {
+		if pantry.loader == nil {
+			pantry.refreshStale(key, stored.value)
+			return
+		}
+		pantry.refreshInBackground(key, func() (T, error) {
+			return pantry.loader(pantry.ctx, key)
+		})
+		return
+	}
+
+	if pantry.loader == nil || pantry.refreshWindow == 0 {
+		return
+	}
+
+	window := time.Duration(float64(pantry.expiration) * pantry.refreshWindow)
+	if time.Duration(stored.expires-pantry.clock.Now().UnixNano()) >= window {
+		return
+	}
+
+	pantry.refreshInBackground(key, func() (T, error) {
+		return pantry.loader(pantry.ctx, key)
+	})
+}
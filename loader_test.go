@@ -0,0 +1,137 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoader(t *testing.T) {
+	var loads atomic.Int32
+	p := New(context.Background(), time.Hour, WithLoader(func(_ context.Context, key string) (string, error) {
+		loads.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		if key == "missing" {
+			return "", ErrNotFound
+		}
+		return "loaded " + key, nil
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, found := p.Get("key"); !found || value != "loaded key" {
+				t.Error("not loaded", value, found)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Fatal("concurrent misses loaded", loads.Load())
+	}
+
+	if _, err := p.GetE("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected ErrNotFound", err)
+	}
+}
+
+func TestLoaderContext(t *testing.T) {
+	release := make(chan struct{})
+	p := New(context.Background(), time.Hour, WithLoader(func(_ context.Context, key string) (string, error) {
+		<-release
+		return "loaded " + key, nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := p.GetContext(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected deadline exceeded, got", err)
+	}
+
+	close(release)
+	eventually(t, func() bool {
+		stored, found := p.lookup("key")
+		return found && stored.value == "loaded key"
+	})
+}
+
+func TestSetContextCanceled(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := p.SetContext(ctx, "key", "value"); !errors.Is(err, context.Canceled) {
+		t.Fatal("expected canceled, got", err)
+	}
+	if _, found := p.Get("key"); found {
+		t.Fatal("value stored with a canceled context")
+	}
+}
+
+func TestRefreshAhead(t *testing.T) {
+	var loads atomic.Int32
+	p := New(context.Background(), 100*time.Millisecond,
+		WithLoader(func(_ context.Context, key string) (string, error) {
+			return strconv.Itoa(int(loads.Add(1))), nil
+		}),
+		WithRefreshAhead[string](0.5),
+	)
+
+	if value, _ := p.Get("key"); value != "1" {
+		t.Fatal("not loaded", value)
+	}
+
+	if value, _ := p.Get("key"); value != "1" || loads.Load() != 1 {
+		t.Fatal("refreshed too early", value)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if value, _ := p.Get("key"); value != "1" {
+		t.Fatal("current value not returned", value)
+	}
+
+	eventually(t, func() bool {
+		value, _ := p.Get("key")
+		return value == "2"
+	})
+
+	if ttl, _ := p.TTL("key"); ttl < 50*time.Millisecond {
+		t.Fatal("expiration not extended", ttl)
+	}
+}
+
+func TestSoftTTL(t *testing.T) {
+	var loads atomic.Int32
+	p := New(context.Background(), time.Hour,
+		WithLoader(func(_ context.Context, key string) (string, error) {
+			return strconv.Itoa(int(loads.Add(1))), nil
+		}),
+		WithSoftTTL[string](20*time.Millisecond),
+	)
+	defer p.Close()
+
+	if value, found, stale := p.GetStale("key"); value != "1" || !found || stale {
+		t.Fatal("unexpected fresh entry", value, found, stale)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if value, found, stale := p.GetStale("key"); value != "1" || !found || !stale {
+		t.Fatal("unexpected stale entry", value, found, stale)
+	}
+
+	eventually(t, func() bool {
+		value, _, stale := p.GetStale("key")
+		return value == "2" && !stale
+	})
+}
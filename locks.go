@@ -0,0 +1,55 @@
+package pantry
+
+import "sync"
+
+type keyLock struct {
+	mutex sync.Mutex
+	refs  int
+}
+
+// keyLocks hands out one mutex per locked key and drops it once nobody holds
+// or waits for it, so unrelated keys never share a lock.
+type keyLocks struct {
+	mutex sync.Mutex
+	locks map[string]*keyLock
+}
+
+// Lock acquires the lock of key and returns the function releasing it. The
+// lock only serializes callers of Lock, DoWithLock and the loaders run by
+// GetOrCompute; it does not block reads or writes of the entry.
+func (pantry *Pantry[T]) Lock(key string) func() {
+	key = pantry.normalize(key)
+	locks := &pantry.keyLocks
+
+	locks.mutex.Lock()
+	lock, found := locks.locks[key]
+	if !found {
+		if locks.locks == nil {
+			locks.locks = make(map[string]*keyLock)
+		}
+		lock = &keyLock{}
+		locks.locks[key] = lock
+	}
+	lock.refs++
+	locks.mutex.Unlock()
+
+	lock.mutex.Lock()
+	return func() {
+		lock.mutex.Unlock()
+
+		locks.mutex.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(locks.locks, key)
+		}
+		locks.mutex.Unlock()
+	}
+}
+
+// DoWithLock runs fn while holding the lock of key.
+func (pantry *Pantry[T]) DoWithLock(key string, fn func()) {
+	unlock := pantry.Lock(key)
+	defer unlock()
+
+	fn()
+}
@@ -0,0 +1,75 @@
+package pantry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockSerializesKey(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.DoWithLock("counter", func() {
+				value, _ := p.Get("counter")
+				p.Set("counter", value+1)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := p.Get("counter"); value != 100 {
+		t.Fatal("lost updates", value)
+	}
+
+	if len(p.keyLocks.locks) != 0 {
+		t.Fatal("locks kept", len(p.keyLocks.locks))
+	}
+}
+
+func TestLockUnrelatedKeys(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	unlock := p.Lock("first")
+	defer unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.DoWithLock("second", func() {})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("unrelated key blocked")
+	}
+}
+
+func TestGetOrComputeTakesLock(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	unlock := p.Lock("key")
+
+	done := make(chan struct{})
+	go func() {
+		p.GetOrCompute("key", func() (int, error) {
+			return 1, nil
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("loader ran while locked")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	unlock()
+	<-done
+}
@@ -0,0 +1,19 @@
+package pantry
+
+import "log/slog"
+
+// WithLogger logs capacity evictions and cleanup passes at debug level, and
+// failures of background work, such as write-behind flushes, backend calls,
+// write-ahead log appends and compactions, as well as options New left out
+// for invalid arguments, at warn level.
+func WithLogger[T any](logger *slog.Logger) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.logger = logger
+	}
+}
+
+func (pantry *Pantry[T]) warn(msg string, err error, args ...any) {
+	if pantry.logger != nil {
+		pantry.logger.Warn(msg, append([]any{"error", err}, args...)...)
+	}
+}
@@ -0,0 +1,75 @@
+package pantry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (buffer *syncBuffer) Write(p []byte) (int, error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	return buffer.buffer.Write(p)
+}
+
+func (buffer *syncBuffer) String() string {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	return buffer.buffer.String()
+}
+
+func newTestLogger() (*slog.Logger, *syncBuffer) {
+	buffer := &syncBuffer{}
+	return slog.New(slog.NewTextHandler(buffer, &slog.HandlerOptions{Level: slog.LevelDebug})), buffer
+}
+
+func TestWithLoggerEvictions(t *testing.T) {
+	logger, buffer := newTestLogger()
+	p := New(context.Background(), time.Hour, WithMaxItems[int](1), WithLogger[int](logger))
+
+	p.Set("a", 1)
+	p.Set("b", 2)
+
+	if output := buffer.String(); !strings.Contains(output, "pantry: evicted") || !strings.Contains(output, "key=a") {
+		t.Errorf("unexpected log output: %q", output)
+	}
+}
+
+func TestWithLoggerCleanup(t *testing.T) {
+	logger, buffer := newTestLogger()
+	p := New(context.Background(), time.Millisecond, WithLogger[int](logger))
+
+	p.Set("a", 1)
+	time.Sleep(5 * time.Millisecond)
+	p.PurgeExpired()
+
+	if output := buffer.String(); !strings.Contains(output, "pantry: cleanup") || !strings.Contains(output, "removed=1") {
+		t.Errorf("unexpected log output: %q", output)
+	}
+}
+
+func TestWithLoggerBackendFailure(t *testing.T) {
+	logger, buffer := newTestLogger()
+	backend := newMapBackend()
+	backend.err = errors.New("unavailable")
+	p := New(context.Background(), time.Hour, WithWriteBehind[string](backend, time.Millisecond), WithLogger[string](logger))
+
+	p.Set("a", "1")
+
+	eventually(t, func() bool {
+		return strings.Contains(buffer.String(), "level=WARN")
+	})
+	if output := buffer.String(); !strings.Contains(output, "error=unavailable") {
+		t.Errorf("unexpected log output: %q", output)
+	}
+}
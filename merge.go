@@ -0,0 +1,105 @@
+package pantry
+
+import "context"
+
+type MergeStrategy int
+
+const (
+	// KeepExisting only adds the keys without a live entry.
+	KeepExisting MergeStrategy = iota
+	// Overwrite replaces every entry with the merged one.
+	Overwrite
+	// Newest keeps whichever value was written last.
+	Newest
+)
+
+// Clone returns a new pantry created with ctx and the options this one was
+// created with, holding a copy of the live entries with their expirations.
+// Values are copied with the cloner of WithCloner, otherwise they are
+// shared. Options with outside effects, such as WithReplication, take effect
+// again for the clone.
+func (pantry *Pantry[T]) Clone(ctx context.Context) *Pantry[T] {
+	clone := New(ctx, pantry.expiration, pantry.options...)
+
+	pantry.rlockAll()
+	entries := pantry.liveItems()
+	pantry.runlockAll()
+
+	defer clone.enforceCapacity()
+
+	clone.lockAll()
+	defer clone.unlockAll()
+
+	now := clone.clock.Now().UnixNano()
+	for _, entry := range entries {
+		entry.item.value = pantry.clone(entry.item.value)
+		entry.item.accessed = clone.nextAccess()
+		clone.put(nil, clone.shardFor(entry.key), entry.key, entry.item, now)
+	}
+	return clone
+}
+
+// Merge copies the live entries of other into the pantry following strategy
+// and returns how many it wrote. Merged entries keep their expiration and
+// count as writes, so they reach the backend, persistence and replicas. Both
+// pantries are expected to normalize keys the same way.
+func (pantry *Pantry[T]) Merge(other *Pantry[T], strategy MergeStrategy) int {
+	if pantry.closed.Load() {
+		return 0
+	}
+
+	other.rlockAll()
+	entries := other.liveItems()
+	other.runlockAll()
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	pantry.lockAll()
+	defer pantry.unlockAll()
+
+	merged := 0
+	now := pantry.clock.Now().UnixNano()
+	for _, entry := range entries {
+		shard := pantry.shardFor(entry.key)
+
+		current, found := shard.store[entry.key]
+		if found && !pantry.isExpired(entry.key, current, now) {
+			switch strategy {
+			case KeepExisting:
+				continue
+			case Newest:
+				if current.written >= entry.item.written {
+					continue
+				}
+			}
+		}
+
+		entry.item.value = other.clone(entry.item.value)
+		entry.item.accessed = pantry.nextAccess()
+		evictions = pantry.put(evictions, shard, entry.key, entry.item, now)
+		evictions = pantry.writeBack(evictions, entry.key, entry.item.value, false)
+		merged++
+	}
+	return merged
+}
+
+type keyedItem[T any] struct {
+	key  string
+	item item[T]
+}
+
+// liveItems must be called with all shards locked.
+func (pantry *Pantry[T]) liveItems() []keyedItem[T] {
+	now := pantry.clock.Now().UnixNano()
+	var entries []keyedItem[T]
+	for key, item := range pantry.items() {
+		if pantry.isExpired(key, item, now) {
+			continue
+		}
+		entries = append(entries, keyedItem[T]{key: key, item: item})
+	}
+	return entries
+}
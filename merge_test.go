@@ -0,0 +1,68 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClone(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithCloner(func(values []int) []int {
+		return append([]int(nil), values...)
+	}))
+
+	p.SetWithTTL("first", []int{1}, time.Minute)
+
+	clone := p.Clone(context.Background())
+	defer clone.Close()
+
+	p.Set("second", []int{2})
+
+	values, found := clone.Get("first")
+	if !found || values[0] != 1 {
+		t.Fatal("first not cloned")
+	}
+	if ttl, _ := clone.TTL("first"); ttl > time.Minute || ttl < 59*time.Second {
+		t.Fatal("unexpected ttl", ttl)
+	}
+	if _, found := clone.Get("second"); found {
+		t.Fatal("clone shares the store")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	for _, test := range []struct {
+		strategy MergeStrategy
+		first    int
+		second   int
+		merged   int
+	}{
+		{KeepExisting, 1, 2, 1},
+		{Overwrite, 10, 20, 3},
+		{Newest, 1, 20, 2},
+	} {
+		p := New[int](context.Background(), time.Hour)
+		other := New[int](context.Background(), time.Hour)
+
+		other.Set("first", 10)
+		time.Sleep(time.Millisecond)
+		p.Set("first", 1)
+		p.Set("second", 2)
+		time.Sleep(time.Millisecond)
+		other.Set("second", 20)
+		other.Set("third", 30)
+
+		if merged := p.Merge(other, test.strategy); merged != test.merged {
+			t.Errorf("%d: expected %d merged, got %d", test.strategy, test.merged, merged)
+		}
+		if value, _ := p.Get("first"); value != test.first {
+			t.Errorf("%d: unexpected first %d", test.strategy, value)
+		}
+		if value, _ := p.Get("second"); value != test.second {
+			t.Errorf("%d: unexpected second %d", test.strategy, value)
+		}
+		if value, _ := p.Get("third"); value != 30 {
+			t.Errorf("%d: third not merged", test.strategy)
+		}
+	}
+}
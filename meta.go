@@ -0,0 +1,78 @@
+package pantry
+
+import (
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// SetWithMeta stores value with the default expiration and attaches the
+// labels in meta to it, for example to record where the value came from.
+// Like tags, the labels belong to this write; a later write to the key
+// replaces them. The map is copied.
+func (pantry *Pantry[T]) SetWithMeta(key string, value T, meta map[string]string) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	canonical := pantry.canonical(key)
+	key = pantry.hash(canonical)
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	stored := pantry.newItem(canonical, value, pantry.expiresAt(now, pantry.defaultTTL(key, value)))
+	stored.meta = maps.Clone(meta)
+	evictions = pantry.put(evictions, shard, key, stored, now.UnixNano())
+	evictions = pantry.writeBack(evictions, key, value, false)
+}
+
+// GetWithMeta is like Get, also returning a copy of the labels of the entry.
+func (pantry *Pantry[T]) GetWithMeta(key string) (T, map[string]string, bool) {
+	item, found := pantry.get(key)
+	return item.value, maps.Clone(item.meta), found
+}
+
+// FilterByLabel yields the live entries labeled name=value, iterating over a
+// snapshot like All.
+func (pantry *Pantry[T]) FilterByLabel(name, value string) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		release := pantry.acquireIteration()
+
+		type labeled struct {
+			key   string
+			value T
+		}
+
+		pantry.rlockAll()
+		now := pantry.clock.Now().UnixNano()
+		var entries []labeled
+		for key, item := range pantry.items() {
+			if label, found := item.meta[name]; found && label == value && !pantry.isExpired(key, item, now) {
+				entries = append(entries, labeled{key: key, value: item.value})
+			}
+		}
+		pantry.runlockAll()
+		release()
+
+		if pantry.stableOrder {
+			slices.SortFunc(entries, func(a, b labeled) int {
+				return strings.Compare(a.key, b.key)
+			})
+		}
+
+		for _, entry := range entries {
+			if !yield(entry.key, pantry.clone(entry.value)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package pantry
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestMeta(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+
+	meta := map[string]string{"source": "primary"}
+	p.SetWithMeta("a", "first", meta)
+	p.SetWithMeta("b", "second", map[string]string{"source": "replica"})
+	p.Set("c", "third")
+	meta["source"] = "changed"
+
+	value, labels, found := p.GetWithMeta("a")
+	if !found || value != "first" || labels["source"] != "primary" {
+		t.Fatal("unexpected result", value, labels, found)
+	}
+	labels["source"] = "changed"
+	if _, labels, _ := p.GetWithMeta("a"); labels["source"] != "primary" {
+		t.Fatal("stored labels modified through the returned map")
+	}
+
+	filtered := maps.Collect(p.FilterByLabel("source", "primary"))
+	if len(filtered) != 1 || filtered["a"] != "first" {
+		t.Fatal("unexpected filtered entries", filtered)
+	}
+
+	for key, entry := range p.Entries() {
+		if key == "b" && entry.Meta["source"] != "replica" {
+			t.Fatal("labels missing from the entry", entry)
+		}
+	}
+
+	p.Set("a", "replaced")
+	if _, labels, _ := p.GetWithMeta("a"); labels != nil {
+		t.Fatal("labels kept after a write", labels)
+	}
+}
@@ -0,0 +1,57 @@
+// Package metrics exposes pantry statistics in the Prometheus text exposition
+// format without depending on the Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/webermarci/pantry"
+)
+
+type Source interface {
+	Stats() pantry.Stats
+}
+
+type metric struct {
+	name  string
+	kind  string
+	help  string
+	value func(stats pantry.Stats) float64
+}
+
+var metrics = []metric{
+	{"items", "gauge", "Number of entries currently stored.", func(stats pantry.Stats) float64 { return float64(stats.Items) }},
+	{"hits_total", "counter", "Number of lookups that found a live entry.", func(stats pantry.Stats) float64 { return float64(stats.Hits) }},
+	{"misses_total", "counter", "Number of lookups that found no live entry.", func(stats pantry.Stats) float64 { return float64(stats.Misses) }},
+	{"expirations_total", "counter", "Number of entries removed because they expired.", func(stats pantry.Stats) float64 { return float64(stats.Expirations) }},
+	{"evictions_total", "counter", "Number of entries evicted to stay within capacity.", func(stats pantry.Stats) float64 { return float64(stats.Evictions) }},
+	{"lock_contended_total", "counter", "Number of sampled shard lock acquisitions that had to wait.", func(stats pantry.Stats) float64 { return float64(stats.Locks.Contended) }},
+	{"lock_wait_seconds_total", "counter", "Time sampled shard lock acquisitions spent waiting.", func(stats pantry.Stats) float64 { return stats.Locks.WaitTime.Seconds() }},
+	{"last_cleanup_duration_seconds", "gauge", "Duration of the last expiration sweep.", func(stats pantry.Stats) float64 { return stats.LastCleanupDuration.Seconds() }},
+}
+
+// Write writes the statistics of source with every metric name prefixed by
+// namespace and an underscore.
+func Write(w io.Writer, namespace string, source Source) error {
+	stats := source.Stats()
+	buffered := bufio.NewWriter(w)
+
+	for _, metric := range metrics {
+		name := namespace + "_" + metric.name
+		fmt.Fprintf(buffered, "# HELP %s %s\n", name, metric.help)
+		fmt.Fprintf(buffered, "# TYPE %s %s\n", name, metric.kind)
+		fmt.Fprintf(buffered, "%s %g\n", name, metric.value(stats))
+	}
+	return buffered.Flush()
+}
+
+// Handler serves the statistics of source for a Prometheus scraper.
+func Handler(namespace string, source Source) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w, namespace, source)
+	})
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func TestHandler(t *testing.T) {
+	p := pantry.New[string](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.Set("key", "value")
+	p.Get("key")
+	p.Get("missing")
+
+	recorder := httptest.NewRecorder()
+	Handler("cache", p).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := recorder.Body.String()
+
+	for _, line := range []string{
+		"# TYPE cache_items gauge",
+		"cache_items 1\n",
+		"# TYPE cache_hits_total counter",
+		"cache_hits_total 1\n",
+		"cache_misses_total 1\n",
+		"cache_evictions_total 0\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("missing %q in\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+// Package msgpackcodec provides a MessagePack pantry.Codec.
+package msgpackcodec
+
+import "github.com/vmihailenco/msgpack/v5"
+
+type Codec struct{}
+
+func (Codec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (Codec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func (Codec) Extension() string {
+	return ".msgpack"
+}
@@ -0,0 +1,33 @@
+package msgpackcodec
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func TestCodec(t *testing.T) {
+	p := pantry.New(context.Background(), time.Hour, pantry.WithCodec[string](Codec{}))
+	defer p.Close()
+
+	p.Set("first", "hello")
+
+	var buffer bytes.Buffer
+	if err := p.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := pantry.New(context.Background(), time.Hour, pantry.WithCodec[string](Codec{}))
+	defer restored.Close()
+
+	if err := restored.Load(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != "hello" {
+		t.Fatal("not restored")
+	}
+}
@@ -0,0 +1,81 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+)
+
+// SetNegative removes key and records it as missing for the negative TTL, or
+// the default expiration without WithNegativeTTL, which with NoExpiration
+// keeps the miss until a value is set. The backend is not touched.
+func (pantry *Pantry[T]) SetNegative(key string) {
+	key = pantry.normalize(key)
+	shard := pantry.shardFor(key)
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	if current, found := shard.store[key]; found {
+		pantry.drop(shard, key)
+		evictions = pantry.evict(evictions, key, current, now.UnixNano(), Removed)
+	}
+
+	ttl := pantry.negativeTTL
+	if ttl <= 0 {
+		ttl = pantry.expiration
+	}
+	shard.miss(key, expiresAfter(now.UnixNano(), ttl))
+}
+
+// GetNegative is like Get, with the last result reporting whether the miss was
+// served from a recorded one.
+func (pantry *Pantry[T]) GetNegative(key string) (T, bool, bool) {
+	item, err := pantry.find(context.Background(), key)
+	return item.value, err == nil, errors.Is(err, ErrCachedMiss)
+}
+
+// cacheMiss records a miss of the backend or the loader.
+func (pantry *Pantry[T]) cacheMiss(key string) {
+	if pantry.negativeTTL <= 0 {
+		return
+	}
+
+	shard := pantry.shardFor(key)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	// A value set while loading wins over the miss.
+	if _, found := shard.store[key]; found {
+		return
+	}
+	shard.miss(key, pantry.clock.Now().Add(pantry.negativeTTL).UnixNano())
+}
+
+// miss must be called with the shard's write lock held.
+func (shard *shard[T]) miss(key string, expires int64) {
+	if shard.misses == nil {
+		shard.misses = make(map[string]int64)
+	}
+	shard.misses[key] = expires
+	shard.admit(key)
+}
+
+func (shard *shard[T]) missed(key string, now int64) bool {
+	expires, found := shard.misses[key]
+	return found && now <= expires
+}
+
+// forgetMisses must be called with the shard's write lock held.
+func (shard *shard[T]) forgetMisses(now int64) {
+	for key, expires := range shard.misses {
+		if now > expires {
+			delete(shard.misses, key)
+			shard.filterStale++
+		}
+	}
+}
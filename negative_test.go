@@ -0,0 +1,98 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNegativeTTLBackend(t *testing.T) {
+	backend := newMapBackend()
+	p := New(context.Background(), time.Hour,
+		WithWriteThrough[string](backend),
+		WithNegativeTTL[string](20*time.Millisecond),
+	)
+
+	for range 3 {
+		if _, found, cachedMiss := p.GetNegative("key"); found {
+			t.Fatal("found missing key")
+		} else if backend.loads.Load() > 1 && !cachedMiss {
+			t.Fatal("miss not cached")
+		}
+	}
+
+	if backend.loads.Load() != 1 {
+		t.Fatal("backend asked again", backend.loads.Load())
+	}
+
+	if _, err := p.GetE("key"); !errors.Is(err, ErrNotFound) || !errors.Is(err, ErrCachedMiss) {
+		t.Fatal("unexpected error", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	backend.values["key"] = "value"
+
+	if value, found, _ := p.GetNegative("key"); !found || value != "value" {
+		t.Fatal("miss not expired", value, found)
+	}
+}
+
+func TestNegativeTTLLoader(t *testing.T) {
+	var loads atomic.Int32
+	p := New(context.Background(), time.Hour,
+		WithLoader(func(context.Context, string) (int, error) {
+			loads.Add(1)
+			return 0, ErrNotFound
+		}),
+		WithNegativeTTL[int](time.Hour),
+	)
+
+	p.Get("key")
+	p.Get("key")
+
+	if loads.Load() != 1 {
+		t.Fatal("loader asked again", loads.Load())
+	}
+
+	p.Set("key", 1)
+
+	if value, found, cachedMiss := p.GetNegative("key"); !found || cachedMiss || value != 1 {
+		t.Fatal("set did not clear the miss", value, found, cachedMiss)
+	}
+}
+
+func TestSetNegative(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("key", 1)
+	p.SetNegative("key")
+
+	if _, found, cachedMiss := p.GetNegative("key"); found || !cachedMiss {
+		t.Fatal("unexpected state", found, cachedMiss)
+	}
+
+	if _, found, cachedMiss := p.GetNegative("other"); found || cachedMiss {
+		t.Fatal("unexpected state for unknown key", found, cachedMiss)
+	}
+}
+
+func TestSetNegativeNoExpiration(t *testing.T) {
+	clock := &testClock{}
+	clock.now.Store(time.Now().UnixNano())
+	p := New(context.Background(), NoExpiration, WithClock[int](clock))
+	defer p.Close()
+
+	p.SetNegative("key")
+	clock.advance(24 * time.Hour)
+
+	if _, found, cachedMiss := p.GetNegative("key"); found || !cachedMiss {
+		t.Fatal("miss expired", found, cachedMiss)
+	}
+
+	p.Set("key", 1)
+	if value, found, cachedMiss := p.GetNegative("key"); !found || cachedMiss || value != 1 {
+		t.Fatal("set did not clear the miss", value, found, cachedMiss)
+	}
+}
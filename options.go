@@ -0,0 +1,336 @@
+package pantry
+
+import (
+	"context"
+	"time"
+)
+
+type Option[T any] func(*Pantry[T])
+
+// WithAccessTracking keeps track of the order in which entries are accessed,
+// which is required by RecentlyUsed. Reads take the write lock when enabled.
+func WithAccessTracking[T any]() Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.accessTracking = true
+	}
+}
+
+// WithNoLazyExpiry makes Get skip the expiration check and return whatever is
+// in the store, leaving the removal of stale entries to the background
+// cleanup. Expired values may be served for up to one cleanup interval.
+func WithNoLazyExpiry[T any]() Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.noLazyExpiry = true
+	}
+}
+
+// WithStableIteration makes Keys, Values, All, Entries and Filter yield in
+// lexical key order, so their output is the same across runs, at the cost of
+// sorting every snapshot.
+func WithStableIteration[T any]() Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.stableOrder = true
+	}
+}
+
+// WithKeyNormalizer applies normalizer to every key passed to the pantry, so
+// logically identical keys (e.g. differing in case) map to the same entry.
+func WithKeyNormalizer[T any](normalizer func(string) string) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.keyNormalizer = normalizer
+	}
+}
+
+// WithKeyHasher stores entries under hash of their key, applied after the
+// key normalizer, to bound the memory of long keys. Everything reporting
+// keys, such as Keys, events and backends, sees the hashed ones, while
+// loaders get the original. With verify, every write remembers the original
+// key of its entry, and Get and its variants report a different key hashing
+// to the same entry as ErrKeyCollision.
+func WithKeyHasher[T any](hash func(string) string, verify bool) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.keyHasher = hash
+		pantry.verifyKeys = verify
+	}
+}
+
+// WithValidityCheck adds a custom check consulted alongside the expiration.
+// Entries for which check returns false are treated as expired.
+func WithValidityCheck[T any](check func(key string, value T) bool) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.validityCheck = check
+	}
+}
+
+// WithMaxConcurrentIterations limits how many iterators may take their
+// snapshot at the same time. Excess iterators wait before taking theirs,
+// bounding the work done under the read locks. The limit is released before
+// the loop body runs, so nested iterations do not deadlock.
+func WithMaxConcurrentIterations[T any](n int) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if n <= 0 {
+			pantry.invalidOption("concurrent iteration limit %d must be positive", n)
+			return
+		}
+		pantry.iterations = make(chan struct{}, n)
+	}
+}
+
+// WithCleanupInterval sets how often the background cleanup removes expired
+// entries. The default is 5 seconds.
+func WithCleanupInterval[T any](interval time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.cleanupInterval = interval
+	}
+}
+
+// WithSlidingExpiration resets the expiration of an entry to the default
+// TTL every time it is read with Get. Iterators do not count as reads and
+// leave the expiration untouched.
+func WithSlidingExpiration[T any]() Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.sliding = true
+	}
+}
+
+// WithShards sets how many independently locked shards the store is split
+// into. More shards reduce lock contention between unrelated keys.
+func WithShards[T any](n int) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if n <= 0 {
+			pantry.invalidOption("shard count %d must be positive", n)
+			return
+		}
+		pantry.shardCount = n
+	}
+}
+
+// WithMaxItems bounds the number of entries. When a new entry does not fit,
+// the eviction policy, LRU by default, picks the entries to evict.
+func WithMaxItems[T any](n int) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.maxItems = n
+	}
+}
+
+// WithEvictionPolicy selects how entries are evicted once WithMaxItems or
+// WithMaxCost is reached.
+func WithEvictionPolicy[T any](policy EvictionPolicy) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.evictionPolicy = policy
+	}
+}
+
+// WithInitialCapacity preallocates the store for n entries.
+func WithInitialCapacity[T any](n int) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.initialCapacity = n
+	}
+}
+
+// WithoutBackgroundCleanup disables the periodic cleanup. Expired entries are
+// still hidden from reads but stay in memory until overwritten or removed.
+func WithoutBackgroundCleanup[T any]() Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.cleanupInterval = 0
+	}
+}
+
+// WithWriteThrough makes the pantry a cache in front of backend. Misses are
+// loaded from the backend, while writes and explicit removals are passed on
+// to it once applied. Expiration, eviction and Clear only affect the cache.
+func WithWriteThrough[T any](backend Backend[T]) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.backend = backend
+		pantry.writeBehind = 0
+	}
+}
+
+// WithWriteBehind works like WithWriteThrough but queues writes and removals,
+// flushing them every flushInterval and on Close. Only the latest write to a
+// key within an interval reaches the backend.
+func WithWriteBehind[T any](backend Backend[T], flushInterval time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.backend = backend
+		pantry.writeBehind = flushInterval
+	}
+}
+
+// WithCompactionInterval sets how often a pantry created by NewWithWAL
+// compacts its log into a snapshot. The default is one minute.
+func WithCompactionInterval[T any](d time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.compactionInterval = d
+	}
+}
+
+// WithCodec selects how Save, ExportSnapshot and Persist encode entries. The
+// default is GobCodec. The write-ahead log always uses gob.
+func WithCodec[T any](codec Codec) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if codec != nil {
+			pantry.codec = codec
+		}
+	}
+}
+
+// WithEncryption encrypts everything written by Save, ExportSnapshot,
+// Persist and the write-ahead log with AES-GCM, and verifies it on read. The
+// key must be 16, 24 or 32 bytes long.
+func WithEncryption[T any](key []byte) Option[T] {
+	aead, err := newAEAD(key)
+
+	return func(pantry *Pantry[T]) {
+		if err != nil {
+			pantry.invalidOption("encryption key: %v", err)
+			return
+		}
+		pantry.aead = aead
+	}
+}
+
+// WithCompression compresses the data written by Save and Persist. Load and
+// NewPersistent detect the compression on read, whatever this setting is,
+// among gzip and the registered ones. Snapshots are always gzip compressed.
+func WithCompression[T any](compression Compression) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if compression != nil {
+			RegisterCompression(compression)
+		}
+		pantry.compression = compression
+	}
+}
+
+// WithStaleRefresh sets the function GetStale uses to refresh a stale entry
+// in the background. On success the result is stored with the default
+// expiration, on error the stale value is kept.
+func WithStaleRefresh[T any](refresh func(key string, stale T) (T, error)) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.staleRefresh = refresh
+	}
+}
+
+// WithLoader sets the function Get uses to load missing keys, after the
+// backend if there is one. Loaded values are stored with the default
+// expiration. Concurrent misses for the same key share a single load, which
+// gets the context passed to New.
+func WithLoader[T any](loader func(ctx context.Context, key string) (T, error)) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.loader = loader
+	}
+}
+
+// WithRefreshAhead makes Get reload an entry in the background with the
+// loader once less than fraction of the default expiration is left, while
+// still returning the current value, so frequently read keys never expire.
+// The fraction must be between 0 and 1.
+func WithRefreshAhead[T any](fraction float64) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if fraction <= 0 || fraction >= 1 {
+			pantry.invalidOption("refresh-ahead fraction %v must be between 0 and 1", fraction)
+			return
+		}
+		pantry.refreshWindow = fraction
+	}
+}
+
+// WithSoftTTL makes entries stale once they were last written longer than d
+// ago, while they stay readable until they expire. Reading a stale entry
+// reloads it in the background with the loader, or with the stale refresh
+// function if there is no loader, and GetStale reports it as stale. d must be
+// positive and is only useful below the expiration of the entries.
+func WithSoftTTL[T any](d time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if d <= 0 {
+			pantry.invalidOption("soft TTL %v must be positive", d)
+			return
+		}
+		pantry.softTTL = d
+	}
+}
+
+// WithNegativeTTL remembers for ttl that the backend or the loader did not
+// find a key, so Get reports the miss without asking them again until a value
+// is set. A loader reports a missing key by returning ErrNotFound.
+func WithNegativeTTL[T any](ttl time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.negativeTTL = ttl
+	}
+}
+
+// WithCloner makes reads return copies made by clone instead of the stored
+// values, so callers can modify what Get, GetMany, GetStale, GetOrSet,
+// GetOrCompute and the iterators return without changing the cached data.
+// Values passed to the pantry are stored as they are.
+func WithCloner[T any](clone func(T) T) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.cloner = clone
+	}
+}
+
+// WithMaxCost bounds the total cost of the entries, as computed by cost for
+// every write. When the total exceeds the budget, the eviction policy picks
+// the entries to evict, just like WithMaxItems.
+func WithMaxCost[T any](total int64, cost func(key string, value T) int64) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.maxCost = total
+		pantry.costFn = cost
+	}
+}
+
+// WithTTLJitter spreads expirations by randomizing every TTL applied by a
+// write within ±fraction of it, so entries written together do not all
+// expire in the same cleanup. fraction must be between 0 and 1.
+func WithTTLJitter[T any](fraction float64) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if fraction <= 0 || fraction >= 1 {
+			pantry.invalidOption("TTL jitter fraction %v must be between 0 and 1", fraction)
+			return
+		}
+		pantry.jitter = fraction
+	}
+}
+
+// WithClock replaces the wall clock used for expiration and the background
+// tasks.
+func WithClock[T any](clock Clock) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if clock != nil {
+			pantry.clock = clock
+		}
+	}
+}
+
+// WithTTLBounds clamps every TTL, including the default expiration, to
+// between min and max, so a misconfigured duration can neither expire
+// entries right away nor keep them forever. Entries set with NoExpiration
+// get max. The bounds must satisfy 0 < min <= max.
+func WithTTLBounds[T any](min, max time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if min <= 0 || max < min {
+			pantry.invalidOption("TTL bounds %v and %v must satisfy 0 < min <= max", min, max)
+			return
+		}
+		pantry.minTTL, pantry.maxTTL = min, max
+	}
+}
+
+// WithEqual sets how values are compared by CompareAndSwap, SetIfChanged
+// and Transaction, for types where == or reflect.DeepEqual is too strict or
+// too slow.
+func WithEqual[T any](equal func(a, b T) bool) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.equal = equal
+	}
+}
+
+// WithTTLPolicy derives the TTL of entries stored without an explicit one,
+// such as by Set or GetOrSet, from the key and value, e.g. to follow the
+// expiry of a token held in the value. NoExpiration is allowed; other
+// non-positive TTLs expire the entry right away. Entries set with SetWithTTL
+// or SetWithExpireAt are not affected.
+func WithTTLPolicy[T any](policy func(key string, value T) time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.ttlPolicy = policy
+	}
+}
@@ -1,68 +1,1146 @@
 package pantry
 
 import (
+	"cmp"
 	"context"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"hash/maphash"
 	"iter"
+	"log/slog"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type item[T any] struct {
-	value   T
-	expires int64
+	value       T
+	expires     int64
+	accessed    uint64
+	onExpire    func(key string, value T)
+	tags        []string
+	cost        int64
+	written     int64
+	lastRead    int64
+	original    string
+	meta        map[string]string
+	version     uint64
+	fingerprint uint64
+}
+
+// Entry describes a stored entry. ExpiresAt is zero for entries that never
+// expire. CreatedAt is when its current value was
+// written, LastAccess when it was last read, only tracked with
+// WithAccessTracking or WithSlidingExpiration. Meta holds the labels set by
+// SetWithMeta.
+type Entry[T any] struct {
+	Key        string
+	Value      T
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	LastAccess time.Time
+	Meta       map[string]string
+	Version    uint64
 }
 
 type Pantry[T any] struct {
-	expiration time.Duration
-	store      map[string]item[T]
-	mutex      sync.RWMutex
+	expiration     time.Duration
+	shards         []*shard[T]
+	seed           maphash.Seed
+	accessTracking bool
+	versions       atomic.Uint64
+	accessCounter  atomic.Uint64
+	noLazyExpiry   bool
+	persistStats   bool
+	stableOrder    bool
+	readOptimized  bool
+	keyNormalizer  func(string) string
+	keyHasher      func(string) string
+	verifyKeys     bool
+	validityCheck  func(key string, value T) bool
+	iterations     chan struct{}
+	onEvict        atomic.Pointer[func(key string, value T, reason EvictionReason)]
+	onExpireRenew  atomic.Pointer[func(key string, value T) (T, time.Duration, bool)]
+	calls          map[string]*call[T]
+	callsMutex     sync.Mutex
+	keyLocks       keyLocks
+	refreshing     map[string]struct{}
+	staleRefresh   func(key string, stale T) (T, error)
+	loader         func(ctx context.Context, key string) (T, error)
+	refreshWindow  float64
+	softTTL        time.Duration
+	negativeTTL    time.Duration
+	filterItems    int
+	filterRate     float64
+	hotKeys        *hotKeys
+	tombstoneGrace time.Duration
+	ctx            context.Context
+	policy         policy
+	policyMutex    sync.Mutex
+	evictionPolicy EvictionPolicy
+	maxItems       int
+	maxCost        int64
+	costFn         func(key string, value T) int64
+	fingerprintFn  func(value T) uint64
+	sizeFn         func(key string, value T) int64
+	jitter         float64
+	minTTL         time.Duration
+	maxTTL         time.Duration
+	configErrs     []error
+	clock          Clock
+	cost           atomic.Int64
+	checksum       atomic.Uint64
+	cloner         func(T) T
+	equal          func(a, b T) bool
+	ttlPolicy      func(key string, value T) time.Duration
+	tracer         Tracer
+	logger         *slog.Logger
+	counters       counters
+	storage        Storage
+	autoPersist    bool
+	persists       persistQueue
+	sliding        bool
+	closed         atomic.Bool
+	closeOnce      sync.Once
+	drainOnce      sync.Once
+	drainErr       error
+	sweeper        *Sweeper
+	stopWatching   func()
+	done           chan struct{}
+	stopped        chan struct{}
+	subscribers    subscribers[T]
+	changes        *changeLog[T]
+	backend        Backend[T]
+	writeBehind    time.Duration
+	writes         writeQueue[T]
+	loads          loads[T]
+	onBackendError atomic.Pointer[func(key string, err error)]
+	onWarmProgress atomic.Pointer[func(loaded int)]
+	onExpireEntry  atomic.Pointer[func(entry Entry[T])]
+	wal            *writeAheadLog[T]
+	codec          Codec
+	aead           cipher.AEAD
+	compression    Compression
+	replication    replication[T]
+
+	cleanupInterval    time.Duration
+	cleanupMin         time.Duration
+	cleanupMax         time.Duration
+	initialCapacity    int
+	shardCount         int
+	compactionInterval time.Duration
+	options            []Option[T]
+
+	contentionSample int
+
+	pressureThreshold uint64
+	pressureFraction  float64
+	heapSize          func() uint64
+
+	snapshotStore    SnapshotStore
+	snapshotInterval time.Duration
+	snapshotKeep     int
+	lastSnapshot     atomic.Pointer[snapshotOutcome]
 }
 
+var (
+	ErrNotFound = errors.New("pantry: key not found")
+	ErrExpired  = errors.New("pantry: key expired")
+	// ErrCachedMiss wraps ErrNotFound for keys recorded as missing, see
+	// WithNegativeTTL.
+	ErrCachedMiss = fmt.Errorf("%w: cached miss", ErrNotFound)
+	// ErrKeyCollision wraps ErrNotFound for keys hashing to the entry of
+	// another key, see WithKeyHasher.
+	ErrKeyCollision = fmt.Errorf("%w: key collision", ErrNotFound)
+)
+
+// Get returns the value of a live entry. An expired entry found on the way is
+// removed right away instead of waiting for the cleanup.
 func (pantry *Pantry[T]) Get(key string) (T, bool) {
-	pantry.mutex.RLock()
-	defer pantry.mutex.RUnlock()
+	item, found := pantry.get(key)
+	return item.value, found
+}
 
-	item, found := pantry.store[key]
-	if found && time.Now().UnixNano() > item.expires {
-		return *new(T), false
+// GetRef is like Get but returns a pointer to a copy of the value, nil on a
+// miss, for callers passing the result on where a stored zero value must not
+// be mistaken for a missing one. Writing through the pointer does not change
+// the stored value.
+func (pantry *Pantry[T]) GetRef(key string) (*T, bool) {
+	item, found := pantry.get(key)
+	if !found {
+		return nil, false
 	}
-	return item.value, found
+	return &item.value, true
+}
+
+// GetContext is like GetE, passing ctx to the tracer. Waiting for a backend
+// load or loader run shared with other callers ends with ctx.Err() once ctx
+// is done; the load itself goes on and stores its result.
+func (pantry *Pantry[T]) GetContext(ctx context.Context, key string) (T, error) {
+	item, err := pantry.find(ctx, key)
+	return item.value, err
+}
+
+// GetE is like Get but reports a miss as ErrNotFound, or as ErrExpired when
+// the entry was there but had already expired.
+func (pantry *Pantry[T]) GetE(key string) (T, error) {
+	item, err := pantry.find(context.Background(), key)
+	return item.value, err
+}
+
+// GetWithExpiration returns the value together with the time it expires, the
+// zero time if it never does.
+func (pantry *Pantry[T]) GetWithExpiration(key string) (T, time.Time, bool) {
+	item, found := pantry.get(key)
+	if !found {
+		return item.value, time.Time{}, false
+	}
+	return item.value, expirationTime(item.expires), true
+}
+
+// TTL returns the remaining lifetime of a live entry.
+func (pantry *Pantry[T]) TTL(key string) (time.Duration, bool) {
+	key = pantry.normalize(key)
+
+	item, found := pantry.lookup(key)
+	now := pantry.clock.Now().UnixNano()
+	if !found || pantry.isExpired(key, item, now) {
+		return 0, false
+	}
+	if item.expires == neverExpires {
+		return NoExpiration, true
+	}
+	return time.Duration(item.expires - now), true
+}
+
+func (pantry *Pantry[T]) get(key string) (item[T], bool) {
+	item, err := pantry.find(context.Background(), key)
+	return item, err == nil
+}
+
+func (pantry *Pantry[T]) find(ctx context.Context, key string) (stored item[T], err error) {
+	if pantry.tracer != nil {
+		end := pantry.tracer.Start(ctx, "get", key)
+		defer func() { end(err == nil, operationError(err)) }()
+	}
+
+	canonical := pantry.canonical(key)
+	key = pantry.hash(canonical)
+	shard := pantry.shardFor(key)
+
+	if pantry.hotKeys != nil {
+		pantry.hotKeys.record(canonical)
+	}
+
+	var present, found, missed bool
+	if shard.mayContain(key) {
+		if stored, found = pantry.readPublished(shard, key); !found {
+			pantry.lockForRead(shard)
+			_, present = shard.store[key]
+			stored, found = pantry.read(shard, key)
+			missed = !found && shard.missed(key, pantry.clock.Now().UnixNano())
+			pantry.publishSnapshot(shard)
+			pantry.unlockForRead(shard)
+		}
+	} else {
+		pantry.counters.misses.Add(1)
+	}
+
+	if present && !found {
+		pantry.expireKey(shard, key)
+	}
+
+	if found && pantry.verifyKeys && stored.original != "" && stored.original != canonical {
+		return item[T]{}, ErrKeyCollision
+	}
+
+	if found {
+		pantry.refreshAhead(canonical, stored)
+		stored.value = pantry.clone(stored.value)
+		return stored, nil
+	}
+
+	if missed {
+		return item[T]{}, ErrCachedMiss
+	}
+
+	if pantry.backend != nil {
+		loaded, found, err := pantry.loadFromBackend(ctx, canonical, key)
+		if err != nil {
+			return item[T]{}, err
+		}
+		if found {
+			loaded.value = pantry.clone(loaded.value)
+			return loaded, nil
+		}
+	}
+
+	if pantry.loader != nil {
+		loaded, err := pantry.loadFromLoader(ctx, canonical, key)
+		if err != nil {
+			return loaded, err
+		}
+		loaded.value = pantry.clone(loaded.value)
+		return loaded, nil
+	}
+
+	if present {
+		return item[T]{}, ErrExpired
+	}
+	return item[T]{}, ErrNotFound
+}
+
+// expireKey removes the entry if it is still expired once the write lock is
+// taken, since it may have been replaced in the meantime.
+func (pantry *Pantry[T]) expireKey(shard *shard[T], key string) {
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now().UnixNano()
+	stored, found := shard.store[key]
+	if !found || !pantry.isExpired(key, stored, now) {
+		return
+	}
+
+	pantry.drop(shard, key)
+	evictions = pantry.evict(evictions, key, stored, now, Expired)
+}
+
+// lockForRead takes the write lock when reads update the entry. Unlike
+// returning the unlock function, pairing it with unlockForRead does not
+// allocate on every read.
+func (pantry *Pantry[T]) lockForRead(shard *shard[T]) {
+	if pantry.accessTracking || pantry.sliding {
+		shard.mutex.Lock()
+		return
+	}
+	shard.mutex.RLock()
+}
+
+func (pantry *Pantry[T]) unlockForRead(shard *shard[T]) {
+	if pantry.accessTracking || pantry.sliding {
+		shard.mutex.Unlock()
+		return
+	}
+	shard.mutex.RUnlock()
+}
+
+// read must be called with the lock taken by lockForRead held.
+func (pantry *Pantry[T]) read(shard *shard[T], key string) (item[T], bool) {
+	stored, found := shard.store[key]
+	if found && !pantry.noLazyExpiry && pantry.isExpired(key, stored, pantry.clock.Now().UnixNano()) {
+		found = false
+	}
+
+	if !found {
+		pantry.counters.misses.Add(1)
+		return item[T]{}, false
+	}
+	pantry.counters.hits.Add(1)
+
+	if pantry.accessTracking || pantry.sliding {
+		now := pantry.clock.Now()
+		stored.accessed = pantry.nextAccess()
+		stored.lastRead = now.UnixNano()
+		if pantry.sliding {
+			stored.expires = pantry.expiresAt(now, pantry.defaultTTL(key, stored.value))
+			shard.schedule(key, stored.expires)
+		}
+		shard.store[key] = stored
+		shard.changed()
+	}
+
+	pantry.touch(key)
+	return stored, true
 }
 
 func (pantry *Pantry[T]) Set(key string, value T) {
-	pantry.mutex.Lock()
-	defer pantry.mutex.Unlock()
+	pantry.set(context.Background(), key, value, defaultTTL, time.Time{})
+}
 
-	pantry.store[key] = item[T]{
-		value:   value,
-		expires: time.Now().Add(pantry.expiration).UnixNano(),
+func (pantry *Pantry[T]) SetWithTTL(key string, value T, ttl time.Duration) {
+	pantry.set(context.Background(), key, value, ttl, time.Time{})
+}
+
+// SetContext is like Set, passing ctx to the tracer. It returns ctx.Err()
+// without storing the value if ctx is already done, and ErrClosed after
+// Close.
+func (pantry *Pantry[T]) SetContext(ctx context.Context, key string, value T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if pantry.closed.Load() {
+		return ErrClosed
 	}
+	pantry.set(ctx, key, value, defaultTTL, time.Time{})
+	return nil
 }
 
+// SetFromContext is like SetContext, but the entry expires no later than
+// the deadline of ctx, so a value fetched under a deadline-bound
+// authorization does not outlive it. Without a deadline the default
+// expiration applies.
+func (pantry *Pantry[T]) SetFromContext(ctx context.Context, key string, value T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if pantry.closed.Load() {
+		return ErrClosed
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		pantry.set(ctx, key, value, defaultTTL, time.Time{})
+		return nil
+	}
+
+	now := pantry.clock.Now()
+	expires := pantry.expiresAt(now, pantry.defaultTTL(pantry.normalize(key), value))
+	expires = min(expires, now.Add(time.Until(deadline)).UnixNano())
+	pantry.set(ctx, key, value, 0, time.Unix(0, expires))
+	return nil
+}
+
+// SetWithExpireAt stores the value until the given moment instead of for a
+// duration. Jitter does not apply.
+func (pantry *Pantry[T]) SetWithExpireAt(key string, value T, at time.Time) {
+	pantry.set(context.Background(), key, value, 0, at)
+}
+
+// defaultTTL passed to set stands for the TTL from pantry.defaultTTL.
+const defaultTTL time.Duration = math.MinInt64
+
+// set stores the value until at, or for ttl if at is zero.
+func (pantry *Pantry[T]) set(ctx context.Context, key string, value T, ttl time.Duration, at time.Time) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	if pantry.tracer != nil {
+		defer pantry.tracer.Start(ctx, "set", key)(false, nil)
+	}
+
+	canonical := pantry.canonical(key)
+	pantry.setKey(canonical, pantry.hash(canonical), value, ttl, at)
+}
+
+// setKey stores the value under the normalized key. The canonical key is
+// only kept for collision checks and may be empty when it is not known.
+func (pantry *Pantry[T]) setKey(canonical, key string, value T, ttl time.Duration, at time.Time) {
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	expires := at.UnixNano()
+	if at.IsZero() {
+		if ttl == defaultTTL {
+			ttl = pantry.defaultTTL(key, value)
+		}
+		expires = pantry.expiresAt(now, ttl)
+	}
+
+	evictions = pantry.put(evictions, shard, key, pantry.newItem(canonical, value, expires), now.UnixNano())
+	evictions = pantry.writeBack(evictions, key, value, false)
+}
+
+// newItem builds the item written for a key, remembering its canonical form
+// for the collision checks of WithKeyHasher's verify.
+func (pantry *Pantry[T]) newItem(canonical string, value T, expires int64) item[T] {
+	stored := item[T]{
+		value:    value,
+		expires:  expires,
+		accessed: pantry.nextAccess(),
+	}
+	if pantry.verifyKeys {
+		stored.original = canonical
+	}
+	return stored
+}
+
+// put must be called with the shard's write lock held.
+func (pantry *Pantry[T]) put(evictions []eviction[T], shard *shard[T], key string, value item[T], now int64) []eviction[T] {
+	if previous, found := shard.store[key]; found {
+		shard.untag(key, previous.tags)
+		pantry.cost.Add(-previous.cost)
+		pantry.checksum.Add(-previous.fingerprint)
+		evictions = pantry.evict(evictions, key, previous, now, Replaced)
+	}
+	if value.written == 0 {
+		value.written = now
+	}
+	value.version = pantry.versions.Add(1)
+	delete(shard.misses, key)
+	delete(shard.tombstones, key)
+	if pantry.costFn != nil {
+		value.cost = pantry.costFn(key, value.value)
+		pantry.cost.Add(value.cost)
+	}
+	if pantry.fingerprintFn != nil {
+		value.fingerprint = pantry.fingerprint(key, value.value)
+		pantry.checksum.Add(value.fingerprint)
+	}
+	shard.store[key] = value
+	shard.changed()
+	shard.admit(key)
+	shard.tag(key, value.tags)
+	shard.schedule(key, value.expires)
+	pantry.track(key)
+	pantry.logSet(key, value)
+	return pantry.event(evictions, EventSet, key, value.value, now)
+}
+
+// expiresAt returns when an entry written at now with ttl expires, spread
+// by the configured jitter.
+func (pantry *Pantry[T]) expiresAt(now time.Time, ttl time.Duration) int64 {
+	if pantry.maxTTL > 0 {
+		if ttl == NoExpiration || ttl > pantry.maxTTL {
+			ttl = pantry.maxTTL
+		}
+		ttl = max(ttl, pantry.minTTL)
+	}
+	if pantry.jitter > 0 && ttl != NoExpiration {
+		ttl += time.Duration((rand.Float64()*2 - 1) * pantry.jitter * float64(ttl))
+	}
+	return expiresAfter(now.UnixNano(), ttl)
+}
+
+// defaultTTL is the TTL of entries set without one: the one from the TTL
+// policy if there is one, or the default expiration.
+func (pantry *Pantry[T]) defaultTTL(key string, value T) time.Duration {
+	if pantry.ttlPolicy != nil {
+		return pantry.ttlPolicy(key, value)
+	}
+	return pantry.expiration
+}
+
+func (pantry *Pantry[T]) clone(value T) T {
+	if pantry.cloner == nil {
+		return value
+	}
+	return pantry.cloner(value)
+}
+
+// normalize returns the key an entry is stored under.
+func (pantry *Pantry[T]) normalize(key string) string {
+	return pantry.hash(pantry.canonical(key))
+}
+
+// canonical applies the key normalizer only. Loaders and collision checks
+// see canonical keys, as hashed ones cannot be mapped back.
+func (pantry *Pantry[T]) canonical(key string) string {
+	if pantry.keyNormalizer == nil {
+		return key
+	}
+	return pantry.keyNormalizer(key)
+}
+
+func (pantry *Pantry[T]) hash(canonical string) string {
+	if pantry.keyHasher == nil {
+		return canonical
+	}
+	return pantry.keyHasher(canonical)
+}
+
+func (pantry *Pantry[T]) isExpired(key string, item item[T], now int64) bool {
+	if now > item.expires {
+		return true
+	}
+	return pantry.validityCheck != nil && !pantry.validityCheck(key, item.value)
+}
+
+func (pantry *Pantry[T]) acquireIteration() func() {
+	if pantry.iterations == nil {
+		return func() {}
+	}
+
+	pantry.iterations <- struct{}{}
+	return func() {
+		<-pantry.iterations
+	}
+}
+
+// SetWithCallback stores the value like Set and calls onExpire once this
+// entry is removed after expiring, either by the cleanup or by the first read
+// finding it expired, whichever comes first. Like the other hooks, it runs
+// outside the lock.
+func (pantry *Pantry[T]) SetWithCallback(key string, value T, onExpire func(key string, value T)) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	canonical := pantry.canonical(key)
+	key = pantry.hash(canonical)
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	stored := pantry.newItem(canonical, value, pantry.expiresAt(now, pantry.defaultTTL(key, value)))
+	stored.onExpire = onExpire
+	evictions = pantry.put(evictions, shard, key, stored, now.UnixNano())
+	evictions = pantry.writeBack(evictions, key, value, false)
+}
+
+func (pantry *Pantry[T]) nextAccess() uint64 {
+	if !pantry.accessTracking {
+		return 0
+	}
+	return pantry.accessCounter.Add(1)
+}
+
+// RecentlyUsed returns up to n live entries ordered from the most to the
+// least recently accessed. Access order is only maintained when the pantry
+// was created with WithAccessTracking or uses LRU eviction, otherwise it
+// returns nil.
+func (pantry *Pantry[T]) RecentlyUsed(n int) []Entry[T] {
+	if n <= 0 {
+		return nil
+	}
+
+	if !pantry.accessTracking {
+		if lru, ok := pantry.policy.(*lruPolicy); ok {
+			return pantry.recentlyUsedFrom(lru, n)
+		}
+		return nil
+	}
+
+	pantry.rlockAll()
+	defer pantry.runlockAll()
+
+	type accessedEntry struct {
+		entry    Entry[T]
+		accessed uint64
+	}
+
+	now := pantry.clock.Now().UnixNano()
+	var candidates []accessedEntry
+	for key, item := range pantry.items() {
+		if pantry.isExpired(key, item, now) {
+			continue
+		}
+
+		candidates = append(candidates, accessedEntry{
+			entry:    pantry.entry(key, item),
+			accessed: item.accessed,
+		})
+	}
+
+	slices.SortFunc(candidates, func(a, b accessedEntry) int {
+		return cmp.Compare(b.accessed, a.accessed)
+	})
+
+	entries := make([]Entry[T], 0, min(n, len(candidates)))
+	for _, candidate := range candidates[:min(n, len(candidates))] {
+		entries = append(entries, candidate.entry)
+	}
+	return entries
+}
+
+// UpdateWithExpiry passes the current value and its remaining lifetime to fn
+// under the write lock. When fn returns store as true, the returned value is
+// stored with the returned TTL.
+func (pantry *Pantry[T]) UpdateWithExpiry(key string, fn func(value T, remaining time.Duration, found bool) (newValue T, newTTL time.Duration, store bool)) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	canonical := pantry.canonical(key)
+	key = pantry.hash(canonical)
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	current, found := shard.store[key]
+	if found && pantry.isExpired(key, current, now.UnixNano()) {
+		current, found = item[T]{}, false
+	}
+
+	var remaining time.Duration
+	if found {
+		remaining = time.Duration(current.expires - now.UnixNano())
+	}
+
+	value, ttl, store := fn(current.value, remaining, found)
+	if !store {
+		return
+	}
+
+	evictions = pantry.put(evictions, shard, key, pantry.newItem(canonical, value, pantry.expiresAt(now, ttl)), now.UnixNano())
+	evictions = pantry.writeBack(evictions, key, value, false)
+}
+
+// Update atomically replaces the value of key with the one returned by fn.
+// Existing entries keep their expiration and new ones get the default TTL.
+// When fn returns false the entry is removed instead.
+func (pantry *Pantry[T]) Update(key string, fn func(old T, exists bool) (T, bool)) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	canonical := pantry.canonical(key)
+	key = pantry.hash(canonical)
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	current, found := shard.store[key]
+	exists := found && !pantry.isExpired(key, current, now.UnixNano())
+
+	var old T
+	if exists {
+		old = current.value
+	}
+
+	value, keep := fn(old, exists)
+	if !keep {
+		if found {
+			pantry.drop(shard, key)
+			evictions = pantry.evict(evictions, key, current, now.UnixNano(), Removed)
+			evictions = pantry.writeBack(evictions, key, current.value, true)
+		}
+		return
+	}
+
+	expires := pantry.expiresAt(now, pantry.defaultTTL(key, value))
+	if exists {
+		expires = current.expires
+	}
+
+	evictions = pantry.put(evictions, shard, key, pantry.newItem(canonical, value, expires), now.UnixNano())
+	evictions = pantry.writeBack(evictions, key, value, false)
+}
+
+// Touch resets the expiration of a live entry to the default TTL.
+func (pantry *Pantry[T]) Touch(key string) bool {
+	return pantry.refresh(key, func(key string, item item[T]) int64 {
+		return pantry.expiresAt(pantry.clock.Now(), pantry.defaultTTL(key, item.value))
+	})
+}
+
+// Extend pushes the expiration of a live entry out by d.
+// With WithTTLBounds the remaining TTL stays within the bounds.
+func (pantry *Pantry[T]) Extend(key string, d time.Duration) bool {
+	return pantry.refresh(key, func(_ string, item item[T]) int64 {
+		if item.expires == neverExpires {
+			return item.expires
+		}
+		expires := expiresAfter(item.expires, d)
+		if pantry.maxTTL > 0 {
+			now := pantry.clock.Now().UnixNano()
+			expires = min(max(expires, expiresAfter(now, pantry.minTTL)), expiresAfter(now, pantry.maxTTL))
+		}
+		return expires
+	})
+}
+
+// Expire makes a live entry expire right away, as if its TTL ran out, so the
+// expiration hooks and events fire. It reports whether there was one.
+func (pantry *Pantry[T]) Expire(key string) bool {
+	if pantry.closed.Load() {
+		return false
+	}
+
+	key = pantry.normalize(key)
+	shard := pantry.shardFor(key)
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now().UnixNano()
+	stored, found := shard.store[key]
+	if !found || pantry.isExpired(key, stored, now) {
+		return false
+	}
+
+	pantry.drop(shard, key)
+	evictions = pantry.evict(evictions, key, stored, now, Expired)
+	return true
+}
+
+func (pantry *Pantry[T]) refresh(key string, expiration func(key string, item item[T]) int64) bool {
+	if pantry.closed.Load() {
+		return false
+	}
+
+	key = pantry.normalize(key)
+	shard := pantry.shardFor(key)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	item, found := shard.store[key]
+	if !found || pantry.isExpired(key, item, pantry.clock.Now().UnixNano()) {
+		return false
+	}
+
+	item.expires = expiration(key, item)
+	shard.store[key] = item
+	shard.changed()
+	shard.schedule(key, item.expires)
+	pantry.logSet(key, item)
+	return true
+}
+
+// Remove deletes the entry, leaving a tombstone for Restore with
+// WithTombstones.
 func (pantry *Pantry[T]) Remove(key string) {
-	pantry.mutex.Lock()
-	defer pantry.mutex.Unlock()
+	pantry.RemoveContext(context.Background(), key)
+}
 
-	delete(pantry.store, key)
+// RemoveContext is like Remove, passing ctx to the tracer.
+func (pantry *Pantry[T]) RemoveContext(ctx context.Context, key string) {
+	if pantry.tracer != nil {
+		defer pantry.tracer.Start(ctx, "remove", key)(false, nil)
+	}
+
+	pantry.remove(pantry.normalize(key))
 }
 
-func (pantry *Pantry[T]) IsEmpty() bool {
-	pantry.mutex.RLock()
-	defer pantry.mutex.RUnlock()
+// remove deletes the entry of the normalized key.
+func (pantry *Pantry[T]) remove(key string) {
+	shard := pantry.shardFor(key)
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
 
-	return len(pantry.store) == 0
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	if item, found := shard.store[key]; found {
+		now := pantry.clock.Now().UnixNano()
+		pantry.drop(shard, key)
+		if pantry.tombstoneGrace > 0 {
+			shard.bury(key, item, now+int64(pantry.tombstoneGrace))
+		}
+		evictions = pantry.evict(evictions, key, item, now, Removed)
+	}
+	evictions = pantry.writeBack(evictions, key, *new(T), true)
 }
 
-func (pantry *Pantry[T]) Keys() iter.Seq[string] {
-	return func(yield func(string) bool) {
-		pantry.mutex.RLock()
-		defer pantry.mutex.RUnlock()
+// Pop atomically retrieves and removes a live entry.
+func (pantry *Pantry[T]) Pop(key string) (T, bool) {
+	key = pantry.normalize(key)
+	shard := pantry.shardFor(key)
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now().UnixNano()
+	item, found := shard.store[key]
+	if !found {
+		return *new(T), false
+	}
+
+	pantry.drop(shard, key)
+	evictions = pantry.evict(evictions, key, item, now, Removed)
+	evictions = pantry.writeBack(evictions, key, *new(T), true)
+
+	if pantry.isExpired(key, item, now) {
+		return *new(T), false
+	}
+	return item.value, true
+}
+
+func (pantry *Pantry[T]) Clear() {
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	pantry.lockAll()
+	defer pantry.unlockAll()
+
+	now := pantry.clock.Now().UnixNano()
+	for key, item := range pantry.items() {
+		evictions = pantry.evict(evictions, key, item, now, Removed)
+	}
+	pantry.resetShards()
+	pantry.logClear()
+	evictions = pantry.event(evictions, EventCleared, "", *new(T), now)
+}
+
+// resetShards must be called with all shards locked.
+func (pantry *Pantry[T]) resetShards() {
+	for _, shard := range pantry.shards {
+		shard.store = make(map[string]item[T], pantry.initialCapacity/len(pantry.shards))
+		shard.changed()
+		shard.expirations = nil
+		shard.tags = nil
+		shard.misses = nil
+		shard.tombstones = nil
+		pantry.rebuildFilter(shard, true)
+	}
+	pantry.cost.Store(0)
+	pantry.checksum.Store(0)
+	pantry.resetPolicy()
+}
+
+// Transaction passes a copy of the live entries to fn under the write lock and
+// commits the modified map atomically. Entries deleted from the map are
+// removed, new ones get the default expiration and existing ones keep theirs.
+// Only the entries fn added, removed or changed are written, notified and
+// written back; values are compared like SetIfChanged. Their cost is
+// recomputed and the capacity limits enforced afterwards.
+func (pantry *Pantry[T]) Transaction(fn func(store map[string]T)) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	pantry.lockAll()
+	defer pantry.unlockAll()
+
+	now := pantry.clock.Now().UnixNano()
+	view := make(map[string]T)
+	for key, item := range pantry.items() {
+		if pantry.isExpired(key, item, now) {
+			continue
+		}
+		view[key] = item.value
+	}
+
+	var stored map[string]struct{}
+	if pantry.keyNormalizer != nil || pantry.keyHasher != nil {
+		stored = make(map[string]struct{}, len(view))
+		for key := range view {
+			stored[key] = struct{}{}
+		}
+	}
+
+	fn(view)
+
+	// The keys fn found are normalized already, only the ones it added are
+	// not, and hashing is not idempotent.
+	canonicals := make(map[string]string)
+	if stored != nil {
+		normalized := make(map[string]T, len(view))
+		for key, value := range view {
+			if _, found := stored[key]; !found {
+				canonical := pantry.canonical(key)
+				key = pantry.hash(canonical)
+				canonicals[key] = canonical
+			}
+			normalized[key] = value
+		}
+		view = normalized
+	}
+
+	for key, item := range pantry.items() {
+		if pantry.isExpired(key, item, now) {
+			continue
+		}
+
+		if _, found := view[key]; !found {
+			pantry.drop(pantry.shardFor(key), key)
+			evictions = pantry.evict(evictions, key, item, now, Removed)
+			evictions = pantry.writeBack(evictions, key, item.value, true)
+		}
+	}
 
-		for key, item := range pantry.store {
-			if time.Now().UnixNano() > item.expires {
+	for key, value := range view {
+		shard := pantry.shardFor(key)
+
+		// Existing entries keep their expiration and tags, but are stored
+		// like any other write so the log, cost and indexes follow.
+		updated, found := shard.store[key]
+		if found && !pantry.isExpired(key, updated, now) {
+			if pantry.equals(updated.value, value) {
 				continue
 			}
+			updated.value = value
+			updated.written = 0
+		} else {
+			canonical, found := canonicals[key]
+			if !found {
+				canonical = key
+			}
+			updated = pantry.newItem(canonical, value, pantry.expiresAt(time.Unix(0, now), pantry.defaultTTL(key, value)))
+		}
+		evictions = pantry.put(evictions, shard, key, updated, now)
+
+		// Replication reads the stored entry, so it has to be written first.
+		evictions = pantry.writeBack(evictions, key, value, false)
+	}
+}
+
+// Replace swaps the contents of the pantry for items, each with the default
+// expiration, under a single write lock, so readers see either the old or
+// the new contents. Keys are normalized and expirations computed before the
+// lock is taken.
+func (pantry *Pantry[T]) Replace(items map[string]T) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	now := pantry.clock.Now()
+	replacements := make(map[string]item[T], len(items))
+	for key, value := range items {
+		canonical := pantry.canonical(key)
+		key = pantry.hash(canonical)
+		replacements[key] = pantry.newItem(canonical, value, pantry.expiresAt(now, pantry.defaultTTL(key, value)))
+	}
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	pantry.lockAll()
+	defer pantry.unlockAll()
+
+	for key, item := range pantry.items() {
+		if _, found := replacements[key]; found {
+			continue
+		}
+		pantry.drop(pantry.shardFor(key), key)
+		evictions = pantry.evict(evictions, key, item, now.UnixNano(), Removed)
+		evictions = pantry.writeBack(evictions, key, item.value, true)
+	}
+
+	for key, replacement := range replacements {
+		replacement.accessed = pantry.nextAccess()
+		evictions = pantry.put(evictions, pantry.shardFor(key), key, replacement, now.UnixNano())
+		evictions = pantry.writeBack(evictions, key, replacement.value, false)
+	}
+}
+
+// Purge removes every live entry matching pred under a single write lock and
+// returns the removed entries.
+func (pantry *Pantry[T]) Purge(pred func(key string, value T) bool) []Entry[T] {
+	var removed []Entry[T]
+	pantry.removeWhere(pred, func(key string, item item[T]) {
+		removed = append(removed, pantry.entry(key, item))
+	})
+	return removed
+}
+
+// RemoveWhere removes every live entry matching pred in one pass under a
+// single write lock and returns how many were removed, such as all entries
+// of a deleted tenant. pred runs with the lock held and must not call the
+// pantry; the removal hooks run after it is released.
+func (pantry *Pantry[T]) RemoveWhere(pred func(key string, value T) bool) int {
+	removed := 0
+	pantry.removeWhere(pred, func(string, item[T]) { removed++ })
+	return removed
+}
+
+func (pantry *Pantry[T]) removeWhere(pred func(key string, value T) bool, removed func(key string, item item[T])) {
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	pantry.lockAll()
+	defer pantry.unlockAll()
 
+	now := pantry.clock.Now().UnixNano()
+	for key, item := range pantry.items() {
+		if pantry.isExpired(key, item, now) || !pred(key, item.value) {
+			continue
+		}
+
+		pantry.drop(pantry.shardFor(key), key)
+		evictions = pantry.evict(evictions, key, item, now, Removed)
+		evictions = pantry.writeBack(evictions, key, item.value, true)
+		removed(key, item)
+	}
+}
+
+// Count returns the number of live entries, skipping expired ones the cleanup
+// has not removed yet.
+func (pantry *Pantry[T]) Count() int {
+	now := pantry.clock.Now().UnixNano()
+	count := 0
+	for _, shard := range pantry.shards {
+		shard.mutex.RLock()
+		for key, item := range shard.store {
+			if !pantry.isExpired(key, item, now) {
+				count++
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+	return count
+}
+
+// CountRaw returns the number of stored entries, including expired ones the
+// cleanup has not removed yet.
+func (pantry *Pantry[T]) CountRaw() int {
+	count := 0
+	for _, shard := range pantry.shards {
+		shard.mutex.RLock()
+		count += len(shard.store)
+		shard.mutex.RUnlock()
+	}
+	return count
+}
+
+// IsEmpty reports whether there are no live entries, agreeing with Get.
+func (pantry *Pantry[T]) IsEmpty() bool {
+	now := pantry.clock.Now().UnixNano()
+	for _, shard := range pantry.shards {
+		shard.mutex.RLock()
+		for key, item := range shard.store {
+			if !pantry.isExpired(key, item, now) {
+				shard.mutex.RUnlock()
+				return false
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+	return true
+}
+
+// Keys, Values and All iterate over a point-in-time snapshot of the live
+// entries taken when iteration starts. No lock is held while yielding, so the
+// loop body may read and modify the pantry; those changes are not reflected in
+// the running iteration. The order is random unless WithStableIteration is
+// set.
+func (pantry *Pantry[T]) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for key := range pantry.All() {
 			if !yield(key) {
 				return
 			}
@@ -72,64 +1150,334 @@ func (pantry *Pantry[T]) Keys() iter.Seq[string] {
 
 func (pantry *Pantry[T]) Values() iter.Seq[T] {
 	return func(yield func(T) bool) {
-		pantry.mutex.RLock()
-		defer pantry.mutex.RUnlock()
+		for _, value := range pantry.All() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
 
-		for _, item := range pantry.store {
-			if time.Now().UnixNano() > item.expires {
-				continue
+func (pantry *Pantry[T]) All() iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		release := pantry.acquireIteration()
+
+		pantry.rlockAll()
+		entries := pantry.snapshotEntries()
+		pantry.runlockAll()
+		release()
+
+		if pantry.stableOrder {
+			slices.SortFunc(entries, func(a, b snapshotEntry[T]) int {
+				return strings.Compare(a.Key, b.Key)
+			})
+		}
+
+		for _, entry := range entries {
+			if !yield(entry.Key, pantry.clone(entry.Value)) {
+				return
 			}
+		}
+	}
+}
 
-			if !yield(item.value) {
+// Entries yields the live entries with their metadata, iterating over a
+// snapshot like All.
+func (pantry *Pantry[T]) Entries() iter.Seq2[string, Entry[T]] {
+	return func(yield func(string, Entry[T]) bool) {
+		release := pantry.acquireIteration()
+
+		pantry.rlockAll()
+		now := pantry.clock.Now().UnixNano()
+		var entries []Entry[T]
+		for key, item := range pantry.items() {
+			if !pantry.isExpired(key, item, now) {
+				entries = append(entries, pantry.entry(key, item))
+			}
+		}
+		pantry.runlockAll()
+		release()
+
+		if pantry.stableOrder {
+			slices.SortFunc(entries, func(a, b Entry[T]) int {
+				return strings.Compare(a.Key, b.Key)
+			})
+		}
+
+		for _, entry := range entries {
+			entry.Value = pantry.clone(entry.Value)
+			if !yield(entry.Key, entry) {
 				return
 			}
 		}
 	}
 }
 
-func (pantry *Pantry[T]) All() iter.Seq2[string, T] {
-	return func(yield func(string, T) bool) {
-		pantry.mutex.RLock()
-		defer pantry.mutex.RUnlock()
+func (pantry *Pantry[T]) entry(key string, item item[T]) Entry[T] {
+	entry := Entry[T]{
+		Key:       key,
+		Value:     item.value,
+		ExpiresAt: expirationTime(item.expires),
+		CreatedAt: time.Unix(0, item.written),
+		Meta:      maps.Clone(item.meta),
+		Version:   item.version,
+	}
+	if item.lastRead != 0 {
+		entry.LastAccess = time.Unix(0, item.lastRead)
+	}
+	return entry
+}
 
-		for key, item := range pantry.store {
-			if time.Now().UnixNano() > item.expires {
-				continue
+// Filter yields the live entries for which pred returns true.
+func (pantry *Pantry[T]) Filter(pred func(key string, value T) bool) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		for key, value := range pantry.All() {
+			if pred(key, value) && !yield(key, value) {
+				return
 			}
+		}
+	}
+}
 
-			if !yield(key, item.value) {
+// SortedKeys yields the live keys in lexical order. The keys are collected
+// before the first one is yielded.
+func (pantry *Pantry[T]) SortedKeys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, key := range slices.Sorted(pantry.Keys()) {
+			if !yield(key) {
 				return
 			}
 		}
 	}
 }
 
-func New[T any](ctx context.Context, expiration time.Duration) *Pantry[T] {
+// Scan returns up to limit live keys starting with prefix in lexical order,
+// beginning after cursor, together with the cursor of the next page. The
+// first page is read with an empty cursor and the last one returns an empty
+// next cursor. As the cursor is the last key returned, keys added or removed
+// between calls are seen or skipped according to their place in the order,
+// and no key is returned twice.
+func (pantry *Pantry[T]) Scan(cursor string, limit int, prefix string) ([]string, string) {
+	if limit <= 0 {
+		return nil, ""
+	}
+
+	now := pantry.clock.Now().UnixNano()
+	var keys []string
+	for _, shard := range pantry.shards {
+		shard.mutex.RLock()
+		for key, item := range shard.store {
+			if (cursor == "" || key > cursor) && strings.HasPrefix(key, prefix) && !pantry.isExpired(key, item, now) {
+				keys = append(keys, key)
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+
+	slices.Sort(keys)
+	if len(keys) <= limit {
+		return keys, ""
+	}
+	keys = keys[:limit]
+	return keys, keys[limit-1]
+}
+
+// PurgeExpired runs a cleanup pass right away and returns how many expired
+// entries it removed.
+func (pantry *Pantry[T]) PurgeExpired() int {
+	return pantry.removeExpired()
+}
+
+func (pantry *Pantry[T]) removeExpired() int {
+	start := time.Now()
+	removed := 0
+	for _, shard := range pantry.shards {
+		removed += pantry.removeExpiredFrom(shard)
+	}
+	pantry.relieveMemoryPressure()
+	duration := time.Since(start)
+	pantry.counters.lastCleanupDuration.Store(int64(duration))
+	if pantry.logger != nil {
+		pantry.logger.Debug("pantry: cleanup", "removed", removed, "duration", duration)
+	}
+	return removed
+}
+
+func (pantry *Pantry[T]) removeExpiredFrom(shard *shard[T]) int {
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	defer pantry.rebuildFilter(shard, false)
+
+	shard.forgetMisses(pantry.clock.Now().UnixNano())
+	shard.purgeTombstones(pantry.clock.Now().UnixNano())
+
+	removed := 0
+
+	// A custom validity check can invalidate entries at any time, so only
+	// time based expiration can be served from the heap.
+	if pantry.validityCheck != nil {
+		for key, item := range shard.store {
+			now := pantry.clock.Now().UnixNano()
+			if !pantry.isExpired(key, item, now) {
+				continue
+			}
+
+			pantry.drop(shard, key)
+			evictions = pantry.evict(evictions, key, item, now, Expired)
+			removed++
+		}
+		shard.compact()
+		return removed
+	}
+
+	now := pantry.clock.Now().UnixNano()
+	for len(shard.expirations) > 0 && shard.expirations[0].expires < now {
+		entry := shard.expirations.pop()
+		if !shard.current(entry) {
+			continue
+		}
+
+		item := shard.store[entry.key]
+		pantry.drop(shard, entry.key)
+		evictions = pantry.evict(evictions, entry.key, item, now, Expired)
+		removed++
+	}
+	shard.compact()
+	return removed
+}
+
+// New creates a pantry whose entries expire after expiration unless set
+// with another TTL. NewValidated rejects configurations, such as a
+// non-positive expiration, that New accepts as given. Options given invalid
+// arguments are left out by New and reported to the logger.
+func New[T any](ctx context.Context, expiration time.Duration, options ...Option[T]) *Pantry[T] {
 	pantry := &Pantry[T]{
-		expiration: expiration,
-		store:      make(map[string]item[T]),
-		mutex:      sync.RWMutex{},
+		expiration:      expiration,
+		ctx:             ctx,
+		seed:            maphash.MakeSeed(),
+		cleanupInterval: 5 * time.Second,
+		shardCount:      defaultShardCount,
+		codec:           GobCodec{},
+		clock:           realClock{},
+		done:            make(chan struct{}),
+		stopped:         make(chan struct{}),
+	}
+
+	pantry.options = options
+	for _, option := range options {
+		option(pantry)
+	}
+	for _, err := range pantry.configErrs {
+		pantry.warn("pantry: option ignored", err)
+	}
+
+	if pantry.maxItems > 0 || pantry.maxCost > 0 {
+		pantry.policy = newPolicy(pantry.evictionPolicy)
+	}
+
+	pantry.shards = make([]*shard[T], pantry.shardCount)
+	for i := range pantry.shards {
+		pantry.shards[i] = &shard[T]{}
+		if pantry.contentionSample > 0 {
+			pantry.shards[i].mutex.contention = &contention{every: uint64(pantry.contentionSample)}
+		}
+	}
+	pantry.resetShards()
+
+	// The tickers are created before New returns, so a fake clock advanced
+	// right away already fires them.
+	var tick <-chan time.Time
+	var tickers []Ticker
+	adaptive := pantry.cleanupInterval > 0 && pantry.cleanupMax > 0
+	if adaptive {
+		pantry.cleanupInterval = min(max(pantry.cleanupInterval, pantry.cleanupMin), pantry.cleanupMax)
+	}
+	if pantry.cleanupInterval > 0 {
+		ticker := pantry.clock.NewTicker(pantry.cleanupInterval)
+		tickers = append(tickers, ticker)
+		tick = ticker.C()
+	}
+
+	var flush <-chan time.Time
+	if pantry.backend != nil && pantry.writeBehind > 0 {
+		ticker := pantry.clock.NewTicker(pantry.writeBehind)
+		tickers = append(tickers, ticker)
+		flush = ticker.C()
+	}
+
+	var upload <-chan time.Time
+	if pantry.snapshotStore != nil && pantry.snapshotInterval > 0 {
+		ticker := pantry.clock.NewTicker(pantry.snapshotInterval)
+		tickers = append(tickers, ticker)
+		upload = ticker.C()
+	}
+
+	pantry.startReplication()
+
+	if pantry.sweeper != nil {
+		pantry.sweeper.add(pantry)
+	}
+
+	// Without tickers only the context needs watching, which takes no
+	// goroutine.
+	if len(tickers) == 0 {
+		stop := context.AfterFunc(ctx, func() {
+			pantry.contextDone()
+			close(pantry.stopped)
+		})
+		pantry.stopWatching = func() {
+			if stop() {
+				close(pantry.stopped)
+			}
+		}
+		return pantry
 	}
 
 	go func() {
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
+		defer close(pantry.stopped)
+		defer func() {
+			for _, ticker := range tickers {
+				ticker.Stop()
+			}
+		}()
 
 		for {
 			select {
-			case <-ticker.C:
-				pantry.mutex.Lock()
-				for key, item := range pantry.store {
-					if time.Now().UnixNano() > item.expires {
-						delete(pantry.store, key)
-					}
+			case <-tick:
+				removed := pantry.removeExpired()
+				if !adaptive {
+					break
 				}
-				pantry.mutex.Unlock()
+
+				took := time.Duration(pantry.counters.lastCleanupDuration.Load())
+				interval := pantry.nextCleanupInterval(pantry.cleanupInterval, removed, pantry.CountRaw(), took)
+				if interval != pantry.cleanupInterval {
+					// The cleanup ticker is the first one.
+					tickers[0].Stop()
+					tickers[0] = pantry.clock.NewTicker(interval)
+					tick = tickers[0].C()
+					pantry.cleanupInterval = interval
+				}
+
+			case <-flush:
+				if err := pantry.flush(); err != nil {
+					pantry.warn("pantry: write-behind flush failed", err)
+				}
+
+			case <-upload:
+				if err := pantry.autoSnapshot(pantry.ctx); err != nil {
+					pantry.warn("pantry: snapshot upload failed", err)
+				}
+
+			case <-pantry.done:
+				return
 
 			case <-ctx.Done():
-				pantry.mutex.Lock()
-				pantry.store = make(map[string]item[T])
-				pantry.mutex.Unlock()
+				pantry.contextDone()
 				return
 			}
 		}
@@ -137,3 +1485,48 @@ func New[T any](ctx context.Context, expiration time.Duration) *Pantry[T] {
 
 	return pantry
 }
+
+// NewValidated is like New, but returns an error wrapping ErrInvalidConfig
+// instead of a pantry when the expiration is neither positive nor
+// NoExpiration, which would expire entries as soon as they are set, or an
+// option got invalid arguments. It suits durations read from configuration.
+func NewValidated[T any](ctx context.Context, expiration time.Duration, options ...Option[T]) (*Pantry[T], error) {
+	if expiration <= 0 && expiration != NoExpiration {
+		return nil, fmt.Errorf("%w: expiration %v must be positive or NoExpiration", ErrInvalidConfig, expiration)
+	}
+
+	pantry := New(ctx, expiration, options...)
+	if err := errors.Join(pantry.configErrs...); err != nil {
+		pantry.Close()
+		return nil, err
+	}
+	return pantry, nil
+}
+
+// invalidOption records an option given invalid arguments, which New leaves
+// out and NewValidated rejects.
+func (pantry *Pantry[T]) invalidOption(format string, args ...any) {
+	pantry.configErrs = append(pantry.configErrs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+}
+
+// contextDone drains the pantry and drops the entries once its context is
+// done.
+func (pantry *Pantry[T]) contextDone() {
+	if pantry.sweeper != nil {
+		pantry.sweeper.remove(pantry)
+	}
+	if err := pantry.drain(context.WithoutCancel(pantry.ctx)); err != nil {
+		pantry.warn("pantry: draining failed", err)
+	}
+	pantry.lockAll()
+	pantry.resetShards()
+	pantry.unlockAll()
+}
+
+// NewFromMap creates a pantry already holding the initial entries, stored
+// like SetMany with the default TTL, the capacity limits and the backend.
+func NewFromMap[T any](ctx context.Context, expiration time.Duration, initial map[string]T, options ...Option[T]) *Pantry[T] {
+	pantry := New(ctx, expiration, options...)
+	pantry.SetMany(initial)
+	return pantry
+}
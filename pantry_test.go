@@ -1,8 +1,15 @@
 package pantry
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -13,74 +20,902 @@ func TestContext(t *testing.T) {
 	cancel()
 }
 
+func TestNewFromMap(t *testing.T) {
+	before := time.Now()
+
+	p := NewFromMap(context.Background(), time.Hour, map[string]int{
+		"first":  1,
+		"second": 2,
+	})
+
+	for key, expected := range map[string]int{"first": 1, "second": 2} {
+		value, found := p.Get(key)
+		if !found || value != expected {
+			t.Fatal("not found")
+		}
+
+		stored, _ := p.lookup(key)
+		expires := time.Unix(0, stored.expires)
+		if expires.Before(before.Add(time.Hour)) || expires.After(time.Now().Add(time.Hour)) {
+			t.Fatal("wrong expiration")
+		}
+	}
+}
+
+func TestNewFromMapOptions(t *testing.T) {
+	backend := newMapBackend()
+	p := NewFromMap(context.Background(), time.Hour, map[string]string{
+		"first":  "1",
+		"second": "2",
+		"third":  "3",
+	},
+		WithMaxItems[string](2),
+		WithWriteThrough[string](backend),
+		WithTTLPolicy(func(key string, value string) time.Duration { return time.Minute }),
+	)
+	defer p.Close()
+
+	if count := p.Count(); count != 2 {
+		t.Fatal("capacity not enforced", count)
+	}
+	for key := range p.Keys() {
+		if ttl, _ := p.TTL(key); ttl > time.Minute {
+			t.Fatal("TTL policy ignored", ttl)
+		}
+	}
+	if _, found := backend.get("first"); !found {
+		t.Fatal("initial entries not written back")
+	}
+}
+
 func TestCleaning(t *testing.T) {
 	p := New[string](context.Background(), 100*time.Millisecond)
 
-	p.Set("test", "hello")
+	p.Set("test", "hello")
+
+	if _, found := p.Get("test"); !found {
+		t.Fatal("not found")
+	}
+
+	time.Sleep(5 * time.Second)
+
+	if _, found := p.Get("test"); found {
+		t.Fatal("found")
+	}
+
+	time.Sleep(time.Second)
+
+	if _, found := p.Get("test"); found {
+		t.Fatal("found")
+	}
+}
+
+func TestCleanupInterval(t *testing.T) {
+	p := New(context.Background(), 10*time.Millisecond, WithCleanupInterval[string](20*time.Millisecond))
+
+	p.Set("test", "hello")
+
+	time.Sleep(50 * time.Millisecond)
+
+	if !p.IsEmpty() {
+		t.Fatal("not cleaned")
+	}
+}
+
+func TestWithoutBackgroundCleanup(t *testing.T) {
+	p := New(context.Background(), 10*time.Millisecond,
+		WithCleanupInterval[string](5*time.Millisecond),
+		WithoutBackgroundCleanup[string](),
+	)
+
+	p.Set("test", "hello")
+
+	time.Sleep(30 * time.Millisecond)
+
+	if p.CountRaw() != 1 {
+		t.Fatal("cleaned")
+	}
+
+	if _, found := p.Get("test"); found {
+		t.Fatal("found")
+	}
+
+	if p.CountRaw() != 0 {
+		t.Fatal("expired entry kept after Get")
+	}
+}
+
+func TestInitialCapacity(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithInitialCapacity[int](1000))
+
+	p.Set("test", 1)
+
+	if _, found := p.Get("test"); !found {
+		t.Fatal("not found")
+	}
+}
+
+func TestIsEmtpy(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+
+	if !p.IsEmpty() {
+		t.Log(p.shards)
+		t.Fatal("not empty")
+	}
+}
+
+func TestSet(t *testing.T) {
+	key := "test"
+	value := "hello"
+
+	p := New[string](context.Background(), time.Hour)
+
+	if _, found := p.Get(key); found {
+		t.Log(p.shards)
+		t.Fatal("found")
+	}
+
+	p.Set(key, value)
+
+	if _, found := p.Get(key); !found {
+		t.Log(p.shards)
+		t.Fatal("not found")
+	}
+}
+
+func TestSetWithTTL(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.SetWithTTL("short", 1, 10*time.Millisecond)
+	p.Set("long", 2)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, found := p.Get("short"); found {
+		t.Fatal("short found")
+	}
+
+	if _, found := p.Get("long"); !found {
+		t.Fatal("long not found")
+	}
+
+	counter := 0
+	for range p.All() {
+		counter++
+	}
+
+	if counter != 1 {
+		t.Fatal("not 1 item")
+	}
+
+	p.removeExpired()
+
+	if _, found := p.lookup("short"); found {
+		t.Fatal("not cleaned")
+	}
+}
+
+func TestUpdateWithExpiryRefreshWhenLow(t *testing.T) {
+	p := New[int](context.Background(), 5*time.Second)
+
+	p.Set(t.Name(), 1)
+
+	p.UpdateWithExpiry(t.Name(), func(value int, remaining time.Duration, found bool) (int, time.Duration, bool) {
+		if !found || remaining > 10*time.Second {
+			return value, 0, false
+		}
+		return value + 1, time.Hour, true
+	})
+
+	if value, _ := p.Get(t.Name()); value != 2 {
+		t.Fatal("not refreshed")
+	}
+}
+
+func TestUpdateWithExpirySkipWhenHigh(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set(t.Name(), 1)
+
+	p.UpdateWithExpiry(t.Name(), func(value int, remaining time.Duration, found bool) (int, time.Duration, bool) {
+		if !found || remaining > 10*time.Second {
+			return value, 0, false
+		}
+		return value + 1, time.Hour, true
+	})
+
+	if value, _ := p.Get(t.Name()); value != 1 {
+		t.Fatal("refreshed")
+	}
+}
+
+func TestUpdateWithExpiryCreateOnMissing(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.UpdateWithExpiry(t.Name(), func(value int, remaining time.Duration, found bool) (int, time.Duration, bool) {
+		if found || remaining != 0 {
+			t.Fatal("found")
+		}
+		return 1, 10 * time.Millisecond, true
+	})
+
+	if value, _ := p.Get(t.Name()); value != 1 {
+		t.Fatal("not created")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, found := p.Get(t.Name()); found {
+		t.Fatal("ttl not applied")
+	}
+}
+
+func TestSetWithCallback(t *testing.T) {
+	p := New[int](context.Background(), 10*time.Millisecond)
+
+	fired := make(map[string]int)
+	onExpire := func(key string, value int) {
+		fired[key] = value
+	}
+
+	p.SetWithCallback("first", 1, onExpire)
+	p.Set("second", 2)
+
+	time.Sleep(20 * time.Millisecond)
+
+	p.SetWithCallback("third", 3, onExpire)
+	p.removeExpired()
+
+	if len(fired) != 1 || fired["first"] != 1 {
+		t.Log(fired)
+		t.Fatal("wrong callbacks fired")
+	}
+}
+
+func TestSetWithCallbackOnRead(t *testing.T) {
+	p := New(context.Background(), 10*time.Millisecond, WithoutBackgroundCleanup[int]())
+
+	var fired atomic.Int32
+	var entries atomic.Int32
+	p.OnExpireEntry(func(Entry[int]) { entries.Add(1) })
+	p.SetWithCallback("key", 1, func(string, int) { fired.Add(1) })
+
+	time.Sleep(20 * time.Millisecond)
+
+	// The read removes the entry and fires the hooks before returning.
+	if _, found := p.Get("key"); found {
+		t.Fatal("expired entry returned")
+	}
+	if fired.Load() != 1 || entries.Load() != 1 {
+		t.Fatal("hooks not fired on read", fired.Load(), entries.Load())
+	}
+
+	p.removeExpired()
+	p.Get("key")
+	if fired.Load() != 1 || entries.Load() != 1 {
+		t.Fatal("hooks fired again", fired.Load(), entries.Load())
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Update(t.Name(), func(old int, exists bool) (int, bool) {
+				return old + 1, true
+			})
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := p.Get(t.Name()); value != 100 {
+		t.Fatal("not 100")
+	}
+}
+
+func TestUpdateKeepsExpiration(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.SetWithTTL(t.Name(), 1, time.Minute)
+	_, before, _ := p.GetWithExpiration(t.Name())
+
+	p.Update(t.Name(), func(old int, exists bool) (int, bool) {
+		return old + 1, true
+	})
+
+	value, after, _ := p.GetWithExpiration(t.Name())
+	if value != 2 || !after.Equal(before) {
+		t.Fatal("expiration changed")
+	}
+}
+
+func TestUpdateDelete(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set(t.Name(), 1)
+
+	p.Update(t.Name(), func(old int, exists bool) (int, bool) {
+		if !exists {
+			t.Fatal("not exists")
+		}
+		return 0, false
+	})
+
+	if _, found := p.Get(t.Name()); found {
+		t.Fatal("not deleted")
+	}
+}
+
+func TestTouch(t *testing.T) {
+	p := New[int](context.Background(), 30*time.Millisecond)
+
+	p.Set(t.Name(), 1)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if !p.Touch(t.Name()) {
+		t.Fatal("not touched")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, found := p.Get(t.Name()); !found {
+		t.Fatal("not found")
+	}
+
+	if p.Touch("missing") {
+		t.Fatal("missing touched")
+	}
+}
+
+func TestExtend(t *testing.T) {
+	p := New[int](context.Background(), 10*time.Millisecond)
+
+	p.Set(t.Name(), 1)
+
+	if !p.Extend(t.Name(), time.Hour) {
+		t.Fatal("not extended")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, found := p.Get(t.Name()); !found {
+		t.Fatal("not found")
+	}
+
+	p.SetWithTTL("expired", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if p.Extend("expired", time.Hour) {
+		t.Fatal("expired extended")
+	}
+}
+
+func TestExpire(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	var reason EvictionReason = -1
+	p.OnEvict(func(_ string, _ int, r EvictionReason) { reason = r })
+
+	p.Set(t.Name(), 1)
+
+	if !p.Expire(t.Name()) {
+		t.Fatal("not expired")
+	}
+	if _, found := p.Get(t.Name()); found {
+		t.Fatal("found after expire")
+	}
+	if reason != Expired {
+		t.Fatal("unexpected reason", reason)
+	}
+	if p.Expire(t.Name()) {
+		t.Fatal("expired twice")
+	}
+}
+
+func TestGetWithExpiration(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	before := time.Now()
+	p.Set(t.Name(), 1)
+
+	value, expires, found := p.GetWithExpiration(t.Name())
+	if !found || value != 1 {
+		t.Fatal("not found")
+	}
+
+	if expires.Before(before.Add(time.Hour)) || expires.After(time.Now().Add(time.Hour)) {
+		t.Fatal("wrong expiration")
+	}
+
+	if _, expires, found := p.GetWithExpiration("missing"); found || !expires.IsZero() {
+		t.Fatal("missing found")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.SetWithTTL(t.Name(), 1, time.Minute)
+
+	ttl, found := p.TTL(t.Name())
+	if !found || ttl <= 0 || ttl > time.Minute {
+		t.Fatal("wrong ttl")
+	}
+
+	if _, found := p.TTL("missing"); found {
+		t.Fatal("missing found")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	key := "test"
+	value := "hello"
+
+	p := New[string](context.Background(), time.Hour)
+
+	p.Set(key, value)
+
+	if _, found := p.Get(key); !found {
+		t.Log(p.shards)
+		t.Fatal("not found")
+	}
+
+	p.Remove(key)
+
+	if _, found := p.Get(key); found {
+		t.Log(p.shards)
+		t.Fatal("found")
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Set("third", 3)
+
+	p.Transaction(func(store map[string]int) {
+		for key, value := range store {
+			store[key] = value * 10
+		}
+		delete(store, "third")
+		store["fourth"] = 40
+	})
+
+	if value, _ := p.Get("first"); value != 10 {
+		t.Fatal("not transformed")
+	}
+
+	if _, found := p.Get("third"); found {
+		t.Fatal("not removed")
+	}
+
+	if value, _ := p.Get("fourth"); value != 40 {
+		t.Fatal("not added")
+	}
+}
+
+func TestTransactionWritesChangesOnly(t *testing.T) {
+	backend := newMapBackend()
+	p := New(context.Background(), time.Hour, WithWriteThrough[string](backend))
+
+	p.Set("kept", "same")
+	p.Set("changed", "old")
+	p.Set("removed", "gone")
+	backend.Store("kept", "untouched")
+
+	var events []string
+	p.OnEvict(func(key string, _ string, reason EvictionReason) {
+		events = append(events, key+" "+reason.String())
+	})
+
+	p.Transaction(func(store map[string]string) {
+		store["kept"] = "same"
+		store["changed"] = "new"
+		delete(store, "removed")
+		store["added"] = "fresh"
+	})
+
+	slices.Sort(events)
+	if !slices.Equal(events, []string{"changed replaced", "removed removed"}) {
+		t.Fatal("unexpected evictions", events)
+	}
+	if value, _ := backend.get("kept"); value != "untouched" {
+		t.Fatal("unchanged entry written back", value)
+	}
+	if value, _ := backend.get("changed"); value != "new" {
+		t.Fatal("changed entry not written back", value)
+	}
+	if value, _ := backend.get("added"); value != "fresh" {
+		t.Fatal("added entry not written back", value)
+	}
+	if _, found := backend.get("removed"); found {
+		t.Fatal("removed entry not deleted")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.SetWithTTL("first", 1, time.Minute)
+	p.Set("second", 2)
+
+	var removed []string
+	p.OnEvict(func(key string, _ int, reason EvictionReason) {
+		if reason == Removed {
+			removed = append(removed, key)
+		}
+	})
+
+	p.Replace(map[string]int{"first": 10, "third": 30})
+
+	if value, _ := p.Get("first"); value != 10 {
+		t.Fatal("first not replaced")
+	}
+	if ttl, _ := p.TTL("first"); ttl <= time.Minute {
+		t.Fatal("first kept its old expiration", ttl)
+	}
+	if _, found := p.Get("second"); found {
+		t.Fatal("second not removed")
+	}
+	if value, _ := p.Get("third"); value != 30 {
+		t.Fatal("third not added")
+	}
+	if !slices.Equal(removed, []string{"second"}) {
+		t.Fatal("unexpected removals", removed)
+	}
+}
+
+func TestTransactionAtomic(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	for i := 0; i < 100; i++ {
+		p.Set(strconv.Itoa(i), 0)
+	}
+
+	done := make(chan struct{})
+	failed := make(chan struct{}, 1)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			seen := -1
+			for _, value := range p.All() {
+				if seen != -1 && value != seen {
+					failed <- struct{}{}
+					return
+				}
+				seen = value
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		p.Transaction(func(store map[string]int) {
+			for key, value := range store {
+				store[key] = value + 1
+			}
+		})
+	}
+	close(done)
+
+	select {
+	case <-failed:
+		t.Fatal("partial state observed")
+	default:
+	}
+}
+
+func TestKeyNormalizer(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithKeyNormalizer[string](strings.ToLower))
+
+	p.Set("Foo", "bar")
+
+	if _, found := p.Get("foo"); !found {
+		t.Fatal("not found")
+	}
+
+	for key := range p.Keys() {
+		if key != "foo" {
+			t.Fatal("not normalized")
+		}
+	}
+
+	p.Remove("FOO")
+
+	if !p.IsEmpty() {
+		t.Fatal("not removed")
+	}
+}
+
+func TestKeyHasher(t *testing.T) {
+	// Keeps only the first letter, so keys sharing it collide.
+	first := func(key string) string { return key[:1] }
+	p := New(context.Background(), time.Hour,
+		WithKeyNormalizer[string](strings.ToLower),
+		WithKeyHasher[string](first, true),
+		WithLoader(func(_ context.Context, key string) (string, error) {
+			return "loaded " + key, nil
+		}),
+	)
+
+	p.Set("Apple", "red")
+
+	if value, _ := p.Get("APPLE"); value != "red" {
+		t.Fatal("unexpected value", value)
+	}
+
+	if _, err := p.GetE("avocado"); !errors.Is(err, ErrKeyCollision) || !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected a collision, got", err)
+	}
+
+	if keys := slices.Collect(p.Keys()); !slices.Equal(keys, []string{"a"}) {
+		t.Fatal("unexpected keys", keys)
+	}
+
+	if value, _ := p.Get("Banana"); value != "loaded banana" {
+		t.Fatal("loader did not get the original key", value)
+	}
+
+	p.Transaction(func(store map[string]string) {
+		store["cherry"] = "dark"
+	})
+
+	if value, _ := p.Get("apple"); value != "red" {
+		t.Fatal("transaction changed existing keys", value)
+	}
+	if value, _ := p.Get("cherry"); value != "dark" {
+		t.Fatal("transaction did not hash new keys", value)
+	}
+}
+
+func TestKeyHasherWritePaths(t *testing.T) {
+	first := func(key string) string { return key[:1] }
+	writes := map[string]func(p *Pantry[string]){
+		"SetMany":   func(p *Pantry[string]) { p.SetMany(map[string]string{"apple": "red"}) },
+		"SetTagged": func(p *Pantry[string]) { p.SetTagged("apple", "red", "fruit") },
+		"SetWithMeta": func(p *Pantry[string]) {
+			p.SetWithMeta("apple", "red", map[string]string{"source": "test"})
+		},
+		"SetWithCallback": func(p *Pantry[string]) { p.SetWithCallback("apple", "red", nil) },
+		"Update": func(p *Pantry[string]) {
+			p.Update("apple", func(string, bool) (string, bool) { return "red", true })
+		},
+		"UpdateWithExpiry": func(p *Pantry[string]) {
+			p.UpdateWithExpiry("apple", func(string, time.Duration, bool) (string, time.Duration, bool) {
+				return "red", time.Minute, true
+			})
+		},
+		"GetOrSet":    func(p *Pantry[string]) { p.GetOrSet("apple", "red") },
+		"SetIfAbsent": func(p *Pantry[string]) { p.SetIfAbsent("apple", "red") },
+		"GetOrCompute": func(p *Pantry[string]) {
+			p.GetOrCompute("apple", func() (string, error) { return "red", nil })
+		},
+		"CompareAndSwapFunc": func(p *Pantry[string]) {
+			p.Set("apple", "green")
+			p.shards[0].store["a"] = item[string]{value: "green", expires: neverExpires}
+			p.CompareAndSwapFunc("apple", "green", "red", func(a, b string) bool { return a == b })
+		},
+		"Transaction": func(p *Pantry[string]) {
+			p.Transaction(func(store map[string]string) { store["apple"] = "red" })
+		},
+	}
+
+	for name, write := range writes {
+		t.Run(name, func(t *testing.T) {
+			p := New(context.Background(), time.Hour, WithShards[string](1), WithKeyHasher[string](first, true))
+			write(p)
+
+			if value, err := p.GetE("apple"); err != nil || value != "red" {
+				t.Fatal("not written", value, err)
+			}
+			if _, err := p.GetE("avocado"); !errors.Is(err, ErrKeyCollision) {
+				t.Fatal("expected a collision, got", err)
+			}
+		})
+	}
+
+	t.Run("restore", func(t *testing.T) {
+		dir := t.TempDir()
+		p, _ := NewPersistent(context.Background(), time.Hour, dir, WithKeyHasher[string](first, true))
+		p.Set("apple", "red")
+		if err := p.Persist("apple"); err != nil {
+			t.Fatal(err)
+		}
+
+		restored, errs := NewPersistent(context.Background(), time.Hour, dir, WithKeyHasher[string](first, true))
+		if len(errs) != 0 {
+			t.Fatal(errs)
+		}
+		if value, err := restored.GetE("apple"); err != nil || value != "red" {
+			t.Fatal("not restored", value, err)
+		}
+		if _, err := restored.GetE("avocado"); !errors.Is(err, ErrKeyCollision) {
+			t.Fatal("expected a collision, got", err)
+		}
+	})
+}
+
+func TestKeyHasherRoundTrips(t *testing.T) {
+	// The hashed keys are not left alone by the normalizer.
+	hash := func(key string) string { return "#" + strings.ToUpper(key) }
+	formats := map[string]func(from, to *Pantry[string]) error{
+		"Save": func(from, to *Pantry[string]) error {
+			var buffer bytes.Buffer
+			if err := from.Save(&buffer); err != nil {
+				return err
+			}
+			return to.Load(&buffer)
+		},
+		"Snapshot": func(from, to *Pantry[string]) error {
+			var buffer bytes.Buffer
+			if err := from.ExportSnapshot(&buffer); err != nil {
+				return err
+			}
+			return to.ImportSnapshot(&buffer)
+		},
+		"JSON": func(from, to *Pantry[string]) error {
+			var buffer bytes.Buffer
+			if err := from.ExportJSON(&buffer); err != nil {
+				return err
+			}
+			return to.ImportJSON(&buffer)
+		},
+	}
+
+	for name, roundTrip := range formats {
+		for _, verify := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/verify=%v", name, verify), func(t *testing.T) {
+				options := []Option[string]{
+					WithKeyNormalizer[string](strings.ToLower),
+					WithKeyHasher[string](hash, verify),
+				}
+				p := New(context.Background(), time.Hour, options...)
+				p.Set("Apple", "red")
+
+				restored := New(context.Background(), time.Hour, options...)
+				if err := roundTrip(p, restored); err != nil {
+					t.Fatal(err)
+				}
+
+				if value, err := restored.GetE("APPLE"); err != nil || value != "red" {
+					t.Fatal("not restored", value, err)
+				}
+				for key, item := range restored.items() {
+					if key != "#APPLE" {
+						t.Fatal("unexpected stored key", key)
+					}
+					if verify && item.original != "apple" {
+						t.Fatal("original key not restored", item.original)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Set("third", 3)
+
+	removed := p.Purge(func(key string, value int) bool {
+		return value >= 2
+	})
 
-	if _, found := p.Get("test"); !found {
-		t.Fatal("not found")
+	if len(removed) != 2 {
+		t.Fatal("not 2 removed")
 	}
 
-	time.Sleep(5 * time.Second)
+	for _, entry := range removed {
+		if entry.Value < 2 {
+			t.Fatal("wrong entry removed")
+		}
 
-	if _, found := p.Get("test"); found {
-		t.Fatal("found")
+		if _, found := p.Get(entry.Key); found {
+			t.Fatal("found")
+		}
 	}
 
-	time.Sleep(time.Second)
-
-	if _, found := p.Get("test"); found {
-		t.Fatal("found")
+	if _, found := p.Get("first"); !found {
+		t.Fatal("not found")
 	}
 }
 
-func TestIsEmtpy(t *testing.T) {
+func TestRemoveWhere(t *testing.T) {
 	p := New[string](context.Background(), time.Hour)
-
-	if !p.IsEmpty() {
-		t.Log(p.store)
-		t.Fatal("not empty")
+	defer p.Close()
+
+	var removed []string
+	p.OnEvict(func(key string, _ string, reason EvictionReason) {
+		if reason == Removed {
+			removed = append(removed, key)
+		}
+		p.Contains(key)
+	})
+
+	p.Set("tenant:1:user", "a")
+	p.Set("tenant:1:order", "b")
+	p.Set("tenant:2:user", "c")
+	p.SetWithTTL("tenant:1:expired", "d", time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+
+	n := p.RemoveWhere(func(key string, _ string) bool {
+		return strings.HasPrefix(key, "tenant:1:")
+	})
+	if n != 2 || len(removed) != 2 {
+		t.Fatal("unexpected removals", n, removed)
+	}
+	if !p.Contains("tenant:2:user") || p.Contains("tenant:1:user") {
+		t.Fatal("wrong entries removed")
 	}
 }
 
-func TestSet(t *testing.T) {
-	key := "test"
-	value := "hello"
-
+func TestPop(t *testing.T) {
 	p := New[string](context.Background(), time.Hour)
 
-	if _, found := p.Get(key); found {
-		t.Log(p.store)
-		t.Fatal("found")
+	p.Set(t.Name(), "token")
+
+	if value, found := p.Pop(t.Name()); !found || value != "token" {
+		t.Fatal("not popped")
 	}
 
-	p.Set(key, value)
+	if _, found := p.Pop(t.Name()); found {
+		t.Fatal("popped twice")
+	}
 
-	if _, found := p.Get(key); !found {
-		t.Log(p.store)
-		t.Fatal("not found")
+	if _, found := p.Get(t.Name()); found {
+		t.Fatal("found")
 	}
 }
 
-func TestRemove(t *testing.T) {
-	key := "test"
-	value := "hello"
-
+func TestPopConcurrent(t *testing.T) {
 	p := New[string](context.Background(), time.Hour)
 
-	p.Set(key, value)
+	p.Set(t.Name(), "token")
 
-	if _, found := p.Get(key); !found {
-		t.Log(p.store)
-		t.Fatal("not found")
+	var popped atomic.Int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, found := p.Pop(t.Name()); found {
+				popped.Add(1)
+			}
+		}()
 	}
+	wg.Wait()
 
-	p.Remove(key)
+	if popped.Load() != 1 {
+		t.Fatal("not popped exactly once")
+	}
+}
 
-	if _, found := p.Get(key); found {
-		t.Log(p.store)
-		t.Fatal("found")
+func TestPopExpired(t *testing.T) {
+	p := New[string](context.Background(), time.Millisecond)
+
+	p.Set(t.Name(), "token")
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, found := p.Pop(t.Name()); found {
+		t.Fatal("expired popped")
 	}
 }
 
@@ -102,6 +937,87 @@ func TestGetIgnoreExpired(t *testing.T) {
 	}
 }
 
+func TestGetNoLazyExpiry(t *testing.T) {
+	p := New(context.Background(), 10*time.Millisecond, WithNoLazyExpiry[int]())
+
+	p.Set(t.Name(), 1)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, found := p.Get(t.Name()); !found {
+		t.Fatal("not found")
+	}
+}
+
+func TestValidityCheck(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithValidityCheck(func(key string, value int) bool {
+		return value%2 == 0
+	}))
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+
+	if _, found := p.Get("first"); found {
+		t.Fatal("invalid found")
+	}
+
+	if _, found := p.Get("second"); !found {
+		t.Fatal("valid not found")
+	}
+
+	counter := 0
+	for range p.All() {
+		counter++
+	}
+
+	if counter != 1 {
+		t.Fatal("not 1 item")
+	}
+
+	p.removeExpired()
+
+	if _, found := p.lookup("first"); found {
+		t.Fatal("not reaped")
+	}
+}
+
+func TestSlidingExpiration(t *testing.T) {
+	p := New(context.Background(), 30*time.Millisecond, WithSlidingExpiration[int]())
+
+	p.Set(t.Name(), 1)
+
+	for i := 0; i < 4; i++ {
+		time.Sleep(15 * time.Millisecond)
+
+		if _, found := p.Get(t.Name()); !found {
+			t.Fatal("expired while read")
+		}
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, found := p.Get(t.Name()); found {
+		t.Fatal("not expired")
+	}
+}
+
+func TestSlidingExpirationIterators(t *testing.T) {
+	p := New(context.Background(), 30*time.Millisecond, WithSlidingExpiration[int]())
+
+	p.Set(t.Name(), 1)
+
+	for i := 0; i < 3; i++ {
+		time.Sleep(15 * time.Millisecond)
+
+		for range p.All() {
+		}
+	}
+
+	if _, found := p.Get(t.Name()); found {
+		t.Fatal("extended by iteration")
+	}
+}
+
 func TestValues(t *testing.T) {
 	p := New[int](context.Background(), time.Hour)
 
@@ -294,27 +1210,373 @@ func TestAllIgnoreExpired(t *testing.T) {
 	}
 }
 
-func BenchmarkGet(b *testing.B) {
+func TestRecentlyUsed(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithAccessTracking[int]())
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Set("third", 3)
+
+	p.Get("second")
+	p.Get("first")
+
+	entries := p.RecentlyUsed(2)
+	if len(entries) != 2 {
+		t.Fatal("not 2 entries")
+	}
+
+	if entries[0].Key != "first" || entries[1].Key != "second" {
+		t.Log(entries)
+		t.Fatal("wrong order")
+	}
+
+	if len(p.RecentlyUsed(10)) != 3 {
+		t.Fatal("not 3 entries")
+	}
+}
+
+func TestRecentlyUsedIgnoreExpired(t *testing.T) {
+	p := New(context.Background(), 10*time.Millisecond, WithAccessTracking[int]())
+
+	p.Set("first", 1)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if len(p.RecentlyUsed(1)) != 0 {
+		t.Fatal("not ignored")
+	}
+}
+
+func TestRecentlyUsedWithoutTracking(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+
+	if p.RecentlyUsed(1) != nil {
+		t.Fatal("not nil")
+	}
+}
+
+func TestMaxConcurrentIterations(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithMaxConcurrentIterations[int](1))
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+
+	// The loop body does not hold the limit, so nesting works.
+	nested := 0
+	for range p.Keys() {
+		for range p.Values() {
+			nested++
+		}
+	}
+	if nested != 4 {
+		t.Fatal("unexpected nested iterations", nested)
+	}
+
+	// Take the only slot as if a snapshot was being taken.
+	p.iterations <- struct{}{}
+
+	var started atomic.Bool
+	done := make(chan struct{})
+
+	go func() {
+		for range p.Values() {
+			started.Store(true)
+		}
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	if started.Load() {
+		t.Fatal("not limited")
+	}
+
+	<-p.iterations
+	<-done
+
+	if !started.Load() {
+		t.Fatal("not started")
+	}
+}
+
+func BenchmarkGetLazyExpiry(b *testing.B) {
 	p := New[int](context.Background(), time.Hour)
+	p.Set("key", 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Get("key")
+	}
+}
 
+func BenchmarkGetNoLazyExpiry(b *testing.B) {
+	p := New(context.Background(), time.Hour, WithNoLazyExpiry[int]())
+	p.Set("key", 1)
+
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		key := strconv.Itoa(i)
+		p.Get("key")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Set("third", 3)
+
+	counter := 0
+
+	for key, value := range p.Filter(func(key string, value int) bool { return value%2 == 1 }) {
+		if value%2 != 1 {
+			t.Fatal("not filtered", key)
+		}
+		counter++
+	}
+
+	if counter != 2 {
+		t.Fatal("not 2 items")
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("c", 3)
+	p.Set("a", 1)
+	p.Set("b", 2)
+
+	keys := slices.Collect(p.SortedKeys())
+	if !slices.Equal(keys, []string{"a", "b", "c"}) {
+		t.Fatal("not sorted", keys)
+	}
+
+	for range p.SortedKeys() {
+		break
+	}
+}
+
+func TestGetRef(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("zero", 0)
+
+	if ref, found := p.GetRef("zero"); !found || ref == nil || *ref != 0 {
+		t.Fatal("stored zero value not found", ref, found)
+	}
+	if ref, found := p.GetRef("missing"); found || ref != nil {
+		t.Fatal("missing key found", ref, found)
+	}
+
+	ref, _ := p.GetRef("zero")
+	*ref = 1
+	if value, _ := p.Get("zero"); value != 0 {
+		t.Fatal("stored value changed through the pointer", value)
+	}
+}
+
+func TestScan(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	for i := range 10 {
+		p.Set("user:"+strconv.Itoa(i), i)
+	}
+	p.Set("session:1", 1)
+
+	var pages [][]string
+	cursor := ""
+	for {
+		keys, next := p.Scan(cursor, 4, "user:")
+		pages = append(pages, keys)
+		if next == "" {
+			break
+		}
+		cursor = next
+
+		if len(pages) == 1 {
+			p.Remove("user:5")
+		}
+	}
+
+	if len(pages) != 3 || !slices.Equal(pages[0], []string{"user:0", "user:1", "user:2", "user:3"}) ||
+		!slices.Equal(pages[2], []string{"user:9"}) {
+		t.Fatal("unexpected pages", pages)
+	}
+	if slices.Contains(pages[1], "user:5") {
+		t.Fatal("removed key returned", pages[1])
+	}
+
+	if keys, next := p.Scan("", 0, ""); keys != nil || next != "" {
+		t.Fatal("unexpected result for limit 0", keys, next)
+	}
+}
+
+func TestStableIteration(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithStableIteration[int]())
+
+	for i, key := range []string{"d", "b", "e", "a", "c"} {
 		p.Set(key, i)
+	}
 
-		b.Run(key, func(b *testing.B) {
-			p.Get(key)
-		})
+	keys := slices.Collect(p.Keys())
+	if !slices.Equal(keys, []string{"a", "b", "c", "d", "e"}) {
+		t.Fatal("keys not sorted", keys)
+	}
+
+	values := slices.Collect(p.Values())
+	if !slices.Equal(values, []int{3, 1, 4, 0, 2}) {
+		t.Fatal("values not in key order", values)
+	}
+
+	var entries []string
+	for key := range p.Entries() {
+		entries = append(entries, key)
+	}
+	if !slices.Equal(entries, keys) {
+		t.Fatal("entries not sorted", entries)
 	}
 }
 
-func BenchmarkSet(b *testing.B) {
+func TestModifyDuringIteration(t *testing.T) {
 	p := New[int](context.Background(), time.Hour)
 
-	for i := 0; i < b.N; i++ {
-		key := strconv.Itoa(i)
+	p.Set("first", 1)
+	p.Set("second", 2)
 
-		b.Run(key, func(b *testing.B) {
-			p.Set(key, i)
-		})
+	counter := 0
+
+	for key, value := range p.All() {
+		p.Remove(key)
+		p.Set(key+"-copy", value)
+		counter++
+	}
+
+	if counter != 2 {
+		t.Fatal("snapshot changed during iteration")
+	}
+
+	if _, found := p.Get("first-copy"); !found {
+		t.Fatal("not modified")
+	}
+}
+
+func TestTTLJitter(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithTTLJitter[int](0.1))
+
+	entries := make(map[string]int, 100)
+	for i := range 100 {
+		entries[strconv.Itoa(i)] = i
+	}
+	p.SetMany(entries)
+
+	distinct := make(map[time.Duration]struct{})
+	for key := range entries {
+		ttl, _ := p.TTL(key)
+		if ttl < 54*time.Minute || ttl > 66*time.Minute {
+			t.Fatal("outside jitter range", ttl)
+		}
+		distinct[ttl.Round(time.Second)] = struct{}{}
+	}
+
+	if len(distinct) < 10 {
+		t.Fatal("not spread", len(distinct))
+	}
+}
+
+func TestCountSkipsExpired(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithoutBackgroundCleanup[int]())
+
+	p.Set("live", 1)
+	p.SetWithTTL("expired", 2, time.Millisecond)
+
+	time.Sleep(2 * time.Millisecond)
+
+	if count := p.Count(); count != 1 {
+		t.Fatal("unexpected count", count)
+	}
+
+	if count := p.CountRaw(); count != 2 {
+		t.Fatal("unexpected raw count", count)
+	}
+
+	p.Remove("live")
+
+	if !p.IsEmpty() {
+		t.Fatal("not empty")
+	}
+}
+
+func TestGetE(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithoutBackgroundCleanup[int]())
+
+	p.Set("live", 1)
+	p.SetWithTTL("expired", 2, time.Millisecond)
+
+	time.Sleep(2 * time.Millisecond)
+
+	if value, err := p.GetE("live"); err != nil || value != 1 {
+		t.Fatal("live entry", value, err)
+	}
+
+	if _, err := p.GetE("expired"); !errors.Is(err, ErrExpired) {
+		t.Fatal("expected ErrExpired", err)
+	}
+
+	if _, err := p.GetE("expired"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected ErrNotFound after removal", err)
+	}
+
+	if _, err := p.GetE("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected ErrNotFound", err)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithAccessTracking[int]())
+
+	before := time.Now()
+	p.Set("read", 1)
+	p.Set("unread", 2)
+	p.Get("read")
+
+	entries := make(map[string]Entry[int])
+	for key, entry := range p.Entries() {
+		entries[key] = entry
+	}
+
+	read := entries["read"]
+	if read.Value != 1 || read.Key != "read" {
+		t.Fatal("unexpected entry", read)
+	}
+	if read.CreatedAt.Before(before) || read.ExpiresAt.Sub(read.CreatedAt) > time.Hour {
+		t.Fatal("unexpected timestamps", read.CreatedAt, read.ExpiresAt)
+	}
+	if read.LastAccess.Before(read.CreatedAt) {
+		t.Fatal("last access not tracked", read.LastAccess)
+	}
+
+	if !entries["unread"].LastAccess.IsZero() {
+		t.Fatal("unread entry has last access")
+	}
+}
+
+func TestHotPathAllocations(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+	p.Set("key", 1)
+
+	for name, op := range map[string]func(){
+		"get":      func() { p.Get("key") },
+		"miss":     func() { p.Get("missing") },
+		"contains": func() { p.Contains("key") },
+		"set":      func() { p.Set("key", 2) },
+		"remove":   func() { p.Remove("missing") },
+	} {
+		if allocs := testing.AllocsPerRun(1000, op); allocs != 0 {
+			t.Error(name, "allocates", allocs)
+		}
 	}
 }
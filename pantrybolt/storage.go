@@ -0,0 +1,72 @@
+// Package pantrybolt keeps persistent pantries in a single bbolt database
+// file.
+package pantrybolt
+
+import (
+	"time"
+
+	"github.com/webermarci/pantry"
+	"go.etcd.io/bbolt"
+)
+
+var bucket = []byte("pantry")
+
+// Storage is a pantry.Storage writing every batch in one bbolt transaction.
+type Storage struct {
+	db *bbolt.DB
+}
+
+var _ pantry.Storage = (*Storage)(nil)
+
+// Open opens or creates the database file at path. It fails if another
+// process holds the file for more than a second.
+func Open(path string) (*Storage, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Storage{db: db}, nil
+}
+
+func (storage *Storage) Load(fn func(key string, data []byte) error) error {
+	return storage.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(key, data []byte) error {
+			return fn(string(key), data)
+		})
+	})
+}
+
+func (storage *Storage) Write(batch map[string][]byte) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return storage.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for key, data := range batch {
+			var err error
+			if data == nil {
+				err = b.Delete([]byte(key))
+			} else {
+				err = b.Put([]byte(key), data)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (storage *Storage) Close() error {
+	return storage.db.Close()
+}
@@ -0,0 +1,61 @@
+package pantrybolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func TestStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pantry.db")
+
+	storage, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, errs := pantry.NewPersistentWithStorage[string](context.Background(), time.Hour, storage)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	p.Set("first", "value")
+	p.Set("second", "value")
+	p.Set("removed", "value")
+
+	if err := p.Persist("removed"); err != nil {
+		t.Fatal(err)
+	}
+	p.Remove("removed")
+	if err := p.Persist("removed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	storage, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, errs := pantry.NewPersistentWithStorage[string](context.Background(), time.Hour, storage)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	defer restored.Close()
+
+	if restored.Count() != 2 {
+		t.Fatalf("expected 2 entries, got %d", restored.Count())
+	}
+	if value, found := restored.Get("first"); !found || value != "value" {
+		t.Error("first was not restored")
+	}
+	if _, found := restored.Get("removed"); found {
+		t.Error("removed was restored")
+	}
+}
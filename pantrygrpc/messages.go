@@ -0,0 +1,111 @@
+package pantrygrpc
+
+import (
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// The messages mirror pantry.proto. They carry protobuf struct tags instead
+// of generated descriptors, which keeps the package free of a protoc step
+// while staying wire compatible with clients generated from the .proto.
+
+type GetRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3"`
+}
+
+type GetResponse struct {
+	Found           bool   `protobuf:"varint,1,opt,name=found,proto3"`
+	Value           []byte `protobuf:"bytes,2,opt,name=value,proto3"`
+	ExpiresUnixNano int64  `protobuf:"varint,3,opt,name=expires_unix_nano,json=expiresUnixNano,proto3"`
+	Version         uint64 `protobuf:"varint,4,opt,name=version,proto3"`
+}
+
+type SetRequest struct {
+	Key          string `protobuf:"bytes,1,opt,name=key,proto3"`
+	Value        []byte `protobuf:"bytes,2,opt,name=value,proto3"`
+	TTLMillis    int64  `protobuf:"varint,3,opt,name=ttl_millis,json=ttlMillis,proto3"`
+	IfVersion    uint64 `protobuf:"varint,4,opt,name=if_version,json=ifVersion,proto3"`
+	CheckVersion bool   `protobuf:"varint,5,opt,name=check_version,json=checkVersion,proto3"`
+}
+
+type SetResponse struct{}
+
+type RemoveRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3"`
+}
+
+type RemoveResponse struct{}
+
+type KeysRequest struct {
+	Pattern string `protobuf:"bytes,1,opt,name=pattern,proto3"`
+}
+
+type KeysResponse struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3"`
+}
+
+type WatchRequest struct {
+	Pattern string `protobuf:"bytes,1,opt,name=pattern,proto3"`
+	Buffer  int32  `protobuf:"varint,2,opt,name=buffer,proto3"`
+}
+
+// EventKind values match pantry.EventKind.
+type EventKind int32
+
+const (
+	EventKindSet EventKind = iota
+	EventKindRemoved
+	EventKindExpired
+	EventKindCleared
+)
+
+type Event struct {
+	Kind         EventKind `protobuf:"varint,1,opt,name=kind,proto3"`
+	Key          string    `protobuf:"bytes,2,opt,name=key,proto3"`
+	Value        []byte    `protobuf:"bytes,3,opt,name=value,proto3"`
+	TimeUnixNano int64     `protobuf:"varint,4,opt,name=time_unix_nano,json=timeUnixNano,proto3"`
+}
+
+func (message *GetRequest) Reset()         { *message = GetRequest{} }
+func (message *GetRequest) ProtoMessage()  {}
+func (message *GetRequest) String() string { return text(message) }
+
+func (message *GetResponse) Reset()         { *message = GetResponse{} }
+func (message *GetResponse) ProtoMessage()  {}
+func (message *GetResponse) String() string { return text(message) }
+
+func (message *SetRequest) Reset()         { *message = SetRequest{} }
+func (message *SetRequest) ProtoMessage()  {}
+func (message *SetRequest) String() string { return text(message) }
+
+func (message *SetResponse) Reset()         { *message = SetResponse{} }
+func (message *SetResponse) ProtoMessage()  {}
+func (message *SetResponse) String() string { return text(message) }
+
+func (message *RemoveRequest) Reset()         { *message = RemoveRequest{} }
+func (message *RemoveRequest) ProtoMessage()  {}
+func (message *RemoveRequest) String() string { return text(message) }
+
+func (message *RemoveResponse) Reset()         { *message = RemoveResponse{} }
+func (message *RemoveResponse) ProtoMessage()  {}
+func (message *RemoveResponse) String() string { return text(message) }
+
+func (message *KeysRequest) Reset()         { *message = KeysRequest{} }
+func (message *KeysRequest) ProtoMessage()  {}
+func (message *KeysRequest) String() string { return text(message) }
+
+func (message *KeysResponse) Reset()         { *message = KeysResponse{} }
+func (message *KeysResponse) ProtoMessage()  {}
+func (message *KeysResponse) String() string { return text(message) }
+
+func (message *WatchRequest) Reset()         { *message = WatchRequest{} }
+func (message *WatchRequest) ProtoMessage()  {}
+func (message *WatchRequest) String() string { return text(message) }
+
+func (message *Event) Reset()         { *message = Event{} }
+func (message *Event) ProtoMessage()  {}
+func (message *Event) String() string { return text(message) }
+
+func text(message protoadapt.MessageV1) string {
+	return prototext.Format(protoadapt.MessageV2Of(message))
+}
@@ -0,0 +1,172 @@
+// Package pantrygrpc serves a pantry over gRPC using the service defined in
+// pantry.proto, so processes on the same host can share one cache.
+package pantrygrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/webermarci/pantry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultWatchBuffer = 64
+	// maxWatchBuffer caps the buffer a client may request, as it is
+	// allocated up front for every watch.
+	maxWatchBuffer = 4096
+)
+
+type Server struct {
+	pantry *pantry.Pantry[[]byte]
+}
+
+func NewServer(p *pantry.Pantry[[]byte]) *Server {
+	return &Server{pantry: p}
+}
+
+// Register adds the service to a gRPC server.
+func (server *Server) Register(registrar grpc.ServiceRegistrar) {
+	registrar.RegisterService(&serviceDesc, server)
+}
+
+func (server *Server) Get(_ context.Context, request *GetRequest) (*GetResponse, error) {
+	value, version, found := server.pantry.GetWithVersion(request.Key)
+	if !found {
+		return &GetResponse{}, nil
+	}
+
+	response := &GetResponse{
+		Found:   true,
+		Value:   value,
+		Version: version,
+	}
+	if ttl, ok := server.pantry.TTL(request.Key); ok && ttl != pantry.NoExpiration {
+		response.ExpiresUnixNano = time.Now().Add(ttl).UnixNano()
+	}
+	return response, nil
+}
+
+func (server *Server) Set(_ context.Context, request *SetRequest) (*SetResponse, error) {
+	switch {
+	case request.TTLMillis < 0:
+		return nil, status.Error(codes.InvalidArgument, "negative ttl")
+	case request.CheckVersion:
+		stored := false
+		if request.TTLMillis > 0 {
+			stored = server.pantry.SetIfVersionWithTTL(request.Key, request.Value, time.Duration(request.TTLMillis)*time.Millisecond, request.IfVersion)
+		} else {
+			stored = server.pantry.SetIfVersion(request.Key, request.Value, request.IfVersion)
+		}
+		if !stored {
+			return nil, status.Error(codes.FailedPrecondition, "version mismatch")
+		}
+	case request.TTLMillis > 0:
+		server.pantry.SetWithTTL(request.Key, request.Value, time.Duration(request.TTLMillis)*time.Millisecond)
+	default:
+		server.pantry.Set(request.Key, request.Value)
+	}
+	return &SetResponse{}, nil
+}
+
+func (server *Server) Remove(_ context.Context, request *RemoveRequest) (*RemoveResponse, error) {
+	server.pantry.Remove(request.Key)
+	return &RemoveResponse{}, nil
+}
+
+func (server *Server) Keys(request *KeysRequest, stream grpc.ServerStream) error {
+	for key := range server.pantry.Keys() {
+		if request.Pattern != "" && !pantry.Match(request.Pattern, key) {
+			continue
+		}
+		if err := stream.SendMsg(&KeysResponse{Key: key}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchBuffer returns the buffer to subscribe with for the requested size.
+func watchBuffer(requested int32) int {
+	if requested <= 0 {
+		return defaultWatchBuffer
+	}
+	return min(int(requested), maxWatchBuffer)
+}
+
+// Watch streams events until the client cancels or the pantry is closed.
+// EventCleared is sent regardless of the pattern.
+func (server *Server) Watch(request *WatchRequest, stream grpc.ServerStream) error {
+	for event := range server.pantry.Subscribe(stream.Context(), watchBuffer(request.Buffer)) {
+		if event.Kind != pantry.EventCleared && request.Pattern != "" && !pantry.Match(request.Pattern, event.Key) {
+			continue
+		}
+
+		err := stream.SendMsg(&Event{
+			Kind:         EventKind(event.Kind),
+			Key:          event.Key,
+			Value:        event.Value,
+			TimeUnixNano: event.Time.UnixNano(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return stream.Context().Err()
+}
+
+type service interface {
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	Set(context.Context, *SetRequest) (*SetResponse, error)
+	Remove(context.Context, *RemoveRequest) (*RemoveResponse, error)
+	Keys(*KeysRequest, grpc.ServerStream) error
+	Watch(*WatchRequest, grpc.ServerStream) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pantry.v1.Pantry",
+	HandlerType: (*service)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Get", Handler: unary("Get", service.Get)},
+		{MethodName: "Set", Handler: unary("Set", service.Set)},
+		{MethodName: "Remove", Handler: unary("Remove", service.Remove)},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Keys", Handler: serverStream(service.Keys), ServerStreams: true},
+		{StreamName: "Watch", Handler: serverStream(service.Watch), ServerStreams: true},
+	},
+	Metadata: "pantry.proto",
+}
+
+func unary[Request, Response any](method string, call func(service, context.Context, *Request) (*Response, error)) func(any, context.Context, func(any) error, grpc.UnaryServerInterceptor) (any, error) {
+	return func(srv any, ctx context.Context, decode func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		request := new(Request)
+		if err := decode(request); err != nil {
+			return nil, err
+		}
+
+		if interceptor == nil {
+			return call(srv.(service), ctx, request)
+		}
+
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/pantry.v1.Pantry/" + method,
+		}
+		return interceptor(ctx, request, info, func(ctx context.Context, request any) (any, error) {
+			return call(srv.(service), ctx, request.(*Request))
+		})
+	}
+}
+
+func serverStream[Request any](call func(service, *Request, grpc.ServerStream) error) grpc.StreamHandler {
+	return func(srv any, stream grpc.ServerStream) error {
+		request := new(Request)
+		if err := stream.RecvMsg(request); err != nil {
+			return err
+		}
+		return call(srv.(service), request, stream)
+	}
+}
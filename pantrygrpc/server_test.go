@@ -0,0 +1,189 @@
+package pantrygrpc
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestConn(t *testing.T) (*pantry.Pantry[[]byte], *grpc.ClientConn) {
+	t.Helper()
+
+	p := pantry.New[[]byte](context.Background(), time.Hour)
+	listener := bufconn.Listen(1 << 20)
+
+	server := grpc.NewServer()
+	NewServer(p).Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return p, conn
+}
+
+func TestGetSetRemove(t *testing.T) {
+	p, conn := newTestConn(t)
+	ctx := context.Background()
+
+	err := conn.Invoke(ctx, "/pantry.v1.Pantry/Set", &SetRequest{Key: "key", Value: []byte("value"), TTLMillis: 60000}, &SetResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl, found := p.TTL("key"); !found || ttl > time.Minute {
+		t.Fatal("ttl not applied", ttl, found)
+	}
+
+	var response GetResponse
+	if err := conn.Invoke(ctx, "/pantry.v1.Pantry/Get", &GetRequest{Key: "key"}, &response); err != nil {
+		t.Fatal(err)
+	}
+	if !response.Found || string(response.Value) != "value" || response.ExpiresUnixNano == 0 {
+		t.Fatal("unexpected response", response.String())
+	}
+
+	if err := conn.Invoke(ctx, "/pantry.v1.Pantry/Remove", &RemoveRequest{Key: "key"}, &RemoveResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	response = GetResponse{}
+	if err := conn.Invoke(ctx, "/pantry.v1.Pantry/Get", &GetRequest{Key: "key"}, &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Found {
+		t.Fatal("found after remove")
+	}
+}
+
+func TestSetIfVersion(t *testing.T) {
+	_, conn := newTestConn(t)
+	ctx := context.Background()
+
+	create := &SetRequest{Key: "key", Value: []byte("first"), CheckVersion: true}
+	if err := conn.Invoke(ctx, "/pantry.v1.Pantry/Set", create, &SetResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	err := conn.Invoke(ctx, "/pantry.v1.Pantry/Set", create, &SetResponse{})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatal("created twice", err)
+	}
+
+	var response GetResponse
+	if err := conn.Invoke(ctx, "/pantry.v1.Pantry/Get", &GetRequest{Key: "key"}, &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Version == 0 {
+		t.Fatal("missing version")
+	}
+
+	update := &SetRequest{Key: "key", Value: []byte("second"), IfVersion: response.Version, CheckVersion: true}
+	if err := conn.Invoke(ctx, "/pantry.v1.Pantry/Set", update, &SetResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	err = conn.Invoke(ctx, "/pantry.v1.Pantry/Set", update, &SetResponse{})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatal("stored with a stale version", err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	p, conn := newTestConn(t)
+
+	p.Set("user:1", nil)
+	p.Set("user:2", nil)
+	p.Set("session:1", nil)
+
+	stream, err := conn.NewStream(context.Background(), &serviceDesc.Streams[0], "/pantry.v1.Pantry/Keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(&KeysRequest{Pattern: "user:*"}); err != nil {
+		t.Fatal(err)
+	}
+	stream.CloseSend()
+
+	var keys []string
+	for {
+		var response KeysResponse
+		if err := stream.RecvMsg(&response); err != nil {
+			break
+		}
+		keys = append(keys, response.Key)
+	}
+
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"user:1", "user:2"}) {
+		t.Fatal("unexpected keys", keys)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	p, conn := newTestConn(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[1], "/pantry.v1.Pantry/Watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(&WatchRequest{Pattern: "config:*"}); err != nil {
+		t.Fatal(err)
+	}
+	stream.CloseSend()
+
+	// The subscription starts once the server has read the request.
+	time.Sleep(20 * time.Millisecond)
+
+	p.Set("other", []byte("ignored"))
+	p.Set("config:a", []byte("1"))
+	p.Remove("config:a")
+
+	var event Event
+	if err := stream.RecvMsg(&event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Kind != EventKindSet || event.Key != "config:a" || string(event.Value) != "1" {
+		t.Fatal("unexpected event", event.String())
+	}
+
+	event = Event{}
+	if err := stream.RecvMsg(&event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Kind != EventKindRemoved || event.Key != "config:a" {
+		t.Fatal("unexpected event", event.String())
+	}
+}
+
+func TestWatchBuffer(t *testing.T) {
+	for requested, expected := range map[int32]int{
+		-1:        defaultWatchBuffer,
+		0:         defaultWatchBuffer,
+		16:        16,
+		1<<31 - 1: maxWatchBuffer,
+	} {
+		if buffer := watchBuffer(requested); buffer != expected {
+			t.Fatal("unexpected buffer", requested, buffer)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package pantryhttp exposes a pantry as a small REST cache.
+//
+//	GET    /keys/{key}  returns the JSON encoded value
+//	PUT    /keys/{key}  stores the JSON encoded request body
+//	DELETE /keys/{key}  removes the entry
+//	GET    /keys        lists the live keys, see below for paging
+//	GET    /stats       returns the pantry statistics
+//	GET    /dump        returns the live entries written by ExportJSON
+//
+// A PUT honours a Pantry-TTL header holding a Go duration such as "30s", and
+// a GET reports the remaining lifetime of the entry in the same header.
+// Concurrent GETs of a missing key share one backend load or loader run,
+// counted in the Coalesced statistic.
+//
+// A GET returns the version of the entry as its ETag. A PUT with an If-Match
+// header only stores the value if the entry still has that version, and one
+// with "If-None-Match: *" only if there is no entry, answering 412
+// Precondition Failed otherwise, so concurrent writers do not overwrite each
+// other.
+//
+// GET /keys pages through the keys with Pantry.Scan when given a limit, and
+// lists only those starting with the prefix parameter. The cursor for the
+// next page is returned in the Pantry-Cursor header and passed back in the
+// cursor parameter; the header is missing on the last page.
+package pantryhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+const (
+	TTLHeader    = "Pantry-TTL"
+	CursorHeader = "Pantry-Cursor"
+)
+
+type handler[T any] struct {
+	pantry *pantry.Pantry[T]
+}
+
+func NewHandler[T any](p *pantry.Pantry[T]) http.Handler {
+	handler := &handler[T]{pantry: p}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keys/{key...}", handler.get)
+	mux.HandleFunc("PUT /keys/{key...}", handler.put)
+	mux.HandleFunc("DELETE /keys/{key...}", handler.remove)
+	mux.HandleFunc("GET /keys", handler.keys)
+	mux.HandleFunc("GET /stats", handler.stats)
+	mux.HandleFunc("GET /dump", handler.dump)
+	return mux
+}
+
+func (handler *handler[T]) get(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	value, version, found := handler.pantry.GetWithVersion(key)
+	if !found {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	if ttl, ok := handler.pantry.TTL(key); ok && ttl != pantry.NoExpiration {
+		w.Header().Set(TTLHeader, ttl.Round(time.Millisecond).String())
+	}
+	w.Header().Set("ETag", etag(version))
+	writeJSON(w, value)
+}
+
+func (handler *handler[T]) put(w http.ResponseWriter, r *http.Request) {
+	var value T
+	if err := json.NewDecoder(r.Body).Decode(&value); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := r.PathValue("key")
+
+	ttl := time.Duration(0)
+	if header := r.Header.Get(TTLHeader); header != "" {
+		var err error
+		if ttl, err = time.ParseDuration(header); err != nil || ttl <= 0 {
+			http.Error(w, "invalid "+TTLHeader+" header", http.StatusBadRequest)
+			return
+		}
+	}
+
+	version, conditional, err := precondition(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case conditional && ttl > 0:
+		if !handler.pantry.SetIfVersionWithTTL(key, value, ttl, version) {
+			http.Error(w, "version mismatch", http.StatusPreconditionFailed)
+			return
+		}
+	case conditional:
+		if !handler.pantry.SetIfVersion(key, value, version) {
+			http.Error(w, "version mismatch", http.StatusPreconditionFailed)
+			return
+		}
+	case ttl > 0:
+		handler.pantry.SetWithTTL(key, value, ttl)
+	default:
+		handler.pantry.Set(key, value)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *handler[T]) remove(w http.ResponseWriter, r *http.Request) {
+	handler.pantry.Remove(r.PathValue("key"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *handler[T]) keys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := math.MaxInt
+	if query.Has("limit") {
+		var err error
+		if limit, err = strconv.Atoi(query.Get("limit")); err != nil || limit <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	keys, next := handler.pantry.Scan(query.Get("cursor"), limit, query.Get("prefix"))
+	if next != "" {
+		w.Header().Set(CursorHeader, next)
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	writeJSON(w, keys)
+}
+
+func (handler *handler[T]) stats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, handler.pantry.Stats())
+}
+
+func (handler *handler[T]) dump(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	handler.pantry.ExportJSON(w)
+}
+
+func etag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// precondition returns the version required by the If-Match or If-None-Match
+// header of the request, zero meaning that the entry must not exist.
+func precondition(r *http.Request) (uint64, bool, error) {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		if header != "*" {
+			return 0, false, errors.New("If-None-Match only supports *")
+		}
+		return 0, true, nil
+	}
+
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, false, nil
+	}
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, false, errors.New("invalid If-Match header")
+	}
+	version, err := strconv.ParseUint(unquoted, 10, 64)
+	if err != nil || version == 0 {
+		return 0, false, errors.New("invalid If-Match header")
+	}
+	return version, true, nil
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,176 @@
+package pantryhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func request(t *testing.T, handler http.Handler, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	for key := range header {
+		r.Header.Set(key, header.Get(key))
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	return recorder
+}
+
+func TestHandler(t *testing.T) {
+	p := pantry.New[string](context.Background(), time.Hour)
+	defer p.Close()
+
+	handler := NewHandler(p)
+
+	if code := request(t, handler, "PUT", "/keys/first", `"hello"`, nil).Code; code != http.StatusNoContent {
+		t.Fatal("unexpected status", code)
+	}
+
+	response := request(t, handler, "GET", "/keys/first", "", nil)
+	body, _ := io.ReadAll(response.Body)
+	if response.Code != http.StatusOK || strings.TrimSpace(string(body)) != `"hello"` {
+		t.Fatal("unexpected response", response.Code, string(body))
+	}
+
+	response = request(t, handler, "GET", "/keys", "", nil)
+	if strings.TrimSpace(response.Body.String()) != `["first"]` {
+		t.Fatal("unexpected keys", response.Body.String())
+	}
+
+	request(t, handler, "PUT", "/keys/second", `"world"`, nil)
+	response = request(t, handler, "GET", "/keys?limit=1", "", nil)
+	if strings.TrimSpace(response.Body.String()) != `["first"]` || response.Header().Get(CursorHeader) != "first" {
+		t.Fatal("unexpected page", response.Body.String(), response.Header())
+	}
+	response = request(t, handler, "GET", "/keys?limit=1&cursor=first", "", nil)
+	if strings.TrimSpace(response.Body.String()) != `["second"]` || response.Header().Get(CursorHeader) != "" {
+		t.Fatal("unexpected last page", response.Body.String(), response.Header())
+	}
+	response = request(t, handler, "GET", "/keys?prefix=sec", "", nil)
+	if strings.TrimSpace(response.Body.String()) != `["second"]` {
+		t.Fatal("prefix ignored", response.Body.String())
+	}
+	request(t, handler, "DELETE", "/keys/second", "", nil)
+
+	request(t, handler, "DELETE", "/keys/first", "", nil)
+
+	if code := request(t, handler, "GET", "/keys/first", "", nil).Code; code != http.StatusNotFound {
+		t.Fatal("unexpected status", code)
+	}
+
+	request(t, handler, "PUT", "/keys/second", `"world"`, nil)
+	response = request(t, handler, "GET", "/dump", "", nil)
+	if !strings.Contains(response.Body.String(), `"value": "world"`) {
+		t.Fatal("unexpected dump", response.Body.String())
+	}
+
+	response = request(t, handler, "GET", "/stats", "", nil)
+	if !strings.Contains(response.Body.String(), `"hits":1`) {
+		t.Fatal("unexpected stats", response.Body.String())
+	}
+}
+
+func TestHandlerTTL(t *testing.T) {
+	p := pantry.New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	handler := NewHandler(p)
+
+	header := http.Header{}
+	header.Set(TTLHeader, "1m")
+	request(t, handler, "PUT", "/keys/counter", "1", header)
+
+	ttl, err := time.ParseDuration(request(t, handler, "GET", "/keys/counter", "", nil).Header().Get(TTLHeader))
+	if err != nil || ttl > time.Minute || ttl < 59*time.Second {
+		t.Fatal("unexpected ttl", ttl, err)
+	}
+
+	header.Set(TTLHeader, "soon")
+	if code := request(t, handler, "PUT", "/keys/counter", "1", header).Code; code != http.StatusBadRequest {
+		t.Fatal("unexpected status", code)
+	}
+
+	if code := request(t, handler, "PUT", "/keys/counter", "nope", nil).Code; code != http.StatusBadRequest {
+		t.Fatal("unexpected status", code)
+	}
+}
+
+func TestHandlerCoalescesLoads(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	p := pantry.New(context.Background(), time.Hour, pantry.WithLoader(func(ctx context.Context, key string) (string, error) {
+		loads.Add(1)
+		<-release
+		return "loaded", nil
+	}))
+	defer p.Close()
+
+	handler := NewHandler(p)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if response := request(t, handler, "GET", "/keys/slow", "", nil); response.Code != http.StatusOK {
+				t.Error("unexpected status", response.Code)
+			}
+		}()
+	}
+
+	for p.Stats().Coalesced < 9 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Fatal("expected one load, got", n)
+	}
+}
+
+func TestHandlerVersions(t *testing.T) {
+	p := pantry.New[string](context.Background(), time.Hour)
+	defer p.Close()
+
+	handler := NewHandler(p)
+
+	create := http.Header{"If-None-Match": {"*"}}
+	if code := request(t, handler, "PUT", "/keys/key", `"first"`, create).Code; code != http.StatusNoContent {
+		t.Fatal("unexpected status", code)
+	}
+	if code := request(t, handler, "PUT", "/keys/key", `"again"`, create).Code; code != http.StatusPreconditionFailed {
+		t.Fatal("created twice", code)
+	}
+
+	tag := request(t, handler, "GET", "/keys/key", "", nil).Header().Get("ETag")
+	if tag == "" {
+		t.Fatal("missing etag")
+	}
+
+	match := http.Header{"If-Match": {tag}}
+	if code := request(t, handler, "PUT", "/keys/key", `"second"`, match).Code; code != http.StatusNoContent {
+		t.Fatal("unexpected status", code)
+	}
+	if code := request(t, handler, "PUT", "/keys/key", `"stale"`, match).Code; code != http.StatusPreconditionFailed {
+		t.Fatal("stored with a stale etag", code)
+	}
+	if code := request(t, handler, "PUT", "/keys/key", `"bad"`, http.Header{"If-Match": {"nope"}}).Code; code != http.StatusBadRequest {
+		t.Fatal("unexpected status", code)
+	}
+
+	if value, _ := p.Get("key"); value != "second" {
+		t.Fatal("unexpected value", value)
+	}
+}
@@ -0,0 +1,251 @@
+// Package pantrymemcached serves a pantry over the memcached text protocol so
+// that existing memcached clients can use it. It supports get, gets, set,
+// delete, touch, flush_all, version and quit. Flags are accepted but not
+// stored, so they are always reported as zero.
+package pantrymemcached
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+const (
+	maxValueSize = 1 << 20
+
+	// Expiration times above this many seconds are absolute unix timestamps.
+	maxRelativeExpiration = 60 * 60 * 24 * 30
+)
+
+var errBadFormat = errors.New("bad command line format")
+
+type Server struct {
+	pantry *pantry.Pantry[[]byte]
+}
+
+func NewServer(p *pantry.Pantry[[]byte]) *Server {
+	return &Server{pantry: p}
+}
+
+// Serve accepts connections on listener until it is closed.
+func (server *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go server.serveConn(conn)
+	}
+}
+
+func (server *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	return server.Serve(listener)
+}
+
+func (server *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			writer.WriteString("ERROR\r\n")
+			writer.Flush()
+			continue
+		}
+
+		if fields[0] == "quit" {
+			writer.Flush()
+			return
+		}
+
+		if err := server.handle(reader, writer, fields); err != nil {
+			if !errors.Is(err, errBadFormat) {
+				return
+			}
+			fmt.Fprintf(writer, "CLIENT_ERROR %s\r\n", err)
+		}
+
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (server *Server) handle(reader *bufio.Reader, writer *bufio.Writer, fields []string) error {
+	command, args := fields[0], fields[1:]
+
+	noreply := len(args) > 0 && args[len(args)-1] == "noreply"
+	if noreply {
+		args = args[:len(args)-1]
+	}
+
+	reply := func(response string) {
+		if !noreply {
+			writer.WriteString(response + "\r\n")
+		}
+	}
+
+	switch command {
+	case "get", "gets":
+		if len(args) == 0 {
+			writer.WriteString("ERROR\r\n")
+			return nil
+		}
+		for _, key := range args {
+			if value, found := server.pantry.Get(key); found {
+				if command == "gets" {
+					fmt.Fprintf(writer, "VALUE %s 0 %d 0\r\n", key, len(value))
+				} else {
+					fmt.Fprintf(writer, "VALUE %s 0 %d\r\n", key, len(value))
+				}
+				writer.Write(value)
+				writer.WriteString("\r\n")
+			}
+		}
+		writer.WriteString("END\r\n")
+
+	case "set":
+		if len(args) != 4 {
+			writer.WriteString("ERROR\r\n")
+			return nil
+		}
+		if _, err := strconv.ParseUint(args[1], 10, 32); err != nil {
+			return errBadFormat
+		}
+		exptime, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errBadFormat
+		}
+		size, err := strconv.Atoi(args[3])
+		if err != nil || size < 0 || size > maxValueSize {
+			return errBadFormat
+		}
+
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return err
+		}
+		if string(value[size:]) != "\r\n" {
+			return fmt.Errorf("%w: bad data chunk", errBadFormat)
+		}
+		value = value[:size]
+
+		switch ttl, ok := expiration(exptime); {
+		case !ok:
+			server.pantry.Remove(args[0])
+		case ttl == 0:
+			server.pantry.Set(args[0], value)
+		default:
+			server.pantry.SetWithTTL(args[0], value, ttl)
+		}
+		reply("STORED")
+
+	case "delete":
+		if len(args) != 1 {
+			writer.WriteString("ERROR\r\n")
+			return nil
+		}
+		if _, found := server.pantry.Pop(args[0]); found {
+			reply("DELETED")
+		} else {
+			reply("NOT_FOUND")
+		}
+
+	case "touch":
+		if len(args) != 2 {
+			writer.WriteString("ERROR\r\n")
+			return nil
+		}
+		exptime, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return errBadFormat
+		}
+		if server.touch(args[0], exptime) {
+			reply("TOUCHED")
+		} else {
+			reply("NOT_FOUND")
+		}
+
+	case "flush_all":
+		delay := int64(0)
+		if len(args) == 1 {
+			parsed, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return errBadFormat
+			}
+			delay = parsed
+		}
+		if delay > 0 {
+			time.AfterFunc(time.Duration(delay)*time.Second, server.pantry.Clear)
+		} else {
+			server.pantry.Clear()
+		}
+		reply("OK")
+
+	case "version":
+		writer.WriteString("VERSION pantry\r\n")
+
+	default:
+		writer.WriteString("ERROR\r\n")
+	}
+	return nil
+}
+
+func (server *Server) touch(key string, exptime int64) bool {
+	ttl, ok := expiration(exptime)
+	if !ok {
+		_, found := server.pantry.Pop(key)
+		return found
+	}
+
+	if ttl == 0 {
+		return server.pantry.Touch(key)
+	}
+
+	touched := false
+	server.pantry.UpdateWithExpiry(key, func(value []byte, _ time.Duration, found bool) ([]byte, time.Duration, bool) {
+		touched = found
+		return value, ttl, found
+	})
+	return touched
+}
+
+// expiration converts a memcached exptime into a TTL. Zero means the pantry's
+// default expiration and false means the entry is already expired.
+func expiration(exptime int64) (time.Duration, bool) {
+	switch {
+	case exptime == 0:
+		return 0, true
+	case exptime < 0:
+		return 0, false
+	case exptime <= maxRelativeExpiration:
+		return time.Duration(exptime) * time.Second, true
+	default:
+		ttl := time.Until(time.Unix(exptime, 0))
+		return ttl, ttl > 0
+	}
+}
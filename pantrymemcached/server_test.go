@@ -0,0 +1,121 @@
+package pantrymemcached
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func dial(t *testing.T) (*pantry.Pantry[[]byte], func(command string, lines int) string) {
+	t.Helper()
+
+	p := pantry.New[[]byte](context.Background(), time.Hour)
+	t.Cleanup(func() { p.Close() })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go NewServer(p).Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	reader := bufio.NewReader(conn)
+
+	return p, func(command string, lines int) string {
+		t.Helper()
+
+		if _, err := conn.Write([]byte(command)); err != nil {
+			t.Fatal(err)
+		}
+
+		var response strings.Builder
+		for range lines {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.WriteString(line)
+		}
+		return response.String()
+	}
+}
+
+func TestServer(t *testing.T) {
+	p, send := dial(t)
+
+	if response := send("set first 0 0 5\r\nhello\r\n", 1); response != "STORED\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send("get first missing\r\n", 3); response != "VALUE first 0 5\r\nhello\r\nEND\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send("touch first 60\r\n", 1); response != "TOUCHED\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if ttl, _ := p.TTL("first"); ttl > time.Minute || ttl < 59*time.Second {
+		t.Fatal("unexpected ttl", ttl)
+	}
+
+	if response := send("delete first\r\n", 1); response != "DELETED\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send("delete first\r\n", 1); response != "NOT_FOUND\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	send("set second 0 0 1 noreply\r\na\r\n", 0)
+
+	if response := send("flush_all\r\n", 1); response != "OK\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if !p.IsEmpty() {
+		t.Fatal("not flushed")
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	_, send := dial(t)
+
+	if response := send("unknown\r\n", 1); response != "ERROR\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send("set key 0 0 nope\r\n", 1); !strings.HasPrefix(response, "CLIENT_ERROR") {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send("version\r\n", 1); response != "VERSION pantry\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	if ttl, ok := expiration(10); !ok || ttl != 10*time.Second {
+		t.Fatal("relative", ttl)
+	}
+
+	if _, ok := expiration(-1); ok {
+		t.Fatal("negative")
+	}
+
+	if ttl, ok := expiration(time.Now().Add(time.Hour).Unix()); !ok || ttl < 59*time.Minute {
+		t.Fatal("absolute", ttl)
+	}
+}
@@ -0,0 +1,47 @@
+// Package pantryotel records pantry operations as OpenTelemetry spans.
+package pantryotel
+
+import (
+	"context"
+
+	"github.com/webermarci/pantry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	KeyAttribute = attribute.Key("pantry.key")
+	HitAttribute = attribute.Key("pantry.hit")
+)
+
+type tracer struct {
+	tracer trace.Tracer
+	keys   bool
+}
+
+// NewTracer returns a pantry.Tracer starting a span named "pantry.<operation>"
+// for every operation, with the hit or miss of lookups as an attribute. Keys
+// are only recorded if withKeys is true, as they may hold personal data.
+func NewTracer(t trace.Tracer, withKeys bool) pantry.Tracer {
+	return &tracer{tracer: t, keys: withKeys}
+}
+
+func (tracer *tracer) Start(ctx context.Context, operation string, key string) func(hit bool, err error) {
+	var options []trace.SpanStartOption
+	if tracer.keys {
+		options = append(options, trace.WithAttributes(KeyAttribute.String(key)))
+	}
+
+	_, span := tracer.tracer.Start(ctx, "pantry."+operation, options...)
+	return func(hit bool, err error) {
+		if operation == "get" {
+			span.SetAttributes(HitAttribute.Bool(hit))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,58 @@
+package pantryotel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	p := pantry.New(context.Background(), time.Hour,
+		pantry.WithTracer[int](NewTracer(provider.Tracer("test"), true)),
+	)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	p.SetContext(ctx, "key", 1)
+	p.GetContext(ctx, "key")
+	p.GetContext(ctx, "missing")
+	p.RemoveContext(ctx, "key")
+	parent.End()
+
+	spans := recorder.Ended()
+	expected := []struct {
+		name string
+		key  string
+		hit  string
+	}{
+		{"pantry.set", "key", ""},
+		{"pantry.get", "key", "true"},
+		{"pantry.get", "missing", "false"},
+		{"pantry.remove", "key", ""},
+	}
+
+	if len(spans) != len(expected)+1 {
+		t.Fatal("unexpected span count", len(spans))
+	}
+
+	for i, want := range expected {
+		span := spans[i]
+		if span.Name() != want.name || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatal("unexpected span", span.Name())
+		}
+
+		attributes := make(map[string]string)
+		for _, attribute := range span.Attributes() {
+			attributes[string(attribute.Key)] = attribute.Value.Emit()
+		}
+		if attributes[string(KeyAttribute)] != want.key || attributes[string(HitAttribute)] != want.hit {
+			t.Fatal("unexpected attributes", span.Name(), attributes)
+		}
+	}
+}
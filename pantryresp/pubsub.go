@@ -0,0 +1,219 @@
+package pantryresp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/webermarci/pantry"
+)
+
+// keyspaceEvents holds the parsed notify-keyspace-events flags.
+type keyspaceEvents struct {
+	flags    string
+	keyspace bool
+	keyevent bool
+	generic  bool
+	strings  bool
+	expired  bool
+}
+
+// NotifyKeyspaceEvents enables keyspace notifications like the Redis setting
+// of the same name, which clients may also change with CONFIG SET. K and E
+// select the __keyspace@0__ and __keyevent@0__ channels, and g ("del"), $
+// ("set"), x ("expired") or A (all of them) the events published. Pantry
+// does not tell evictions from removals, so those are reported as "del". The
+// other Redis classes are accepted but never published. An empty string
+// disables notifications, which is the default.
+func (server *Server) NotifyKeyspaceEvents(flags string) error {
+	events := &keyspaceEvents{flags: flags}
+	for _, flag := range flags {
+		switch flag {
+		case 'K':
+			events.keyspace = true
+		case 'E':
+			events.keyevent = true
+		case 'g':
+			events.generic = true
+		case '$':
+			events.strings = true
+		case 'x':
+			events.expired = true
+		case 'A':
+			events.generic, events.strings, events.expired = true, true, true
+		case 'l', 's', 'h', 'z', 'e', 't', 'm', 'd', 'n':
+		default:
+			return fmt.Errorf("ERR Invalid argument '%s' for CONFIG SET 'notify-keyspace-events'", flags)
+		}
+	}
+	server.keyspaceEvents.Store(events)
+	return nil
+}
+
+// notifications returns the channels and messages an event is published on.
+func (server *Server) notifications(event pantry.Event[[]byte]) [][2]string {
+	events := server.keyspaceEvents.Load()
+	if events == nil {
+		return nil
+	}
+
+	var name string
+	switch {
+	case event.Kind == pantry.EventSet && events.strings:
+		name = "set"
+	case event.Kind == pantry.EventRemoved && events.generic:
+		name = "del"
+	case event.Kind == pantry.EventExpired && events.expired:
+		name = "expired"
+	default:
+		return nil
+	}
+
+	var notifications [][2]string
+	if events.keyspace {
+		notifications = append(notifications, [2]string{"__keyspace@0__:" + event.Key, name})
+	}
+	if events.keyevent {
+		notifications = append(notifications, [2]string{"__keyevent@0__:" + name, event.Key})
+	}
+	return notifications
+}
+
+// session is the state of a connection, whose writer is shared with the
+// delivery of notifications once it subscribed.
+type session struct {
+	mutex    sync.Mutex
+	writer   *bufio.Writer
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	cancel   context.CancelFunc
+}
+
+func (session *session) subscriptions() int {
+	return len(session.channels) + len(session.patterns)
+}
+
+func (session *session) close() {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if session.cancel != nil {
+		session.cancel()
+	}
+}
+
+// handleSubscription handles the pub/sub commands and refuses all others but
+// PING while the session is subscribed. It reports whether it handled the
+// command and must be called with the session locked.
+func (server *Server) handleSubscription(session *session, args []string) bool {
+	command := strings.ToUpper(args[0])
+	args = args[1:]
+	writer := session.writer
+
+	switch command {
+	case "SUBSCRIBE", "PSUBSCRIBE":
+		if len(args) == 0 {
+			writeArity(writer, command)
+			return true
+		}
+
+		subscriptions := &session.channels
+		if command == "PSUBSCRIBE" {
+			subscriptions = &session.patterns
+		}
+		if *subscriptions == nil {
+			*subscriptions = make(map[string]struct{})
+		}
+
+		for _, name := range args {
+			(*subscriptions)[name] = struct{}{}
+			writeSubscription(writer, strings.ToLower(command), name, session.subscriptions())
+		}
+
+		if session.cancel == nil {
+			ctx, cancel := context.WithCancel(context.Background())
+			session.cancel = cancel
+			go server.deliver(session, server.pantry.Subscribe(ctx, defaultNotifyBuffer))
+		}
+
+	case "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		subscriptions := session.channels
+		if command == "PUNSUBSCRIBE" {
+			subscriptions = session.patterns
+		}
+
+		if len(args) == 0 {
+			for name := range subscriptions {
+				args = append(args, name)
+			}
+		}
+
+		if len(args) == 0 {
+			writer.WriteString("*3\r\n")
+			writeBulk(writer, []byte(strings.ToLower(command)))
+			writer.WriteString("$-1\r\n")
+			writeInteger(writer, int64(session.subscriptions()))
+		}
+		for _, name := range args {
+			delete(subscriptions, name)
+			writeSubscription(writer, strings.ToLower(command), name, session.subscriptions())
+		}
+
+		if session.subscriptions() == 0 && session.cancel != nil {
+			session.cancel()
+			session.cancel = nil
+		}
+
+	case "PING":
+		if session.subscriptions() == 0 {
+			return false
+		}
+		message := ""
+		if len(args) == 1 {
+			message = args[0]
+		}
+		writeArray(writer, []string{"pong", message})
+
+	default:
+		if session.subscriptions() == 0 {
+			return false
+		}
+		writeError(writer, fmt.Sprintf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", strings.ToLower(command)))
+	}
+	return true
+}
+
+// deliver writes the notifications of events to the session until its
+// subscription ends.
+func (server *Server) deliver(session *session, events <-chan pantry.Event[[]byte]) {
+	for event := range events {
+		notifications := server.notifications(event)
+		if len(notifications) == 0 {
+			continue
+		}
+
+		session.mutex.Lock()
+		for _, notification := range notifications {
+			channel, message := notification[0], notification[1]
+			if _, found := session.channels[channel]; found {
+				writeArray(session.writer, []string{"message", channel, message})
+			}
+			for pattern := range session.patterns {
+				if pantry.Match(pattern, channel) {
+					writeArray(session.writer, []string{"pmessage", pattern, channel, message})
+				}
+			}
+		}
+		session.writer.Flush()
+		session.mutex.Unlock()
+	}
+}
+
+func writeSubscription(writer *bufio.Writer, kind, name string, count int) {
+	writer.WriteString("*3\r\n")
+	writeBulk(writer, []byte(kind))
+	writeBulk(writer, []byte(name))
+	writeInteger(writer, int64(count))
+}
@@ -0,0 +1,59 @@
+package pantryresp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyspaceNotifications(t *testing.T) {
+	p, send := dial(t)
+
+	if response := send(1, "CONFIG", "SET", "notify-keyspace-events", "nope"); !strings.HasPrefix(response, "-ERR Invalid argument") {
+		t.Fatalf("unexpected response %q", response)
+	}
+	if response := send(1, "CONFIG", "SET", "notify-keyspace-events", "Egx$"); response != "+OK\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+	if response := send(5, "CONFIG", "GET", "notify-*"); response != "*2\r\n$22\r\nnotify-keyspace-events\r\n$4\r\nEgx$\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	expected := "*3\r\n$9\r\nsubscribe\r\n$22\r\n__keyevent@0__:expired\r\n:1\r\n"
+	if response := send(6, "SUBSCRIBE", "__keyevent@0__:expired"); response != expected {
+		t.Fatalf("unexpected response %q", response)
+	}
+	expected = "*3\r\n$10\r\npsubscribe\r\n$16\r\n__keyevent@0__:*\r\n:2\r\n"
+	if response := send(6, "PSUBSCRIBE", "__keyevent@0__:*"); response != expected {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(1, "GET", "key"); !strings.HasPrefix(response, "-ERR Can't execute 'get'") {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	p.SetWithTTL("key", []byte("value"), time.Millisecond)
+	expected = "*4\r\n$8\r\npmessage\r\n$16\r\n__keyevent@0__:*\r\n$18\r\n__keyevent@0__:set\r\n$3\r\nkey\r\n"
+	if response := send(9); response != expected {
+		t.Fatalf("unexpected notification %q", response)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	p.Get("key")
+
+	expected = "*3\r\n$7\r\nmessage\r\n$22\r\n__keyevent@0__:expired\r\n$3\r\nkey\r\n" +
+		"*4\r\n$8\r\npmessage\r\n$16\r\n__keyevent@0__:*\r\n$22\r\n__keyevent@0__:expired\r\n$3\r\nkey\r\n"
+	if response := send(16); response != expected {
+		t.Fatalf("unexpected notification %q", response)
+	}
+
+	send(6, "PUNSUBSCRIBE")
+	expected = "*3\r\n$11\r\nunsubscribe\r\n$22\r\n__keyevent@0__:expired\r\n:0\r\n"
+	if response := send(6, "UNSUBSCRIBE"); response != expected {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(1, "PING"); response != "+PONG\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+}
@@ -0,0 +1,326 @@
+// Package pantryresp serves a pantry over a subset of the Redis protocol
+// (RESP) so that redis-cli and Redis client libraries can talk to it. It
+// supports PING, GET, SET with EX or PX, DEL, EXISTS, TTL, KEYS and FLUSHDB.
+//
+// Keyspace notifications are published to clients using SUBSCRIBE or
+// PSUBSCRIBE once enabled with CONFIG SET notify-keyspace-events or
+// Server.NotifyKeyspaceEvents, so tooling reacting to Redis expiry events
+// works against a pantry.
+package pantryresp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+const (
+	// maxLength bounds both the number of arguments of a command and the
+	// length of each, so a single header line cannot make the server
+	// allocate arbitrary amounts of memory.
+	maxLength           = 1 << 20
+	defaultNotifyBuffer = 1024
+)
+
+var errProtocol = errors.New("ERR Protocol error")
+
+type Server struct {
+	pantry         *pantry.Pantry[[]byte]
+	keyspaceEvents atomic.Pointer[keyspaceEvents]
+}
+
+func NewServer(p *pantry.Pantry[[]byte]) *Server {
+	return &Server{pantry: p}
+}
+
+// Serve accepts connections on listener until it is closed.
+func (server *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go server.serveConn(conn)
+	}
+}
+
+func (server *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	return server.Serve(listener)
+}
+
+func (server *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+
+	session := &session{writer: writer}
+	defer session.close()
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				session.mutex.Lock()
+				writeError(writer, err.Error())
+				writer.Flush()
+				session.mutex.Unlock()
+			}
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		session.mutex.Lock()
+		if strings.EqualFold(args[0], "QUIT") {
+			writeSimple(writer, "OK")
+			writer.Flush()
+			session.mutex.Unlock()
+			return
+		}
+
+		if !server.handleSubscription(session, args) {
+			server.handle(writer, args)
+		}
+
+		err = writer.Flush()
+		session.mutex.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (server *Server) handle(writer *bufio.Writer, args []string) {
+	command := strings.ToUpper(args[0])
+	args = args[1:]
+
+	switch command {
+	case "PING":
+		if len(args) == 1 {
+			writeBulk(writer, []byte(args[0]))
+		} else {
+			writeSimple(writer, "PONG")
+		}
+
+	case "COMMAND":
+		writeArray(writer, nil)
+
+	case "GET":
+		if len(args) != 1 {
+			writeArity(writer, command)
+			return
+		}
+		if value, found := server.pantry.Get(args[0]); found {
+			writeBulk(writer, value)
+		} else {
+			writer.WriteString("$-1\r\n")
+		}
+
+	case "SET":
+		if len(args) != 2 && len(args) != 4 {
+			writeError(writer, "ERR syntax error")
+			return
+		}
+
+		if len(args) == 2 {
+			server.pantry.Set(args[0], []byte(args[1]))
+			writeSimple(writer, "OK")
+			return
+		}
+
+		amount, err := strconv.ParseInt(args[3], 10, 64)
+		if err != nil || amount <= 0 {
+			writeError(writer, "ERR invalid expire time in 'set' command")
+			return
+		}
+
+		var ttl time.Duration
+		switch strings.ToUpper(args[2]) {
+		case "EX":
+			ttl = time.Duration(amount) * time.Second
+		case "PX":
+			ttl = time.Duration(amount) * time.Millisecond
+		default:
+			writeError(writer, "ERR syntax error")
+			return
+		}
+
+		server.pantry.SetWithTTL(args[0], []byte(args[1]), ttl)
+		writeSimple(writer, "OK")
+
+	case "DEL", "EXISTS":
+		if len(args) == 0 {
+			writeArity(writer, command)
+			return
+		}
+		count := 0
+		for _, key := range args {
+			var found bool
+			if command == "DEL" {
+				_, found = server.pantry.Pop(key)
+			} else {
+				_, found = server.pantry.Get(key)
+			}
+			if found {
+				count++
+			}
+		}
+		writeInteger(writer, int64(count))
+
+	case "TTL":
+		if len(args) != 1 {
+			writeArity(writer, command)
+			return
+		}
+		ttl, found := server.pantry.TTL(args[0])
+		switch {
+		case !found:
+			writeInteger(writer, -2)
+		case ttl == pantry.NoExpiration:
+			writeInteger(writer, -1)
+		default:
+			writeInteger(writer, int64((ttl+time.Second-1)/time.Second))
+		}
+
+	case "KEYS":
+		if len(args) != 1 {
+			writeArity(writer, command)
+			return
+		}
+		var keys []string
+		for key := range server.pantry.Keys() {
+			if pantry.Match(args[0], key) {
+				keys = append(keys, key)
+			}
+		}
+		writeArray(writer, keys)
+
+	case "CONFIG":
+		switch {
+		case len(args) == 3 && strings.EqualFold(args[0], "SET") && strings.EqualFold(args[1], "notify-keyspace-events"):
+			if err := server.NotifyKeyspaceEvents(args[2]); err != nil {
+				writeError(writer, err.Error())
+				return
+			}
+			writeSimple(writer, "OK")
+		case len(args) == 2 && strings.EqualFold(args[0], "GET"):
+			flags := ""
+			if events := server.keyspaceEvents.Load(); events != nil {
+				flags = events.flags
+			}
+			if pantry.Match(args[1], "notify-keyspace-events") {
+				writeArray(writer, []string{"notify-keyspace-events", flags})
+			} else {
+				writeArray(writer, nil)
+			}
+		case len(args) >= 1 && strings.EqualFold(args[0], "SET"):
+			writeError(writer, "ERR Unsupported CONFIG parameter")
+		default:
+			writeError(writer, "ERR syntax error")
+		}
+
+	case "FLUSHDB", "FLUSHALL":
+		server.pantry.Clear()
+		writeSimple(writer, "OK")
+
+	default:
+		writeError(writer, fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(command)))
+	}
+}
+
+// readCommand reads either a RESP array of bulk strings or an inline command.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 0 || count > maxLength {
+		return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+	}
+
+	args := make([]string, 0, count)
+	for range count {
+		header, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(header, "$") {
+			return nil, fmt.Errorf("%w: expected '$', got '%.1s'", errProtocol, header)
+		}
+
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 || size > maxLength {
+			return nil, fmt.Errorf("%w: invalid bulk length", errProtocol)
+		}
+
+		bulk := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, bulk); err != nil {
+			return nil, err
+		}
+		args = append(args, string(bulk[:size]))
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeSimple(writer *bufio.Writer, value string) {
+	writer.WriteString("+" + value + "\r\n")
+}
+
+func writeError(writer *bufio.Writer, message string) {
+	writer.WriteString("-" + message + "\r\n")
+}
+
+func writeArity(writer *bufio.Writer, command string) {
+	writeError(writer, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(command)))
+}
+
+func writeInteger(writer *bufio.Writer, value int64) {
+	writer.WriteString(":" + strconv.FormatInt(value, 10) + "\r\n")
+}
+
+func writeBulk(writer *bufio.Writer, value []byte) {
+	writer.WriteString("$" + strconv.Itoa(len(value)) + "\r\n")
+	writer.Write(value)
+	writer.WriteString("\r\n")
+}
+
+func writeArray(writer *bufio.Writer, values []string) {
+	writer.WriteString("*" + strconv.Itoa(len(values)) + "\r\n")
+	for _, value := range values {
+		writeBulk(writer, []byte(value))
+	}
+}
@@ -0,0 +1,150 @@
+package pantryresp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func dial(t *testing.T) (*pantry.Pantry[[]byte], func(lines int, args ...string) string) {
+	t.Helper()
+
+	p := pantry.New[[]byte](context.Background(), time.Hour)
+	t.Cleanup(func() { p.Close() })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go NewServer(p).Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	reader := bufio.NewReader(conn)
+
+	// Without args, send only reads, such as notifications pushed to
+	// subscribers.
+	return p, func(lines int, args ...string) string {
+		t.Helper()
+
+		if len(args) > 0 {
+			writer := bufio.NewWriter(conn)
+			writeArray(writer, args)
+			if err := writer.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var response strings.Builder
+		for range lines {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.WriteString(line)
+		}
+		return response.String()
+	}
+}
+
+func TestServer(t *testing.T) {
+	p, send := dial(t)
+
+	if response := send(1, "PING"); response != "+PONG\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(1, "SET", "first", "hello", "EX", "60"); response != "+OK\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(2, "GET", "first"); response != "$5\r\nhello\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(1, "GET", "missing"); response != "$-1\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(1, "TTL", "first"); response != ":60\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(1, "TTL", "missing"); response != ":-2\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	send(1, "SET", "second", "world")
+
+	if response := send(1, "EXISTS", "first", "second", "missing"); response != ":2\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(3, "KEYS", "f*"); response != "*1\r\n$5\r\nfirst\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(1, "DEL", "first", "missing"); response != ":1\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(1, "FLUSHDB"); response != "+OK\r\n" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if !p.IsEmpty() {
+		t.Fatal("not flushed")
+	}
+
+	if response := send(1, "NOPE"); !strings.HasPrefix(response, "-ERR unknown command") {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if response := send(1, "SET", "key", "value", "EX", "-1"); !strings.HasPrefix(response, "-ERR invalid expire") {
+		t.Fatalf("unexpected response %q", response)
+	}
+}
+
+func TestProtocolLimits(t *testing.T) {
+	p := pantry.New[[]byte](context.Background(), time.Hour)
+	defer p.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go NewServer(p).Serve(listener)
+
+	for _, request := range []string{
+		"*99999999999999\r\n",
+		"*-1\r\n",
+		"*1\r\n$99999999999\r\n",
+	} {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte(request))
+
+		reply, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil || !strings.HasPrefix(reply, "-ERR Protocol error") {
+			t.Fatalf("unexpected reply to %q: %q %v", request, reply, err)
+		}
+		if _, err := bufio.NewReader(conn).ReadByte(); err == nil {
+			t.Fatal("connection not closed")
+		}
+		conn.Close()
+	}
+}
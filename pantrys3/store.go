@@ -0,0 +1,247 @@
+// Package pantrys3 keeps pantry snapshots in S3 compatible object storage.
+// Requests are signed with AWS Signature Version 4 and need no SDK.
+package pantrys3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+// Config locates the bucket and holds the credentials.
+type Config struct {
+	// Endpoint is the base URL of the service, for example
+	// https://s3.eu-central-1.amazonaws.com or the address of a MinIO
+	// server. Objects are addressed path style, as Endpoint/Bucket/name.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix is prepended to the snapshot names.
+	Prefix string
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// Store is a pantry.SnapshotStore keeping every snapshot in one object.
+type Store struct {
+	config Config
+	now    func() time.Time
+}
+
+var _ pantry.SnapshotStore = (*Store)(nil)
+
+// New returns a store keeping snapshots in the bucket described by config.
+func New(config Config) *Store {
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &Store{config: config, now: time.Now}
+}
+
+// Put uploads the snapshot in a single request, so it is read into memory
+// first to sign its hash.
+func (store *Store) Put(ctx context.Context, name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	response, err := store.do(ctx, http.MethodPut, store.config.Prefix+name, nil, data)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	io.Copy(io.Discard, response.Body)
+	return nil
+}
+
+func (store *Store) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	response, err := store.do(ctx, http.MethodGet, store.config.Prefix+name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+type listResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// List pages through ListObjectsV2.
+func (store *Store) List(ctx context.Context, prefix string) ([]string, error) {
+	query := url.Values{"list-type": {"2"}, "prefix": {store.config.Prefix + prefix}}
+
+	var names []string
+	for {
+		response, err := store.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range result.Contents {
+			names = append(names, strings.TrimPrefix(object.Key, store.config.Prefix))
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+func (store *Store) Delete(ctx context.Context, name string) error {
+	response, err := store.do(ctx, http.MethodDelete, store.config.Prefix+name, nil, nil)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// do sends a signed request for the object key, or the bucket itself if key
+// is empty, and turns error responses into errors, a missing object into one
+// matching fs.ErrNotExist.
+func (store *Store) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	endpoint := strings.TrimSuffix(store.config.Endpoint, "/")
+	target := endpoint + "/" + escapePath(store.config.Bucket)
+	if key != "" {
+		target += "/" + escapePath(key)
+	}
+	if len(query) > 0 {
+		target += "?" + canonicalQuery(query)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.ContentLength = int64(len(body))
+
+	sign(request, body, store.config, store.now())
+
+	response, err := store.config.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode/100 == 2 {
+		return response, nil
+	}
+	defer response.Body.Close()
+
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	err = fmt.Errorf("pantrys3: %s %s: %s: %s", method, key, response.Status, bytes.TrimSpace(message))
+	if response.StatusCode == http.StatusNotFound {
+		err = fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+	}
+	return nil, err
+}
+
+// sign adds the AWS Signature Version 4 headers, signing the host and every
+// header already set on the request.
+func sign(request *http.Request, body []byte, config Config, now time.Time) {
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+	timestamp := now.UTC().Format("20060102T150405Z")
+	date := timestamp[:8]
+
+	request.Header.Set("X-Amz-Date", timestamp)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + config.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + timestamp + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := []byte("AWS4" + config.SecretAccessKey)
+	for _, part := range []string{date, config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+config.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery encodes the query sorted by name with spaces as %20, which
+// is also how it is sent.
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapePath escapes everything but unreserved characters and slashes, as
+// Signature Version 4 expects.
+func escapePath(path string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			escaped.WriteByte(c)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
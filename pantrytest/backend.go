@@ -0,0 +1,67 @@
+package pantrytest
+
+import (
+	"sync"
+)
+
+// Backend is an in-memory pantry.Backend whose calls can be made to fail,
+// for testing how an application copes with its backing store going down.
+type Backend[T any] struct {
+	mutex  sync.Mutex
+	values map[string]T
+	err    error
+}
+
+func NewBackend[T any]() *Backend[T] {
+	return &Backend[T]{values: make(map[string]T)}
+}
+
+// Fail makes every later call return err, until called again with nil.
+func (backend *Backend[T]) Fail(err error) {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	backend.err = err
+}
+
+// Value returns what the backend holds for key, ignoring Fail.
+func (backend *Backend[T]) Value(key string) (T, bool) {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	value, found := backend.values[key]
+	return value, found
+}
+
+func (backend *Backend[T]) Load(key string) (T, bool, error) {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	if backend.err != nil {
+		return *new(T), false, backend.err
+	}
+	value, found := backend.values[key]
+	return value, found, nil
+}
+
+func (backend *Backend[T]) Store(key string, value T) error {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	if backend.err != nil {
+		return backend.err
+	}
+	backend.values[key] = value
+	return nil
+}
+
+func (backend *Backend[T]) Delete(key string) error {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	if backend.err != nil {
+		return backend.err
+	}
+	delete(backend.values, key)
+	return nil
+}
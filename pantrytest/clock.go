@@ -0,0 +1,93 @@
+// Package pantrytest provides helpers for testing code that uses pantry.
+package pantrytest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+// Clock is a fake pantry.Clock that only moves when advanced, so
+// expiration can be tested without sleeping.
+type Clock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	tickers []*ticker
+}
+
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (clock *Clock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+func (clock *Clock) NewTicker(d time.Duration) pantry.Ticker {
+	if d <= 0 {
+		panic("pantrytest: non-positive interval for NewTicker")
+	}
+
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	ticker := &ticker{
+		clock:    clock,
+		interval: d,
+		next:     clock.now.Add(d),
+		c:        make(chan time.Time, 1),
+	}
+	clock.tickers = append(clock.tickers, ticker)
+	return ticker
+}
+
+// Advance moves the clock forward by d and fires the tickers that became
+// due. Like time.Ticker, a ticker whose channel is full drops the tick.
+func (clock *Clock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.now = clock.now.Add(d)
+
+	for _, ticker := range clock.tickers {
+		if ticker.next.After(clock.now) {
+			continue
+		}
+
+		select {
+		case ticker.c <- clock.now:
+		default:
+		}
+
+		for !ticker.next.After(clock.now) {
+			ticker.next = ticker.next.Add(ticker.interval)
+		}
+	}
+}
+
+type ticker struct {
+	clock    *Clock
+	interval time.Duration
+	next     time.Time
+	c        chan time.Time
+}
+
+func (ticker *ticker) C() <-chan time.Time {
+	return ticker.c
+}
+
+func (ticker *ticker) Stop() {
+	ticker.clock.mutex.Lock()
+	defer ticker.clock.mutex.Unlock()
+
+	for i, registered := range ticker.clock.tickers {
+		if registered == ticker {
+			ticker.clock.tickers = append(ticker.clock.tickers[:i], ticker.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package pantrytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func TestClockExpiration(t *testing.T) {
+	clock := NewClock(time.Now())
+
+	p := pantry.New(context.Background(), time.Hour, pantry.WithClock[string](clock))
+	defer p.Close()
+
+	p.Set("key", "value")
+
+	clock.Advance(59 * time.Minute)
+
+	if _, found := p.Get("key"); !found {
+		t.Fatal("expired early")
+	}
+
+	clock.Advance(2 * time.Minute)
+
+	if _, found := p.Get("key"); found {
+		t.Fatal("not expired")
+	}
+}
+
+func TestClockCleanup(t *testing.T) {
+	clock := NewClock(time.Now())
+
+	p := pantry.New(context.Background(), time.Minute, pantry.WithClock[string](clock))
+	defer p.Close()
+
+	p.Set("key", "value")
+
+	clock.Advance(2 * time.Minute)
+
+	deadline := time.Now().Add(time.Second)
+	for p.Stats().Items != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("not cleaned up")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTicker(t *testing.T) {
+	clock := NewClock(time.Now())
+
+	ticker := clock.NewTicker(time.Second)
+
+	clock.Advance(500 * time.Millisecond)
+
+	select {
+	case <-ticker.C():
+		t.Fatal("ticked early")
+	default:
+	}
+
+	clock.Advance(time.Second)
+
+	select {
+	case <-ticker.C():
+	default:
+		t.Fatal("not ticked")
+	}
+
+	ticker.Stop()
+	clock.Advance(time.Hour)
+
+	select {
+	case <-ticker.C():
+		t.Fatal("ticked after stop")
+	default:
+	}
+}
@@ -0,0 +1,60 @@
+package pantrytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+// Harness is a pantry running on a fake clock, writing through to a Backend
+// and persisting to a Storage, both of which can be made to fail. It lets
+// tests drive expiration and failures without sleeping or breaking real
+// infrastructure.
+type Harness[T any] struct {
+	*pantry.Pantry[T]
+	Clock   *Clock
+	Backend *Backend[T]
+	Storage *Storage
+}
+
+// NewHarness creates a harness whose pantry is closed when the test ends.
+// The options are applied after the harness' own, so they may replace the
+// write-through backend, for example with pantry.WithWriteBehind.
+func NewHarness[T any](tb testing.TB, expiration time.Duration, opts ...pantry.Option[T]) *Harness[T] {
+	tb.Helper()
+
+	harness := &Harness[T]{
+		Clock:   NewClock(time.Now()),
+		Backend: NewBackend[T](),
+		Storage: NewStorage(),
+	}
+
+	opts = append([]pantry.Option[T]{
+		pantry.WithClock[T](harness.Clock),
+		pantry.WithWriteThrough[T](harness.Backend),
+	}, opts...)
+
+	p, errs := pantry.NewPersistentWithStorage(context.Background(), expiration, harness.Storage, opts...)
+	tb.Cleanup(func() { p.Close() })
+	if len(errs) > 0 {
+		tb.Fatal(errs)
+	}
+
+	harness.Pantry = p
+	return harness
+}
+
+// Advance moves the clock forward by d, expiring the entries that became due.
+func (harness *Harness[T]) Advance(d time.Duration) {
+	harness.Clock.Advance(d)
+}
+
+// Expire makes the live entries of keys expire right away, firing the
+// expiration hooks as if their TTL ran out.
+func (harness *Harness[T]) Expire(keys ...string) {
+	for _, key := range keys {
+		harness.Pantry.Expire(key)
+	}
+}
@@ -0,0 +1,79 @@
+package pantrytest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func TestHarnessExpiration(t *testing.T) {
+	harness := NewHarness[string](t, time.Hour)
+
+	var expired []string
+	harness.OnExpireEntry(func(entry pantry.Entry[string]) {
+		expired = append(expired, entry.Key)
+	})
+
+	harness.Set("first", "value")
+	harness.Set("second", "value")
+
+	harness.Expire("first")
+	if harness.Contains("first") {
+		t.Fatal("not expired")
+	}
+	if len(expired) != 1 || expired[0] != "first" {
+		t.Fatal("unexpected expirations", expired)
+	}
+
+	harness.Advance(2 * time.Hour)
+	if harness.Contains("second") {
+		t.Fatal("not expired after advance")
+	}
+}
+
+func TestHarnessBackendFailure(t *testing.T) {
+	harness := NewHarness[string](t, time.Hour)
+
+	var failures int
+	harness.OnBackendError(func(key string, err error) { failures++ })
+
+	harness.Set("key", "value")
+	if value, found := harness.Backend.Value("key"); !found || value != "value" {
+		t.Fatal("not written through", value, found)
+	}
+
+	outage := errors.New("backend down")
+	harness.Backend.Fail(outage)
+
+	harness.Set("key", "changed")
+	if failures != 1 {
+		t.Fatal("failure not reported", failures)
+	}
+
+	harness.Remove("key")
+	if _, err := harness.GetE("key"); !errors.Is(err, pantry.ErrNotFound) || failures != 3 {
+		t.Fatal("unexpected error", err, failures)
+	}
+
+	harness.Backend.Fail(nil)
+	if value, err := harness.GetE("key"); err != nil || value != "value" {
+		t.Fatal("unexpected value after recovery", value, err)
+	}
+}
+
+func TestHarnessStorageFailure(t *testing.T) {
+	harness := NewHarness(t, time.Hour, pantry.WithAutoPersist[string]())
+
+	outage := errors.New("disk full")
+	harness.Storage.Fail(outage)
+	harness.Set("key", "value")
+
+	if err := harness.Close(); !errors.Is(err, outage) {
+		t.Fatal("unexpected error", err)
+	}
+	if harness.Storage.Len() != 0 {
+		t.Fatal("written despite failure")
+	}
+}
@@ -0,0 +1,72 @@
+package pantrytest
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Storage is an in-memory pantry.Storage whose calls can be made to fail,
+// for testing how an application copes with persistence errors without
+// breaking a real disk.
+type Storage struct {
+	mutex sync.Mutex
+	data  map[string][]byte
+	err   error
+}
+
+func NewStorage() *Storage {
+	return &Storage{data: make(map[string][]byte)}
+}
+
+// Fail makes every later Load and Write return err, until called again with
+// nil.
+func (storage *Storage) Fail(err error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	storage.err = err
+}
+
+// Len returns the number of stored entries.
+func (storage *Storage) Len() int {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	return len(storage.data)
+}
+
+func (storage *Storage) Load(fn func(key string, data []byte) error) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	if storage.err != nil {
+		return storage.err
+	}
+	for key, data := range storage.data {
+		if err := fn(key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (storage *Storage) Write(batch map[string][]byte) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	if storage.err != nil {
+		return storage.err
+	}
+	for key, data := range batch {
+		if data == nil {
+			delete(storage.data, key)
+		} else {
+			storage.data[key] = bytes.Clone(data)
+		}
+	}
+	return nil
+}
+
+func (storage *Storage) Close() error {
+	return nil
+}
@@ -0,0 +1,321 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// savedEntry is written by Save. Key is the stored key and Original the
+// canonical key the entry remembers, if any, as in persistedItem.
+type savedEntry[T any] struct {
+	Key      string
+	Value    T
+	TTL      time.Duration
+	Original string
+}
+
+// savedStore is written by Save instead of the bare entries with
+// WithPersistStats.
+type savedStore[T any] struct {
+	Entries []savedEntry[T]
+	Stats   savedStats
+}
+
+// Save writes the live entries with their remaining TTLs using the codec,
+// and with WithPersistStats the counters too.
+func (pantry *Pantry[T]) Save(w io.Writer) error {
+	pantry.rlockAll()
+	now := pantry.clock.Now().UnixNano()
+	var entries []savedEntry[T]
+	for key, item := range pantry.items() {
+		if !pantry.isExpired(key, item, now) {
+			entries = append(entries, saved(key, item, now))
+		}
+	}
+	pantry.runlockAll()
+
+	var saving any = entries
+	if pantry.persistStats {
+		saving = savedStore[T]{Entries: entries, Stats: pantry.savedStats()}
+	}
+
+	data, err := pantry.codec.Marshal(saving)
+	if err != nil {
+		return err
+	}
+
+	if data, err = pantry.compress(data); err != nil {
+		return err
+	}
+
+	if data, err = pantry.seal(data); err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// Load reads entries written by Save, each expiring after its saved
+// remaining TTL counted from now. With WithPersistStats, saved counters are
+// added to the current ones.
+func (pantry *Pantry[T]) Load(r io.Reader) error {
+	if pantry.closed.Load() {
+		return ErrClosed
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if data, err = pantry.open(data); err != nil {
+		return err
+	}
+
+	if data, err = decompress(data); err != nil {
+		return err
+	}
+
+	entries, stats, err := pantry.decodeSaved(data)
+	if err != nil {
+		return err
+	}
+
+	if stats != nil && pantry.persistStats {
+		pantry.restoreStats(*stats)
+	}
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	pantry.lockAll()
+	defer pantry.unlockAll()
+
+	now := pantry.clock.Now().UnixNano()
+	for _, entry := range entries {
+		evictions = pantry.putSaved(evictions, entry, now)
+	}
+	return nil
+}
+
+// decodeSaved accepts both the bare entries and a savedStore, so a file
+// keeps loading whether or not it was saved with WithPersistStats.
+func (pantry *Pantry[T]) decodeSaved(data []byte) ([]savedEntry[T], *savedStats, error) {
+	var entries []savedEntry[T]
+	err := pantry.codec.Unmarshal(data, &entries)
+	if err == nil {
+		return entries, nil, nil
+	}
+
+	var store savedStore[T]
+	if pantry.codec.Unmarshal(data, &store) != nil {
+		return nil, nil, err
+	}
+	return store.Entries, &store.Stats, nil
+}
+
+func saved[T any](key string, item item[T], now int64) savedEntry[T] {
+	ttl := time.Duration(item.expires - now)
+	if item.expires == neverExpires {
+		ttl = NoExpiration
+	}
+	return savedEntry[T]{Key: key, Value: item.value, TTL: ttl, Original: item.original}
+}
+
+// putSaved must be called with the shard of the entry's restored key locked.
+func (pantry *Pantry[T]) putSaved(evictions []eviction[T], entry savedEntry[T], now int64) []eviction[T] {
+	key, canonical := pantry.restoredKey(entry.Key, entry.Original)
+	return pantry.put(evictions, pantry.shardFor(key), key, pantry.newItem(canonical, entry.Value, expiresAfter(now, entry.TTL)), now)
+}
+
+// restoredKey returns the stored and the canonical key of an entry read back
+// by Load, ImportSnapshot or from the storage, which all keep the stored key.
+// A remembered original key is normalized and hashed again, while a hashed
+// key without one can only be kept as it is.
+func (pantry *Pantry[T]) restoredKey(key, original string) (string, string) {
+	if original != "" {
+		canonical := pantry.canonical(original)
+		return pantry.hash(canonical), canonical
+	}
+	if pantry.keyHasher != nil {
+		return key, ""
+	}
+	canonical := pantry.canonical(key)
+	return canonical, canonical
+}
+
+// SaveFile writes the entries to path atomically, leaving a previous file
+// intact if saving fails.
+func (pantry *Pantry[T]) SaveFile(path string) error {
+	return writeFileAtomic(path, pantry.Save)
+}
+
+func (pantry *Pantry[T]) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return pantry.Load(file)
+}
+
+var ErrNotPersistent = errors.New("pantry: no persistent storage configured")
+
+// persistedItem is stored for every entry of a persistent pantry. Key is the
+// stored key, hashed with WithKeyHasher, and Original the canonical key the
+// entry remembers for collision checks, if any.
+type persistedItem[T any] struct {
+	Key      string
+	Value    T
+	Expires  int64
+	Original string
+}
+
+func persisted[T any](key string, item item[T]) persistedItem[T] {
+	return persistedItem[T]{
+		Key:      key,
+		Value:    item.value,
+		Expires:  item.expires,
+		Original: item.original,
+	}
+}
+
+// NewPersistent creates a pantry backed by one file per key in dir and
+// restores the unexpired entries found there. Files that cannot be read or
+// decoded are skipped and reported in the returned errors.
+func NewPersistent[T any](ctx context.Context, expiration time.Duration, dir string, options ...Option[T]) (*Pantry[T], []error) {
+	pantry := New(ctx, expiration, options...)
+
+	storage, err := newDirStorage(dir, pantry.codec.Extension())
+	if err != nil {
+		return pantry, []error{err}
+	}
+	return pantry, pantry.restore(storage)
+}
+
+// NewPersistentWithStorage works like NewPersistent but keeps the entries in
+// storage, which the pantry closes on Close.
+func NewPersistentWithStorage[T any](ctx context.Context, expiration time.Duration, storage Storage, options ...Option[T]) (*Pantry[T], []error) {
+	pantry := New(ctx, expiration, options...)
+	return pantry, pantry.restore(storage)
+}
+
+// PersistencePath returns the directory passed to NewPersistent, or an empty
+// string if the pantry is not kept in a directory.
+func (pantry *Pantry[T]) PersistencePath() string {
+	if storage, ok := pantry.storage.(*dirStorage); ok {
+		return storage.dir
+	}
+	return ""
+}
+
+func (pantry *Pantry[T]) restore(storage Storage) []error {
+	pantry.storage = storage
+
+	var errs []error
+	now := pantry.clock.Now().UnixNano()
+	err := storage.Load(func(key string, data []byte) error {
+		persisted, err := pantry.decodePersisted(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pantry: restoring %q: %w", key, err))
+			return nil
+		}
+
+		if now > persisted.Expires {
+			return nil
+		}
+
+		key, canonical := pantry.restoredKey(persisted.Key, persisted.Original)
+		shard := pantry.shardFor(key)
+
+		shard.mutex.Lock()
+		pantry.put(nil, shard, key, pantry.newItem(canonical, persisted.Value, persisted.Expires), now)
+		shard.mutex.Unlock()
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	pantry.enforceCapacity()
+
+	if pantry.autoPersist {
+		pantry.startAutoPersist()
+	}
+	return errs
+}
+
+func (pantry *Pantry[T]) decodePersisted(data []byte) (persistedItem[T], error) {
+	var persisted persistedItem[T]
+
+	data, err := pantry.open(data)
+	if err != nil {
+		return persisted, fmt.Errorf("decrypting: %w", err)
+	}
+
+	if data, err = decompress(data); err != nil {
+		return persisted, fmt.Errorf("decompressing: %w", err)
+	}
+
+	if err := pantry.codec.Unmarshal(data, &persisted); err != nil {
+		return persisted, fmt.Errorf("decoding: %w", err)
+	}
+	return persisted, nil
+}
+
+// Persist writes the current state of key to the persistent storage. A
+// missing or expired key is deleted from it instead. Failures are returned as
+// a *PersistError. The encoded entry is held in memory while it is written,
+// as the gob and JSON encoders build a whole value before writing any of it
+// and Compression works on whole slices, so very large values are better
+// split over several keys.
+func (pantry *Pantry[T]) Persist(key string) error {
+	if pantry.storage == nil {
+		return ErrNotPersistent
+	}
+
+	if pantry.closed.Load() {
+		return ErrClosed
+	}
+
+	key = pantry.normalize(key)
+	shard := pantry.shardFor(key)
+
+	shard.mutex.RLock()
+	item, found := shard.store[key]
+	shard.mutex.RUnlock()
+
+	var data []byte
+	if found && !pantry.isExpired(key, item, pantry.clock.Now().UnixNano()) {
+		var err error
+		data, err = pantry.encodePersisted(persisted(key, item))
+		if err != nil {
+			return &PersistError{Keys: []string{key}, Err: err, Attempts: 1}
+		}
+	}
+
+	if failure := pantry.writeStorage(map[string][]byte{key: data}); failure != nil {
+		return failure
+	}
+	return nil
+}
+
+func (pantry *Pantry[T]) encodePersisted(persisted persistedItem[T]) ([]byte, error) {
+	data, err := pantry.codec.Marshal(persisted)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = pantry.compress(data); err != nil {
+		return nil, err
+	}
+	return pantry.seal(data)
+}
@@ -0,0 +1,269 @@
+package pantry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveLoad(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+
+	p.Set("first", "hello")
+	p.SetWithTTL("second", "world", 50*time.Millisecond)
+	p.SetWithTTL("expired", "gone", time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+
+	var buffer bytes.Buffer
+	if err := p.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New[string](context.Background(), time.Hour)
+	if err := restored.Load(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != "hello" {
+		t.Fatal("first not restored")
+	}
+
+	if _, found := restored.Get("expired"); found {
+		t.Fatal("expired restored")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if _, found := restored.Get("second"); found {
+		t.Fatal("remaining ttl not kept")
+	}
+}
+
+func TestSaveLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pantry.gob")
+
+	p := New[int](context.Background(), time.Hour)
+	p.Set("first", 1)
+
+	if err := p.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New[int](context.Background(), time.Hour)
+	if err := restored.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != 1 {
+		t.Fatal("not restored")
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	if err := p.LoadFile(filepath.Join(t.TempDir(), "missing.gob")); err == nil {
+		t.Fatal("no error")
+	}
+}
+
+func TestNewPersistent(t *testing.T) {
+	dir := t.TempDir()
+
+	p, errs := NewPersistent[string](context.Background(), time.Hour, dir)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	p.Set("user/1", "hello")
+	p.SetWithTTL("expired", "gone", time.Millisecond)
+
+	if err := p.Persist("user/1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Persist("expired"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.gob"), []byte("broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, errs := NewPersistent[string](context.Background(), time.Hour, dir)
+	if len(errs) != 1 {
+		t.Fatal("broken file not reported")
+	}
+
+	if value, _ := restored.Get("user/1"); value != "hello" {
+		t.Fatal("not restored")
+	}
+
+	if _, found := restored.Get("expired"); found {
+		t.Fatal("expired restored")
+	}
+}
+
+func TestPersistRemoved(t *testing.T) {
+	dir := t.TempDir()
+
+	p, _ := NewPersistent[string](context.Background(), time.Hour, dir)
+
+	p.Set("first", "hello")
+	if err := p.Persist("first"); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Remove("first")
+	if err := p.Persist("first"); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, _ := NewPersistent[string](context.Background(), time.Hour, dir)
+	if _, found := restored.Get("first"); found {
+		t.Fatal("removed entry restored")
+	}
+}
+
+func TestPersistLargeValue(t *testing.T) {
+	large := strings.Repeat("0123456789abcdef", 1<<20)
+	configs := map[string][]Option[string]{
+		"plain":      nil,
+		"compressed": {WithCompression[string](GzipCompression)},
+		"encrypted":  {WithEncryption[string](bytes.Repeat([]byte{7}, 32))},
+	}
+
+	for name, options := range configs {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			p, _ := NewPersistent(context.Background(), time.Hour, dir, options...)
+			p.Set("large", large)
+			if err := p.Persist("large"); err != nil {
+				t.Fatal(err)
+			}
+
+			restored, errs := NewPersistent(context.Background(), time.Hour, dir, options...)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			if value, _ := restored.Get("large"); value != large {
+				t.Fatal("large value not restored", len(value))
+			}
+		})
+	}
+}
+
+func TestPersistKeyWithSeparators(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "pantry")
+
+	p, errs := NewPersistent[string](context.Background(), time.Hour, dir)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	key := "../users/1\\profile"
+	p.Set(key, "hello")
+	if err := p.Persist(key); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].IsDir() {
+		t.Fatalf("expected a single file, got %v", files)
+	}
+
+	restored, _ := NewPersistent[string](context.Background(), time.Hour, dir)
+	if value, found := restored.Get(key); !found || value != "hello" {
+		t.Fatal("entry not restored")
+	}
+}
+
+func TestPersistWithoutDirectory(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+
+	if err := p.Persist("first"); !errors.Is(err, ErrNotPersistent) {
+		t.Fatal("not ErrNotPersistent")
+	}
+}
+
+func TestPersistencePath(t *testing.T) {
+	dir := t.TempDir()
+
+	p, errs := NewPersistent[string](context.Background(), time.Hour, dir)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if path := p.PersistencePath(); path != dir {
+		t.Fatal("unexpected path", path)
+	}
+
+	if path := New[string](context.Background(), time.Hour).PersistencePath(); path != "" {
+		t.Fatal("unexpected path", path)
+	}
+
+	custom, _ := NewPersistentWithStorage[string](context.Background(), time.Hour, &failingStorage{})
+	if path := custom.PersistencePath(); path != "" {
+		t.Fatal("unexpected path", path)
+	}
+}
+
+func TestSaveLoadStats(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithPersistStats[int](), WithMaxItems[int](1))
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Get("second")
+	p.Get("second")
+	p.Get("missing")
+
+	var buffer bytes.Buffer
+	if err := p.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	saved := buffer.Bytes()
+
+	restored := New(context.Background(), time.Hour, WithPersistStats[int]())
+	if err := restored.Load(bytes.NewReader(saved)); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := restored.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Evictions != 1 || stats.Items != 1 {
+		t.Fatal("counters not restored", stats)
+	}
+
+	// Activity after the restart adds to the loaded counters.
+	restored.Get("second")
+	if hits := restored.Stats().Hits; hits != 3 {
+		t.Fatal("counters not additive", hits)
+	}
+
+	// Without the option the counters are left alone, but the entries load.
+	plain := New[int](context.Background(), time.Hour)
+	if err := plain.Load(bytes.NewReader(saved)); err != nil {
+		t.Fatal(err)
+	}
+	if stats := plain.Stats(); stats.Hits != 0 || stats.Items != 1 {
+		t.Fatal("unexpected stats", stats)
+	}
+
+	// Files saved without the option still load with it.
+	buffer.Reset()
+	if err := plain.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Load(&buffer); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,126 @@
+package pantry
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// PersistError reports keys that could not be written to the persistent
+// storage.
+type PersistError struct {
+	Keys []string
+	Err  error
+	// Transient reports whether writing again may succeed. Storage errors
+	// are transient unless marked with Permanent, encoding errors never are.
+	Transient bool
+	// Attempts is the number of writes tried, including retries.
+	Attempts int
+}
+
+func (err *PersistError) Error() string {
+	return fmt.Sprintf("pantry: persisting %d keys failed after %d attempts: %v", len(err.Keys), err.Attempts, err.Err)
+}
+
+func (err *PersistError) Unwrap() error {
+	return err.Err
+}
+
+type permanentError struct {
+	err error
+}
+
+func (err permanentError) Error() string {
+	return err.err.Error()
+}
+
+func (err permanentError) Unwrap() error {
+	return err.err
+}
+
+// Permanent marks an error returned by a Storage as one that writing again
+// will not fix, so it is not retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// WithPersistRetry retries storage writes that fail with a transient error up
+// to attempts times in total, waiting backoff before the first retry and
+// twice as long before each further one. Retries stop early once the context
+// passed to New is done. attempts must be at least 1 and backoff positive.
+func WithPersistRetry[T any](attempts int, backoff time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if attempts < 1 || backoff <= 0 {
+			pantry.invalidOption("persist retry with %d attempts and backoff %v needs attempts >= 1 and a positive backoff", attempts, backoff)
+			return
+		}
+		pantry.persists.attempts, pantry.persists.backoff = attempts, backoff
+	}
+}
+
+// OnPersistDeadLetter registers a hook called with the keys that the
+// automatic persistence or Close gave up on, so they can be written again
+// later, for example with Persist. Errors of Persist are returned to its
+// caller instead.
+func (pantry *Pantry[T]) OnPersistDeadLetter(fn func(err *PersistError)) {
+	if fn == nil {
+		pantry.persists.onDeadLetter.Store(nil)
+		return
+	}
+	pantry.persists.onDeadLetter.Store(&fn)
+}
+
+// writeStorage writes batch to the storage, retrying transient failures.
+func (pantry *Pantry[T]) writeStorage(batch map[string][]byte) *PersistError {
+	backoff := pantry.persists.backoff
+	for attempt := 1; ; attempt++ {
+		err := pantry.storage.Write(batch)
+		if err == nil {
+			return nil
+		}
+
+		var permanent permanentError
+		transient := !errors.As(err, &permanent)
+		if !transient || attempt >= pantry.persists.attempts || !pantry.wait(backoff) {
+			return &PersistError{
+				Keys:      slices.Sorted(maps.Keys(batch)),
+				Err:       err,
+				Transient: transient,
+				Attempts:  attempt,
+			}
+		}
+		backoff *= 2
+	}
+}
+
+// wait sleeps for d and reports false if the context passed to New is done
+// first.
+func (pantry *Pantry[T]) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-pantry.ctx.Done():
+		return false
+	}
+}
+
+// deadLetter reports the failures and joins them into one error.
+func (pantry *Pantry[T]) deadLetter(failures []*PersistError) error {
+	errs := make([]error, len(failures))
+	onDeadLetter := pantry.persists.onDeadLetter.Load()
+	for i, failure := range failures {
+		if onDeadLetter != nil {
+			(*onDeadLetter)(failure)
+		}
+		errs[i] = failure
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,513 @@
+package pantry
+
+import (
+	"container/list"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+)
+
+type EvictionPolicy int
+
+const (
+	// PolicyLRU evicts the least recently used entry.
+	PolicyLRU EvictionPolicy = iota
+	// PolicyLFU evicts the least frequently used entry, the least recently
+	// used among equally frequent ones. The entry added last is never chosen,
+	// so new entries get a chance to be read.
+	PolicyLFU
+	// PolicyClock approximates LRU with a reference bit per entry, which is
+	// cheaper to maintain on reads.
+	PolicyClock
+	// PolicyRandom evicts a random entry, the cheapest to maintain.
+	PolicyRandom
+)
+
+// policy decides which key to evict once a bounded pantry is full. It is
+// guarded by the pantry's policy mutex, always taken after a shard lock,
+// except for the access step of a lockFreeAccess policy. victim is only asked for when the pantry is over capacity and must keep
+// returning the same key until it is removed.
+type policy interface {
+	add(key string)
+	access(key string)
+	remove(key string)
+	victim() (string, bool)
+	len() int
+	reset()
+}
+
+// lockFreeAccess is implemented by policies whose access step is safe
+// without the policy mutex, so reads of different shards do not serialize on
+// it.
+type lockFreeAccess interface {
+	accessLockFree(key string)
+}
+
+func newPolicy(kind EvictionPolicy) policy {
+	switch kind {
+	case PolicyLFU:
+		return newLFUPolicy()
+	case PolicyClock:
+		return newClockPolicy()
+	case PolicyRandom:
+		return newRandomPolicy()
+	default:
+		return newLRUPolicy()
+	}
+}
+
+type lruPolicy struct {
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newLRUPolicy() *lruPolicy {
+	return &lruPolicy{
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+func (lru *lruPolicy) add(key string) {
+	if element, found := lru.elements[key]; found {
+		lru.order.MoveToFront(element)
+		return
+	}
+	lru.elements[key] = lru.order.PushFront(key)
+}
+
+func (lru *lruPolicy) access(key string) {
+	if element, found := lru.elements[key]; found {
+		lru.order.MoveToFront(element)
+	}
+}
+
+func (lru *lruPolicy) remove(key string) {
+	if element, found := lru.elements[key]; found {
+		lru.order.Remove(element)
+		delete(lru.elements, key)
+	}
+}
+
+func (lru *lruPolicy) victim() (string, bool) {
+	element := lru.order.Back()
+	if element == nil {
+		return "", false
+	}
+	return element.Value.(string), true
+}
+
+func (lru *lruPolicy) len() int {
+	return len(lru.elements)
+}
+
+func (lru *lruPolicy) reset() {
+	lru.order.Init()
+	lru.elements = make(map[string]*list.Element)
+}
+
+// keys yields the tracked keys from the most to the least recently used.
+func (lru *lruPolicy) keys(yield func(string) bool) {
+	for element := lru.order.Front(); element != nil; element = element.Next() {
+		if !yield(element.Value.(string)) {
+			return
+		}
+	}
+}
+
+// lfuPolicy keeps the frequencies in ascending order, each with its keys from
+// the most to the least recently used.
+type lfuPolicy struct {
+	frequencies *list.List
+	entries     map[string]*lfuEntry
+	newest      string
+}
+
+type lfuFrequency struct {
+	count int
+	keys  *list.List
+}
+
+type lfuEntry struct {
+	frequency *list.Element
+	element   *list.Element
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{
+		frequencies: list.New(),
+		entries:     make(map[string]*lfuEntry),
+	}
+}
+
+func (lfu *lfuPolicy) add(key string) {
+	lfu.newest = key
+	if _, found := lfu.entries[key]; found {
+		lfu.access(key)
+		return
+	}
+
+	first := lfu.frequencies.Front()
+	if first == nil || first.Value.(*lfuFrequency).count != 1 {
+		first = lfu.frequencies.PushFront(&lfuFrequency{count: 1, keys: list.New()})
+	}
+	lfu.entries[key] = &lfuEntry{
+		frequency: first,
+		element:   first.Value.(*lfuFrequency).keys.PushFront(key),
+	}
+}
+
+func (lfu *lfuPolicy) access(key string) {
+	entry, found := lfu.entries[key]
+	if !found {
+		return
+	}
+
+	current := entry.frequency.Value.(*lfuFrequency)
+	next := entry.frequency.Next()
+	if next == nil || next.Value.(*lfuFrequency).count != current.count+1 {
+		next = lfu.frequencies.InsertAfter(&lfuFrequency{count: current.count + 1, keys: list.New()}, entry.frequency)
+	}
+
+	current.keys.Remove(entry.element)
+	if current.keys.Len() == 0 {
+		lfu.frequencies.Remove(entry.frequency)
+	}
+	entry.frequency = next
+	entry.element = next.Value.(*lfuFrequency).keys.PushFront(key)
+}
+
+func (lfu *lfuPolicy) remove(key string) {
+	entry, found := lfu.entries[key]
+	if !found {
+		return
+	}
+
+	frequency := entry.frequency.Value.(*lfuFrequency)
+	frequency.keys.Remove(entry.element)
+	if frequency.keys.Len() == 0 {
+		lfu.frequencies.Remove(entry.frequency)
+	}
+	delete(lfu.entries, key)
+}
+
+func (lfu *lfuPolicy) victim() (string, bool) {
+	for frequency := lfu.frequencies.Front(); frequency != nil; frequency = frequency.Next() {
+		for element := frequency.Value.(*lfuFrequency).keys.Back(); element != nil; element = element.Prev() {
+			if key := element.Value.(string); key != lfu.newest || len(lfu.entries) == 1 {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (lfu *lfuPolicy) len() int {
+	return len(lfu.entries)
+}
+
+func (lfu *lfuPolicy) reset() {
+	lfu.frequencies.Init()
+	lfu.entries = make(map[string]*lfuEntry)
+	lfu.newest = ""
+}
+
+type clockSlot struct {
+	key        string
+	index      int
+	referenced atomic.Bool
+}
+
+// clockPolicy gives every entry a second chance: the hand clears the
+// reference bits of recently used entries and stops at the first one without.
+// The slots are found through a sync.Map and their bits are atomic, so reads
+// set them without the policy mutex.
+type clockPolicy struct {
+	slots   []*clockSlot
+	indexes sync.Map
+	hand    int
+}
+
+func newClockPolicy() *clockPolicy {
+	return &clockPolicy{}
+}
+
+func (clock *clockPolicy) add(key string) {
+	if _, found := clock.indexes.Load(key); found {
+		clock.access(key)
+		return
+	}
+	slot := &clockSlot{key: key, index: len(clock.slots)}
+	clock.indexes.Store(key, slot)
+	clock.slots = append(clock.slots, slot)
+}
+
+func (clock *clockPolicy) access(key string) {
+	// Reading the bit first avoids writing to a shared cache line on every
+	// read of an entry that is already referenced.
+	if slot, found := clock.indexes.Load(key); found && !slot.(*clockSlot).referenced.Load() {
+		slot.(*clockSlot).referenced.Store(true)
+	}
+}
+
+func (clock *clockPolicy) accessLockFree(key string) {
+	clock.access(key)
+}
+
+// remove moves the last slot into the freed one.
+func (clock *clockPolicy) remove(key string) {
+	loaded, found := clock.indexes.LoadAndDelete(key)
+	if !found {
+		return
+	}
+
+	index := loaded.(*clockSlot).index
+	last := len(clock.slots) - 1
+	if index != last {
+		clock.slots[index] = clock.slots[last]
+		clock.slots[index].index = index
+	}
+	clock.slots[last] = nil
+	clock.slots = clock.slots[:last]
+
+	if clock.hand >= len(clock.slots) {
+		clock.hand = 0
+	}
+}
+
+func (clock *clockPolicy) victim() (string, bool) {
+	if len(clock.slots) == 0 {
+		return "", false
+	}
+
+	for clock.slots[clock.hand].referenced.Swap(false) {
+		clock.hand = (clock.hand + 1) % len(clock.slots)
+	}
+	return clock.slots[clock.hand].key, true
+}
+
+func (clock *clockPolicy) len() int {
+	return len(clock.slots)
+}
+
+func (clock *clockPolicy) reset() {
+	clock.slots = nil
+	clock.indexes.Clear()
+	clock.hand = 0
+}
+
+// randomPolicy keeps the chosen victim until it is removed.
+type randomPolicy struct {
+	keys      []string
+	indexes   map[string]int
+	candidate string
+	chosen    bool
+}
+
+func newRandomPolicy() *randomPolicy {
+	return &randomPolicy{indexes: make(map[string]int)}
+}
+
+func (random *randomPolicy) add(key string) {
+	if _, found := random.indexes[key]; found {
+		return
+	}
+	random.indexes[key] = len(random.keys)
+	random.keys = append(random.keys, key)
+}
+
+func (random *randomPolicy) access(string) {}
+
+func (random *randomPolicy) accessLockFree(string) {}
+
+func (random *randomPolicy) remove(key string) {
+	index, found := random.indexes[key]
+	if !found {
+		return
+	}
+
+	last := len(random.keys) - 1
+	if index != last {
+		random.keys[index] = random.keys[last]
+		random.indexes[random.keys[index]] = index
+	}
+	random.keys = random.keys[:last]
+	delete(random.indexes, key)
+
+	if random.chosen && key == random.candidate {
+		random.chosen = false
+	}
+}
+
+func (random *randomPolicy) victim() (string, bool) {
+	if len(random.keys) == 0 {
+		return "", false
+	}
+	if !random.chosen {
+		random.candidate = random.keys[rand.IntN(len(random.keys))]
+		random.chosen = true
+	}
+	return random.candidate, true
+}
+
+func (random *randomPolicy) len() int {
+	return len(random.keys)
+}
+
+func (random *randomPolicy) reset() {
+	random.keys = nil
+	random.indexes = make(map[string]int)
+	random.chosen = false
+}
+
+func (pantry *Pantry[T]) recentlyUsedFrom(lru *lruPolicy, n int) []Entry[T] {
+	pantry.rlockAll()
+	defer pantry.runlockAll()
+
+	pantry.policyMutex.Lock()
+	defer pantry.policyMutex.Unlock()
+
+	now := pantry.clock.Now().UnixNano()
+	var entries []Entry[T]
+	for key := range lru.keys {
+		item, found := pantry.shardFor(key).store[key]
+		if !found || pantry.isExpired(key, item, now) {
+			continue
+		}
+
+		entries = append(entries, pantry.entry(key, item))
+
+		if len(entries) == n {
+			break
+		}
+	}
+	return entries
+}
+
+// track must be called with the shard's write lock held.
+func (pantry *Pantry[T]) track(key string) {
+	if pantry.policy == nil {
+		return
+	}
+
+	pantry.policyMutex.Lock()
+	defer pantry.policyMutex.Unlock()
+
+	pantry.policy.add(key)
+}
+
+func (pantry *Pantry[T]) touch(key string) {
+	if pantry.policy == nil {
+		return
+	}
+
+	if policy, ok := pantry.policy.(lockFreeAccess); ok {
+		policy.accessLockFree(key)
+		return
+	}
+
+	pantry.policyMutex.Lock()
+	defer pantry.policyMutex.Unlock()
+
+	pantry.policy.access(key)
+}
+
+// drop must be called with the shard's write lock held.
+func (pantry *Pantry[T]) drop(shard *shard[T], key string) {
+	pantry.unstore(shard, key)
+
+	if pantry.policy == nil {
+		return
+	}
+
+	pantry.policyMutex.Lock()
+	defer pantry.policyMutex.Unlock()
+
+	pantry.policy.remove(key)
+}
+
+// unstore removes key from the shard and the indexes kept next to it,
+// leaving the policy alone. It must be called with the shard's write lock held.
+func (pantry *Pantry[T]) unstore(shard *shard[T], key string) {
+	if item, found := shard.store[key]; found {
+		shard.untag(key, item.tags)
+		pantry.cost.Add(-item.cost)
+		pantry.checksum.Add(-item.fingerprint)
+		shard.filterStale++
+	}
+	delete(shard.store, key)
+	shard.changed()
+	pantry.logRemove(key)
+}
+
+// resetPolicy must be called with all shards locked.
+func (pantry *Pantry[T]) resetPolicy() {
+	if pantry.policy == nil {
+		return
+	}
+
+	pantry.policyMutex.Lock()
+	defer pantry.policyMutex.Unlock()
+
+	pantry.policy.reset()
+}
+
+// overCapacity must be called with the policy mutex held.
+func (pantry *Pantry[T]) overCapacity() bool {
+	if pantry.maxItems > 0 && pantry.policy.len() > pantry.maxItems {
+		return true
+	}
+	return pantry.maxCost > 0 && pantry.cost.Load() > pantry.maxCost
+}
+
+// enforceCapacity evicts entries chosen by the policy until the pantry fits
+// into its maximum item count and cost. It must be called without holding
+// any lock.
+func (pantry *Pantry[T]) enforceCapacity() {
+	if pantry.policy == nil {
+		return
+	}
+
+	for {
+		pantry.policyMutex.Lock()
+		if !pantry.overCapacity() {
+			pantry.policyMutex.Unlock()
+			return
+		}
+		key, found := pantry.policy.victim()
+		pantry.policyMutex.Unlock()
+
+		if !found {
+			return
+		}
+
+		pantry.evictVictim(key)
+	}
+}
+
+func (pantry *Pantry[T]) evictVictim(key string) {
+	shard := pantry.shardFor(key)
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	pantry.policyMutex.Lock()
+	if !pantry.overCapacity() {
+		pantry.policyMutex.Unlock()
+		return
+	}
+	if victim, found := pantry.policy.victim(); !found || victim != key {
+		pantry.policyMutex.Unlock()
+		return
+	}
+	pantry.policy.remove(key)
+	pantry.policyMutex.Unlock()
+
+	if item, found := shard.store[key]; found {
+		pantry.unstore(shard, key)
+		evictions = pantry.evict(evictions, key, item, pantry.clock.Now().UnixNano(), Evicted)
+	}
+}
@@ -0,0 +1,219 @@
+package pantry
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxItemsLRU(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithMaxItems[int](2))
+
+	evicted := make(map[string]EvictionReason)
+	p.OnEvict(func(key string, value int, reason EvictionReason) {
+		evicted[key] = reason
+	})
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Get("first")
+	p.Set("third", 3)
+
+	if _, found := p.Get("second"); found {
+		t.Fatal("least recently used not evicted")
+	}
+
+	if _, found := p.Get("first"); !found {
+		t.Fatal("recently used evicted")
+	}
+
+	if _, found := p.Get("third"); !found {
+		t.Fatal("new entry evicted")
+	}
+
+	if reason, found := evicted["second"]; !found || reason != Evicted {
+		t.Log(evicted)
+		t.Fatal("eviction not reported")
+	}
+}
+
+func TestMaxItemsBound(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithMaxItems[int](10))
+
+	for i := 0; i < 100; i++ {
+		p.Set(strconv.Itoa(i), i)
+	}
+
+	counter := 0
+	for range p.All() {
+		counter++
+	}
+
+	if counter != 10 {
+		t.Fatal("not 10 items")
+	}
+
+	for i := 90; i < 100; i++ {
+		if _, found := p.Get(strconv.Itoa(i)); !found {
+			t.Fatal("newest evicted")
+		}
+	}
+}
+
+func TestMaxItemsRemove(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithMaxItems[int](2))
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Remove("first")
+	p.Set("third", 3)
+
+	if _, found := p.Get("second"); !found {
+		t.Fatal("evicted despite free slot")
+	}
+}
+
+func TestRecentlyUsedLRU(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithMaxItems[int](10))
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Set("third", 3)
+	p.Get("first")
+
+	entries := p.RecentlyUsed(2)
+	if len(entries) != 2 || entries[0].Key != "first" || entries[1].Key != "third" {
+		t.Log(entries)
+		t.Fatal("wrong order")
+	}
+}
+
+func TestMaxCost(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithMaxCost(10, func(key string, value string) int64 {
+		return int64(len(value))
+	}))
+
+	p.Set("first", "aaaa")
+	p.Set("second", "bbbb")
+	p.Get("first")
+
+	if cost := p.Stats().Cost; cost != 8 {
+		t.Fatal("unexpected cost", cost)
+	}
+
+	p.Set("third", "cccc")
+
+	if _, found := p.Get("second"); found {
+		t.Fatal("least recently used not evicted")
+	}
+
+	if _, found := p.Get("first"); !found {
+		t.Fatal("recently used evicted")
+	}
+
+	p.Set("first", "a")
+	p.Remove("third")
+
+	if cost := p.Stats().Cost; cost != 1 {
+		t.Fatal("cost not tracked", cost)
+	}
+
+	p.Clear()
+
+	if cost := p.Stats().Cost; cost != 0 {
+		t.Fatal("cost not reset", cost)
+	}
+}
+
+func TestMaxItemsLFU(t *testing.T) {
+	p := New(context.Background(), time.Hour,
+		WithMaxItems[int](2),
+		WithEvictionPolicy[int](PolicyLFU),
+	)
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Get("first")
+	p.Get("first")
+	p.Get("second")
+	p.Set("third", 3)
+
+	if _, found := p.Get("second"); found {
+		t.Fatal("least frequently used not evicted")
+	}
+
+	if _, found := p.Get("first"); !found {
+		t.Fatal("frequently used evicted")
+	}
+
+	if _, found := p.Get("third"); !found {
+		t.Fatal("new entry evicted")
+	}
+}
+
+func TestMaxItemsClock(t *testing.T) {
+	p := New(context.Background(), time.Hour,
+		WithMaxItems[int](2),
+		WithEvictionPolicy[int](PolicyClock),
+	)
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Get("first")
+	p.Set("third", 3)
+
+	if _, found := p.Get("second"); found {
+		t.Fatal("unreferenced entry not evicted")
+	}
+
+	if _, found := p.Get("first"); !found {
+		t.Fatal("referenced entry evicted")
+	}
+}
+
+func TestPoliciesBound(t *testing.T) {
+	for _, policy := range []EvictionPolicy{PolicyLRU, PolicyLFU, PolicyClock, PolicyRandom} {
+		p := New(context.Background(), time.Hour,
+			WithMaxItems[int](10),
+			WithEvictionPolicy[int](policy),
+		)
+
+		for i := 0; i < 100; i++ {
+			p.Set(strconv.Itoa(i), i)
+			p.Get(strconv.Itoa(i % 7))
+		}
+
+		if count := p.Count(); count != 10 {
+			t.Fatal("policy", policy, "kept", count)
+		}
+
+		p.Clear()
+		p.Set("after clear", 1)
+
+		if count := p.Count(); count != 1 {
+			t.Fatal("policy", policy, "kept after clear", count)
+		}
+	}
+}
+
+func TestMaxCostTransaction(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithMaxCost(100, func(key string, value string) int64 {
+		return int64(len(value))
+	}))
+	defer p.Close()
+
+	p.Set("small", "a")
+	p.Set("large", "b")
+	p.Transaction(func(store map[string]string) {
+		store["large"] = strings.Repeat("b", 500)
+	})
+
+	if cost := p.Stats().Cost; cost > 100 {
+		t.Fatal("cost over the limit after a transaction", cost)
+	}
+	if p.Contains("large") {
+		t.Fatal("oversized entry not evicted")
+	}
+}
@@ -0,0 +1,120 @@
+package pantry
+
+import (
+	"cmp"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"slices"
+)
+
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// WithMemoryPressure makes the cleanup evict fraction of the live entries
+// whenever the heap holds more than threshold bytes of objects, or 90% of
+// GOMEMLIMIT if threshold is zero. Entries are evicted least recently used
+// first with WithAccessTracking and oldest written first otherwise. The
+// fraction must be in (0, 1].
+func WithMemoryPressure[T any](threshold uint64, fraction float64) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if fraction <= 0 || fraction > 1 {
+			pantry.invalidOption("memory pressure fraction %v must be in (0, 1]", fraction)
+			return
+		}
+		pantry.pressureThreshold = threshold
+		pantry.pressureFraction = fraction
+		pantry.heapSize = heapSize
+	}
+}
+
+func heapSize() uint64 {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// pressureLimit returns the heap size above which entries are evicted.
+func (pantry *Pantry[T]) pressureLimit() uint64 {
+	if pantry.pressureThreshold > 0 {
+		return pantry.pressureThreshold
+	}
+
+	limit := debug.SetMemoryLimit(-1)
+	if limit == math.MaxInt64 {
+		return math.MaxUint64
+	}
+	return uint64(float64(limit) * 0.9)
+}
+
+// relieveMemoryPressure evicts the configured fraction of the entries if the
+// heap is over the limit and returns how many it evicted.
+func (pantry *Pantry[T]) relieveMemoryPressure() int {
+	if pantry.heapSize == nil {
+		return 0
+	}
+
+	heap := pantry.heapSize()
+	if heap <= pantry.pressureLimit() {
+		return 0
+	}
+
+	type candidate struct {
+		key      string
+		accessed uint64
+		written  int64
+	}
+
+	pantry.rlockAll()
+	now := pantry.clock.Now().UnixNano()
+	var candidates []candidate
+	for key, item := range pantry.items() {
+		if !pantry.isExpired(key, item, now) {
+			candidates = append(candidates, candidate{key: key, accessed: item.accessed, written: item.written})
+		}
+	}
+	pantry.runlockAll()
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if c := cmp.Compare(a.accessed, b.accessed); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.written, b.written)
+	})
+
+	n := int(math.Ceil(float64(len(candidates)) * pantry.pressureFraction))
+	evicted := 0
+	for _, candidate := range candidates[:n] {
+		if pantry.evictUnchanged(candidate.key, candidate.accessed, candidate.written) {
+			evicted++
+		}
+	}
+
+	if pantry.logger != nil {
+		pantry.logger.Info("pantry: memory pressure", "heap", heap, "evicted", evicted)
+	}
+	return evicted
+}
+
+// evictUnchanged evicts the entry unless it was written or read since the
+// candidates were collected.
+func (pantry *Pantry[T]) evictUnchanged(key string, accessed uint64, written int64) bool {
+	shard := pantry.shardFor(key)
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	item, found := shard.store[key]
+	if !found || item.accessed != accessed || item.written != written {
+		return false
+	}
+
+	pantry.drop(shard, key)
+	evictions = pantry.evict(evictions, key, item, pantry.clock.Now().UnixNano(), Evicted)
+	return true
+}
@@ -0,0 +1,52 @@
+package pantry
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryPressure(t *testing.T) {
+	p := New(context.Background(), time.Hour,
+		WithMemoryPressure[int](1000, 0.5),
+		WithAccessTracking[int](),
+		WithoutBackgroundCleanup[int](),
+	)
+	defer p.Close()
+
+	heap := uint64(0)
+	p.heapSize = func() uint64 { return heap }
+
+	for i := range 10 {
+		p.Set(strconv.Itoa(i), i)
+	}
+	for i := range 5 {
+		p.Get(strconv.Itoa(i))
+	}
+
+	p.PurgeExpired()
+	if p.Count() != 10 {
+		t.Fatal("evicted below the threshold", p.Count())
+	}
+
+	heap = 2000
+	p.PurgeExpired()
+	if p.Count() != 5 {
+		t.Fatal("unexpected count", p.Count())
+	}
+	for i := range 5 {
+		if !p.Contains(strconv.Itoa(i)) {
+			t.Fatal("recently read entry evicted", i)
+		}
+	}
+	if stats := p.Stats(); stats.Evictions != 5 {
+		t.Fatal("evictions not counted", stats.Evictions)
+	}
+}
+
+func TestHeapSize(t *testing.T) {
+	if heapSize() == 0 {
+		t.Fatal("heap size not read")
+	}
+}
@@ -0,0 +1,52 @@
+// Package ratelimit limits requests per key in fixed windows kept in a pantry,
+// so idle keys expire on their own. It binds a limit and a window to the
+// pantry's RateLimiter.
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+// Limiter allows up to limit requests per key in each window. Windows are
+// aligned to the clock and a key's counter expires when its window ends.
+type Limiter struct {
+	limit   int
+	window  time.Duration
+	limiter *pantry.RateLimiter
+}
+
+// New creates a limiter. Options are passed to the underlying pantry, e.g. to
+// inject a clock.
+func New(ctx context.Context, limit int, window time.Duration, opts ...pantry.Option[int]) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  window,
+		limiter: pantry.NewRateLimiter(ctx, opts...),
+	}
+}
+
+// Allow reports whether another request for key fits into the current window,
+// counting it if it does.
+func (limiter *Limiter) Allow(key string) bool {
+	return limiter.AllowN(key, 1)
+}
+
+// AllowN reports whether n more requests for key fit into the current window,
+// counting all of them if they do and none otherwise.
+func (limiter *Limiter) AllowN(key string, n int) bool {
+	return limiter.limiter.AllowN(key, n, limiter.limit, limiter.window)
+}
+
+// Remaining returns how many requests key may still make in the current
+// window.
+func (limiter *Limiter) Remaining(key string) int {
+	return limiter.limiter.Remaining(key, limiter.limit, limiter.window)
+}
+
+// Reset forgets the current window of key.
+func (limiter *Limiter) Reset(key string) {
+	limiter.limiter.Reset(key, limiter.window)
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+	"github.com/webermarci/pantry/pantrytest"
+)
+
+func TestAllowBurst(t *testing.T) {
+	limiter := New(context.Background(), 10, time.Hour)
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.Allow("user") {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() != 10 {
+		t.Fatal("not 10 allowed", allowed.Load())
+	}
+}
+
+func TestAllowN(t *testing.T) {
+	limiter := New(context.Background(), 5, time.Hour)
+
+	if !limiter.AllowN("user", 3) {
+		t.Fatal("not allowed")
+	}
+
+	if limiter.AllowN("user", 3) {
+		t.Fatal("allowed over the limit")
+	}
+
+	if remaining := limiter.Remaining("user"); remaining != 2 {
+		t.Fatal("unexpected remaining", remaining)
+	}
+
+	if !limiter.AllowN("other", 5) {
+		t.Fatal("keys share a window")
+	}
+}
+
+func TestWindowExpires(t *testing.T) {
+	// Windows are aligned to the clock, so start at the beginning of one.
+	clock := pantrytest.NewClock(time.Now().Truncate(time.Minute))
+	limiter := New(context.Background(), 1, time.Minute, pantry.WithClock[int](clock))
+
+	if !limiter.Allow("user") {
+		t.Fatal("not allowed")
+	}
+
+	clock.Advance(30 * time.Second)
+
+	if limiter.Allow("user") {
+		t.Fatal("allowed in the same window")
+	}
+
+	clock.Advance(31 * time.Second)
+
+	if !limiter.Allow("user") {
+		t.Fatal("window not reset")
+	}
+}
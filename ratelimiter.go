@@ -0,0 +1,63 @@
+package pantry
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// RateLimiter counts requests per key in fixed windows aligned to the clock,
+// each window counter expiring together with the window.
+type RateLimiter struct {
+	counters *Pantry[int]
+}
+
+// NewRateLimiter creates a rate limiter. The options configure the pantry
+// holding the counters, for example to inject a clock.
+func NewRateLimiter(ctx context.Context, options ...Option[int]) *RateLimiter {
+	return &RateLimiter{
+		counters: New(ctx, time.Minute, options...),
+	}
+}
+
+// Allow reports whether another request for key fits into the limit of the
+// current window, counting it if it does.
+func (limiter *RateLimiter) Allow(key string, limit int, window time.Duration) bool {
+	return limiter.AllowN(key, 1, limit, window)
+}
+
+// AllowN reports whether n more requests for key fit into the limit of the
+// current window, counting all of them if they do and none otherwise.
+func (limiter *RateLimiter) AllowN(key string, n, limit int, window time.Duration) bool {
+	now := limiter.counters.clock.Now()
+	start := now.Truncate(window)
+
+	allowed := false
+	limiter.counters.UpdateWithExpiry(rateBucket(key, start), func(count int, _ time.Duration, _ bool) (int, time.Duration, bool) {
+		if count+n > limit {
+			return count, 0, false
+		}
+
+		allowed = true
+		return count + n, start.Add(window).Sub(now), true
+	})
+	return allowed
+}
+
+// Remaining returns how many requests key may still make within limit in the
+// current window.
+func (limiter *RateLimiter) Remaining(key string, limit int, window time.Duration) int {
+	start := limiter.counters.clock.Now().Truncate(window)
+	count, _ := limiter.counters.Get(rateBucket(key, start))
+	return max(limit-count, 0)
+}
+
+// Reset forgets the requests key made in the current window.
+func (limiter *RateLimiter) Reset(key string, window time.Duration) {
+	start := limiter.counters.clock.Now().Truncate(window)
+	limiter.counters.Remove(rateBucket(key, start))
+}
+
+func rateBucket(key string, start time.Time) string {
+	return key + ":" + strconv.FormatInt(start.UnixNano(), 10)
+}
@@ -0,0 +1,71 @@
+package pantry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurst(t *testing.T) {
+	limiter := NewRateLimiter(context.Background())
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.Allow("user", 10, time.Hour) {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() != 10 {
+		t.Fatal("not 10 allowed")
+	}
+}
+
+func TestRateLimiterReset(t *testing.T) {
+	limiter := NewRateLimiter(context.Background())
+
+	for limiter.Allow("user", 1, 50*time.Millisecond) {
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if !limiter.Allow("user", 1, 50*time.Millisecond) {
+		t.Fatal("not reset")
+	}
+}
+
+func TestRateLimiterAllowN(t *testing.T) {
+	clock := &testClock{}
+	clock.now.Store(time.Now().Truncate(time.Minute).UnixNano())
+	limiter := NewRateLimiter(context.Background(), WithClock[int](clock))
+
+	if !limiter.AllowN("user", 3, 5, time.Minute) {
+		t.Fatal("not allowed")
+	}
+	if limiter.AllowN("user", 3, 5, time.Minute) {
+		t.Fatal("allowed over the limit")
+	}
+	if remaining := limiter.Remaining("user", 5, time.Minute); remaining != 2 {
+		t.Fatal("unexpected remaining", remaining)
+	}
+
+	limiter.Reset("user", time.Minute)
+	if remaining := limiter.Remaining("user", 5, time.Minute); remaining != 5 {
+		t.Fatal("not reset", remaining)
+	}
+
+	limiter.AllowN("user", 5, 5, time.Minute)
+	clock.advance(time.Minute)
+	if !limiter.Allow("user", 5, time.Minute) {
+		t.Fatal("next window not allowed")
+	}
+}
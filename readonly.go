@@ -0,0 +1,76 @@
+package pantry
+
+import (
+	"iter"
+	"time"
+)
+
+// ReadOnlyPantry is a view of a pantry without any method that changes it.
+// Values are shared with the pantry unless it has a cloner, see WithCloner.
+type ReadOnlyPantry[T any] struct {
+	pantry *Pantry[T]
+}
+
+func (pantry *Pantry[T]) ReadOnly() ReadOnlyPantry[T] {
+	return ReadOnlyPantry[T]{pantry: pantry}
+}
+
+// Contains reports whether key has a live entry, without loading it or
+// counting as an access.
+func (pantry *Pantry[T]) Contains(key string) bool {
+	key = pantry.normalize(key)
+	item, found := pantry.lookup(key)
+	return found && !pantry.isExpired(key, item, pantry.clock.Now().UnixNano())
+}
+
+func (view ReadOnlyPantry[T]) Get(key string) (T, bool) {
+	return view.pantry.Get(key)
+}
+
+func (view ReadOnlyPantry[T]) GetE(key string) (T, error) {
+	return view.pantry.GetE(key)
+}
+
+func (view ReadOnlyPantry[T]) GetWithExpiration(key string) (T, time.Time, bool) {
+	return view.pantry.GetWithExpiration(key)
+}
+
+func (view ReadOnlyPantry[T]) TTL(key string) (time.Duration, bool) {
+	return view.pantry.TTL(key)
+}
+
+func (view ReadOnlyPantry[T]) Contains(key string) bool {
+	return view.pantry.Contains(key)
+}
+
+func (view ReadOnlyPantry[T]) Count() int {
+	return view.pantry.Count()
+}
+
+func (view ReadOnlyPantry[T]) IsEmpty() bool {
+	return view.pantry.IsEmpty()
+}
+
+func (view ReadOnlyPantry[T]) Keys() iter.Seq[string] {
+	return view.pantry.Keys()
+}
+
+func (view ReadOnlyPantry[T]) SortedKeys() iter.Seq[string] {
+	return view.pantry.SortedKeys()
+}
+
+func (view ReadOnlyPantry[T]) Values() iter.Seq[T] {
+	return view.pantry.Values()
+}
+
+func (view ReadOnlyPantry[T]) All() iter.Seq2[string, T] {
+	return view.pantry.All()
+}
+
+func (view ReadOnlyPantry[T]) Entries() iter.Seq2[string, Entry[T]] {
+	return view.pantry.Entries()
+}
+
+func (view ReadOnlyPantry[T]) Filter(pred func(key string, value T) bool) iter.Seq2[string, T] {
+	return view.pantry.Filter(pred)
+}
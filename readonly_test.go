@@ -0,0 +1,44 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReadOnly(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	view := p.ReadOnly()
+
+	p.Set("first", 1)
+
+	if value, found := view.Get("first"); !found || value != 1 {
+		t.Fatal("not visible through the view")
+	}
+	if !view.Contains("first") || view.Contains("second") {
+		t.Fatal("unexpected contains")
+	}
+	if view.Count() != 1 {
+		t.Fatal("unexpected count", view.Count())
+	}
+
+	p.Set("second", 2)
+	sum := 0
+	for _, value := range view.All() {
+		sum += value
+	}
+	if sum != 3 {
+		t.Fatal("unexpected sum", sum)
+	}
+}
+
+func TestContainsExpired(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.SetWithTTL("short", 1, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+
+	if p.Contains("short") {
+		t.Fatal("expired entry contained")
+	}
+}
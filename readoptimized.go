@@ -0,0 +1,68 @@
+package pantry
+
+// minSnapshotReads is the least number of locked reads a shard serves before
+// publishing a snapshot, so tiny shards are not copied on every other read.
+const minSnapshotReads = 64
+
+// WithReadOptimizedStore lets Get serve hits from an immutable copy of each
+// shard without taking its lock. A write drops the copy, and the shard
+// publishes a new one once it has served about as many locked reads as it
+// holds entries, so the copying is paid for by the reads it speeds up. Pays
+// off for read-mostly workloads; the copies take as much memory as the maps
+// themselves. Has no effect with access tracking or sliding expiration, where
+// every read writes.
+func WithReadOptimizedStore[T any]() Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.readOptimized = true
+	}
+}
+
+// readPublished looks up key in the published copy of the shard, reporting
+// only live entries.
+func (pantry *Pantry[T]) readPublished(shard *shard[T], key string) (item[T], bool) {
+	if !pantry.readOptimized || pantry.accessTracking || pantry.sliding {
+		return item[T]{}, false
+	}
+
+	snapshot := shard.snapshot.Load()
+	if snapshot == nil {
+		return item[T]{}, false
+	}
+
+	stored, found := (*snapshot)[key]
+	if !found || (!pantry.noLazyExpiry && pantry.isExpired(key, stored, pantry.clock.Now().UnixNano())) {
+		return item[T]{}, false
+	}
+
+	pantry.counters.hits.Add(1)
+	pantry.touch(key)
+	return stored, true
+}
+
+// publishSnapshot counts a locked read and copies the shard once enough of them were
+// served since the last write. It must be called with the shard's read lock
+// held, which keeps writers from changing the store while it is copied.
+func (pantry *Pantry[T]) publishSnapshot(shard *shard[T]) {
+	if !pantry.readOptimized || pantry.accessTracking || pantry.sliding {
+		return
+	}
+
+	if shard.snapshotReads.Add(1) != int64(max(len(shard.store), minSnapshotReads)) {
+		return
+	}
+
+	snapshot := make(map[string]item[T], len(shard.store))
+	for key, item := range shard.store {
+		snapshot[key] = item
+	}
+	shard.snapshot.Store(&snapshot)
+}
+
+// changed drops the published copy of the shard. It must be called with the
+// shard's write lock held whenever the store changes.
+func (shard *shard[T]) changed() {
+	if shard.snapshot.Load() != nil {
+		shard.snapshot.Store(nil)
+	}
+	shard.snapshotReads.Store(0)
+}
@@ -0,0 +1,44 @@
+package pantry
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReadOptimizedStore(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithReadOptimizedStore[int](), WithShards[int](1))
+	defer p.Close()
+
+	for i := range 10 {
+		p.Set("key:"+strconv.Itoa(i), i)
+	}
+	for range minSnapshotReads {
+		p.Get("key:0")
+	}
+	if p.shards[0].snapshot.Load() == nil {
+		t.Fatal("snapshot not published")
+	}
+
+	if value, found := p.Get("key:1"); !found || value != 1 {
+		t.Fatal("unexpected value", value, found)
+	}
+
+	p.Set("key:1", 100)
+	if p.shards[0].snapshot.Load() != nil {
+		t.Fatal("snapshot kept after write")
+	}
+	if value, _ := p.Get("key:1"); value != 100 {
+		t.Fatal("stale value", value)
+	}
+
+	p.Remove("key:2")
+	if _, found := p.Get("key:2"); found {
+		t.Fatal("found after remove")
+	}
+
+	if hits := p.Stats().Hits; hits != minSnapshotReads+2 {
+		t.Fatal("unexpected hits", hits)
+	}
+}
@@ -0,0 +1,127 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// registered is the part of a pantry the registry needs regardless of its
+// value type.
+type registered interface {
+	Stats() Stats
+	Close() error
+}
+
+// Registry keeps named pantries of any value type, cleans all of them up with
+// a single Sweeper and closes them together.
+type Registry struct {
+	ctx      context.Context
+	mutex    sync.Mutex
+	pantries map[string]registered
+	closed   bool
+	sweeper  *Sweeper
+}
+
+// NewRegistry creates a registry cleaning up its pantries every
+// cleanupInterval until ctx is done or Close is called. A cleanupInterval of
+// zero or less disables the cleanup.
+func NewRegistry(ctx context.Context, cleanupInterval time.Duration) *Registry {
+	registry := &Registry{
+		ctx:      ctx,
+		pantries: make(map[string]registered),
+	}
+	if cleanupInterval > 0 {
+		registry.sweeper = NewSweeper(ctx, cleanupInterval)
+	}
+	return registry
+}
+
+// Named returns the pantry registered under name, creating it with
+// expiration and options on first use. The pantry is cleaned up by the
+// registry instead of its own goroutine. Named panics if name is registered
+// with a different value type or the registry is closed.
+func Named[T any](registry *Registry, name string, expiration time.Duration, options ...Option[T]) *Pantry[T] {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if registry.closed {
+		panic("pantry: registry closed")
+	}
+
+	if existing, found := registry.pantries[name]; found {
+		pantry, ok := existing.(*Pantry[T])
+		if !ok {
+			panic(fmt.Sprintf("pantry: %q is registered as %T", name, existing))
+		}
+		return pantry
+	}
+
+	cleanup := WithoutBackgroundCleanup[T]()
+	if registry.sweeper != nil {
+		cleanup = WithSweeper[T](registry.sweeper)
+	}
+
+	options = append(options[:len(options):len(options)], cleanup)
+	pantry := New(registry.ctx, expiration, options...)
+	registry.pantries[name] = pantry
+	return pantry
+}
+
+// Stats returns the sum of the statistics of all pantries.
+// LastCleanupDuration is the longest among them and the capacity is left
+// unset.
+func (registry *Registry) Stats() Stats {
+	var total Stats
+	for _, pantry := range registry.snapshot() {
+		stats := pantry.Stats()
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Expirations += stats.Expirations
+		total.Evictions += stats.Evictions
+		total.Coalesced += stats.Coalesced
+		total.Items += stats.Items
+		total.Cost += stats.Cost
+		total.MemoryUsage += stats.MemoryUsage
+		total.LastCleanupDuration = max(total.LastCleanupDuration, stats.LastCleanupDuration)
+		total.Locks.add(stats.Locks)
+	}
+	return total
+}
+
+// Close stops the cleanup and closes every pantry, returning their errors
+// joined. Closing more than once is a no-op.
+func (registry *Registry) Close() error {
+	registry.mutex.Lock()
+	if registry.closed {
+		registry.mutex.Unlock()
+		return nil
+	}
+	registry.closed = true
+	registry.mutex.Unlock()
+
+	if registry.sweeper != nil {
+		registry.sweeper.Close()
+	}
+
+	var errs []error
+	for name, pantry := range registry.snapshot() {
+		if err := pantry.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("pantry: closing %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (registry *Registry) snapshot() map[string]registered {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	pantries := make(map[string]registered, len(registry.pantries))
+	for name, pantry := range registry.pantries {
+		pantries[name] = pantry
+	}
+	return pantries
+}
@@ -0,0 +1,57 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type registryUser struct {
+	Name string
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry(context.Background(), 10*time.Millisecond)
+
+	users := Named[registryUser](registry, "users", time.Hour)
+	counters := Named[int](registry, "counters", time.Hour)
+
+	if Named[registryUser](registry, "users", time.Minute) != users {
+		t.Fatal("registered pantry not returned")
+	}
+
+	users.Set("alice", registryUser{Name: "Alice"})
+	counters.SetWithTTL("requests", 1, time.Millisecond)
+	users.Get("alice")
+
+	eventually(t, func() bool { return counters.CountRaw() == 0 })
+
+	stats := registry.Stats()
+	if stats.Items != 1 || stats.Hits != 1 || stats.Expirations != 1 {
+		t.Fatal("unexpected stats", stats)
+	}
+
+	if err := registry.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if users.Set("bob", registryUser{}); users.Contains("bob") {
+		t.Fatal("pantry not closed")
+	}
+	if err := registry.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistryTypeMismatch(t *testing.T) {
+	registry := NewRegistry(context.Background(), 0)
+	defer registry.Close()
+
+	Named[int](registry, "counters", time.Hour)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic on type mismatch")
+		}
+	}()
+	Named[string](registry, "counters", time.Hour)
+}
@@ -0,0 +1,319 @@
+package pantry
+
+import (
+	"encoding/gob"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	replicationQueue = 1024
+	replicationDial  = time.Second
+	replicationRetry = time.Second
+)
+
+// replicationOp is a write or removal sent to the peers. Written is the time
+// of the change on the node it happened on, used to let the last write win.
+type replicationOp[T any] struct {
+	Key     string
+	Value   T
+	Expires int64
+	Written int64
+	Deleted bool
+}
+
+// replicationMessage carries either a change or, with gossip, the members
+// known to the sender.
+type replicationMessage[T any] struct {
+	Op      *replicationOp[T]
+	Members []string
+}
+
+type replica[T any] struct {
+	address  string
+	messages chan replicationMessage[T]
+}
+
+type replication[T any] struct {
+	mutex    sync.RWMutex
+	self     string
+	peers    []string
+	interval time.Duration
+	replicas map[string]*replica[T]
+}
+
+// WithReplication streams every explicit write and removal to the peers,
+// which apply them with ServeReplication. A peer receives all live entries
+// whenever a connection to it is established. Replication is best effort:
+// changes are dropped while a peer is unreachable or falling behind, and
+// expiration happens independently on every node. Clear, Extend and capacity
+// evictions are not replicated. Conflicting changes are resolved by the last
+// write according to the clocks of the nodes.
+func WithReplication[T any](peers []string) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.replication.peers = append(pantry.replication.peers, peers...)
+	}
+}
+
+// WithGossip replicates to the nodes discovered by exchanging member lists
+// with the seeds every interval. self is the address this node serves
+// replication on, as the other nodes reach it. Members are never forgotten,
+// unreachable ones are retried.
+func WithGossip[T any](self string, seeds []string, interval time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.replication.self = self
+		pantry.replication.interval = interval
+		pantry.replication.peers = append(pantry.replication.peers, seeds...)
+	}
+}
+
+// Members returns the addresses of the peers the pantry replicates to.
+func (pantry *Pantry[T]) Members() []string {
+	pantry.replication.mutex.RLock()
+	defer pantry.replication.mutex.RUnlock()
+
+	members := make([]string, 0, len(pantry.replication.replicas))
+	for address := range pantry.replication.replicas {
+		members = append(members, address)
+	}
+	slices.Sort(members)
+	return members
+}
+
+func (pantry *Pantry[T]) startReplication() {
+	for _, address := range pantry.replication.peers {
+		pantry.addReplica(address)
+	}
+
+	if pantry.replication.self == "" || pantry.replication.interval <= 0 {
+		return
+	}
+
+	ticker := pantry.clock.NewTicker(pantry.replication.interval)
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C():
+				members := append(pantry.Members(), pantry.replication.self)
+				pantry.broadcast(replicationMessage[T]{Members: members})
+			case <-pantry.done:
+				return
+			}
+		}
+	}()
+}
+
+func (pantry *Pantry[T]) addReplica(address string) {
+	pantry.replication.mutex.Lock()
+	defer pantry.replication.mutex.Unlock()
+
+	if address == pantry.replication.self || pantry.replication.replicas[address] != nil {
+		return
+	}
+
+	peer := &replica[T]{
+		address:  address,
+		messages: make(chan replicationMessage[T], replicationQueue),
+	}
+	if pantry.replication.replicas == nil {
+		pantry.replication.replicas = make(map[string]*replica[T])
+	}
+	pantry.replication.replicas[address] = peer
+
+	go pantry.replicateTo(peer)
+}
+
+// broadcast drops the message for replicas whose queue is full.
+func (pantry *Pantry[T]) broadcast(message replicationMessage[T]) {
+	pantry.replication.mutex.RLock()
+	defer pantry.replication.mutex.RUnlock()
+
+	for _, replica := range pantry.replication.replicas {
+		select {
+		case replica.messages <- message:
+		default:
+		}
+	}
+}
+
+// replicate must be called with the shard's write lock held, which keeps the
+// changes of a key in order.
+func (pantry *Pantry[T]) replicate(key string, value T, deleted bool) {
+	if len(pantry.replication.peers) == 0 && pantry.replication.self == "" {
+		return
+	}
+
+	op := replicationOp[T]{
+		Key:     key,
+		Written: pantry.clock.Now().UnixNano(),
+		Deleted: true,
+	}
+	if stored, found := pantry.shardFor(key).store[key]; found && !deleted {
+		op = replicationOp[T]{
+			Key:     key,
+			Value:   value,
+			Expires: stored.expires,
+			Written: stored.written,
+		}
+	}
+
+	pantry.broadcast(replicationMessage[T]{Op: &op})
+}
+
+// replicateTo sends the messages to one peer until the pantry is closed,
+// reconnecting after failures and dropping the messages meanwhile. Every new
+// connection starts with a transfer of the live entries.
+func (pantry *Pantry[T]) replicateTo(replica *replica[T]) {
+	var conn net.Conn
+	var encoder *gob.Encoder
+	var retry time.Time
+
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case message := <-replica.messages:
+			if conn == nil {
+				if time.Now().Before(retry) {
+					continue
+				}
+
+				var err error
+				if conn, err = net.DialTimeout("tcp", replica.address, replicationDial); err != nil {
+					retry = time.Now().Add(replicationRetry)
+					continue
+				}
+
+				encoder = gob.NewEncoder(conn)
+				if err := pantry.transferState(encoder); err != nil {
+					conn.Close()
+					conn = nil
+					continue
+				}
+			}
+
+			if err := encoder.Encode(message); err != nil {
+				conn.Close()
+				conn = nil
+			}
+
+		case <-pantry.done:
+			return
+		}
+	}
+}
+
+func (pantry *Pantry[T]) transferState(encoder *gob.Encoder) error {
+	pantry.rlockAll()
+	now := pantry.clock.Now().UnixNano()
+	var ops []replicationOp[T]
+	for key, item := range pantry.items() {
+		if pantry.isExpired(key, item, now) {
+			continue
+		}
+		ops = append(ops, replicationOp[T]{
+			Key:     key,
+			Value:   item.value,
+			Expires: item.expires,
+			Written: item.written,
+		})
+	}
+	pantry.runlockAll()
+
+	for i := range ops {
+		if err := encoder.Encode(replicationMessage[T]{Op: &ops[i]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeReplication accepts connections from peers configured with
+// WithReplication or WithGossip and applies their messages until listener is
+// closed.
+func (pantry *Pantry[T]) ServeReplication(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go pantry.serveReplica(conn)
+	}
+}
+
+func (pantry *Pantry[T]) serveReplica(conn net.Conn) {
+	defer conn.Close()
+
+	decoder := gob.NewDecoder(conn)
+	for {
+		var message replicationMessage[T]
+		if err := decoder.Decode(&message); err != nil {
+			return
+		}
+
+		if message.Op != nil {
+			pantry.applyReplicated(*message.Op)
+		}
+
+		if pantry.replication.self != "" && !pantry.closed.Load() {
+			for _, address := range message.Members {
+				pantry.addReplica(address)
+			}
+		}
+	}
+}
+
+// applyReplicated applies a change from a peer unless the local entry was
+// written later. Applied changes are not replicated again.
+func (pantry *Pantry[T]) applyReplicated(op replicationOp[T]) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	shard := pantry.shardFor(op.Key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now().UnixNano()
+	current, found := shard.store[op.Key]
+	if found && current.written > op.Written {
+		return
+	}
+
+	if op.Deleted {
+		if found {
+			pantry.drop(shard, op.Key)
+			evictions = pantry.evict(evictions, op.Key, current, now, Removed)
+		}
+		return
+	}
+
+	if now > op.Expires {
+		return
+	}
+
+	evictions = pantry.put(evictions, shard, op.Key, item[T]{
+		value:    op.Value,
+		expires:  op.Expires,
+		accessed: pantry.nextAccess(),
+		written:  op.Written,
+	}, now)
+}
@@ -0,0 +1,152 @@
+package pantry
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func newReplicatedPair(t *testing.T) (*Pantry[string], *Pantry[string]) {
+	t.Helper()
+
+	first, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		first.Close()
+		second.Close()
+	})
+
+	a := New(context.Background(), time.Hour, WithReplication[string]([]string{second.Addr().String()}))
+	b := New(context.Background(), time.Hour, WithReplication[string]([]string{first.Addr().String()}))
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+
+	go a.ServeReplication(first)
+	go b.ServeReplication(second)
+
+	return a, b
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReplication(t *testing.T) {
+	a, b := newReplicatedPair(t)
+
+	a.Set("key", "value")
+	eventually(t, func() bool {
+		value, found := b.Get("key")
+		return found && value == "value"
+	})
+
+	b.Remove("key")
+	eventually(t, func() bool {
+		_, found := a.Get("key")
+		return !found
+	})
+
+	if ttl, found := b.TTL("key"); found {
+		t.Fatal("removed key has ttl", ttl)
+	}
+}
+
+func TestReplicationTransaction(t *testing.T) {
+	a, b := newReplicatedPair(t)
+
+	a.Transaction(func(store map[string]string) {
+		store["key"] = "value"
+	})
+	eventually(t, func() bool {
+		value, found := b.Get("key")
+		return found && value == "value"
+	})
+}
+
+func TestReplicationLastWriteWins(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.Set("key", "local")
+
+	p.applyReplicated(replicationOp[string]{
+		Key:     "key",
+		Value:   "stale",
+		Expires: time.Now().Add(time.Hour).UnixNano(),
+		Written: time.Now().Add(-time.Minute).UnixNano(),
+	})
+
+	if value, _ := p.Get("key"); value != "local" {
+		t.Fatal("older write applied", value)
+	}
+
+	p.applyReplicated(replicationOp[string]{
+		Key:     "key",
+		Written: time.Now().Add(time.Minute).UnixNano(),
+		Deleted: true,
+	})
+
+	if _, found := p.Get("key"); found {
+		t.Fatal("newer removal ignored")
+	}
+}
+
+func TestGossip(t *testing.T) {
+	listen := func() net.Listener {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { listener.Close() })
+		return listener
+	}
+
+	start := func(listener net.Listener, seeds []string) *Pantry[string] {
+		p := New(context.Background(), time.Hour,
+			WithGossip[string](listener.Addr().String(), seeds, 5*time.Millisecond),
+		)
+		t.Cleanup(func() { p.Close() })
+		go p.ServeReplication(listener)
+		return p
+	}
+
+	first, second, third := listen(), listen(), listen()
+
+	a := start(first, nil)
+	a.Set("key", "value")
+
+	b := start(second, []string{first.Addr().String()})
+	c := start(third, []string{first.Addr().String()})
+
+	eventually(t, func() bool {
+		value, found := b.Get("key")
+		return found && value == "value"
+	})
+
+	eventually(t, func() bool {
+		return len(a.Members()) == 2 && len(b.Members()) == 2 && len(c.Members()) == 2
+	})
+
+	c.Set("other", "value")
+	eventually(t, func() bool {
+		_, found := b.Get("other")
+		return found
+	})
+}
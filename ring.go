@@ -0,0 +1,127 @@
+package pantry
+
+import (
+	"cmp"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+const defaultVirtualNodes = 128
+
+type ringPoint struct {
+	hash uint64
+	node string
+}
+
+// Ring assigns keys to nodes, e.g. the addresses of pantry servers, by
+// consistent hashing. Every node is placed on the ring several times so keys
+// spread evenly, and adding or removing a node only moves the keys it gains
+// or loses. The hash is stable across processes, so every client of the same
+// set of nodes agrees on the placement.
+type Ring struct {
+	mutex        sync.RWMutex
+	virtualNodes int
+	points       []ringPoint
+	nodes        map[string]struct{}
+}
+
+// NewRing creates a ring placing each node virtualNodes times, or 128 times if
+// virtualNodes is not positive.
+func NewRing(virtualNodes int, nodes ...string) *Ring {
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+
+	ring := &Ring{
+		virtualNodes: virtualNodes,
+		nodes:        make(map[string]struct{}),
+	}
+	ring.Add(nodes...)
+	return ring
+}
+
+func (ring *Ring) Add(nodes ...string) {
+	ring.mutex.Lock()
+	defer ring.mutex.Unlock()
+
+	for _, node := range nodes {
+		if _, found := ring.nodes[node]; found {
+			continue
+		}
+		ring.nodes[node] = struct{}{}
+
+		for i := range ring.virtualNodes {
+			ring.points = append(ring.points, ringPoint{
+				hash: ringHash(node + "#" + strconv.Itoa(i)),
+				node: node,
+			})
+		}
+	}
+
+	slices.SortFunc(ring.points, func(a, b ringPoint) int {
+		// Colliding points are ordered by node so every client agrees.
+		return cmp.Or(cmp.Compare(a.hash, b.hash), cmp.Compare(a.node, b.node))
+	})
+}
+
+func (ring *Ring) Remove(nodes ...string) {
+	ring.mutex.Lock()
+	defer ring.mutex.Unlock()
+
+	for _, node := range nodes {
+		delete(ring.nodes, node)
+	}
+
+	ring.points = slices.DeleteFunc(ring.points, func(point ringPoint) bool {
+		_, found := ring.nodes[point.node]
+		return !found
+	})
+}
+
+// Node returns the node responsible for key, false if the ring is empty.
+func (ring *Ring) Node(key string) (string, bool) {
+	ring.mutex.RLock()
+	defer ring.mutex.RUnlock()
+
+	if len(ring.points) == 0 {
+		return "", false
+	}
+
+	hash := ringHash(key)
+	i, _ := slices.BinarySearchFunc(ring.points, hash, func(point ringPoint, hash uint64) int {
+		return cmp.Compare(point.hash, hash)
+	})
+	if i == len(ring.points) {
+		i = 0
+	}
+	return ring.points[i].node, true
+}
+
+// Nodes returns the nodes on the ring in sorted order.
+func (ring *Ring) Nodes() []string {
+	ring.mutex.RLock()
+	defer ring.mutex.RUnlock()
+
+	nodes := make([]string, 0, len(ring.nodes))
+	for node := range ring.nodes {
+		nodes = append(nodes, node)
+	}
+	slices.Sort(nodes)
+	return nodes
+}
+
+// ringHash is FNV-1a followed by the splitmix64 finalizer, which spreads the
+// hashes of similar strings such as the virtual node names.
+func ringHash(s string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(s))
+	x := hash.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
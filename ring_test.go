@@ -0,0 +1,69 @@
+package pantry
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRingEmpty(t *testing.T) {
+	ring := NewRing(0)
+
+	if _, found := ring.Node("key"); found {
+		t.Fatal("node found on empty ring")
+	}
+}
+
+func TestRingDistribution(t *testing.T) {
+	ring := NewRing(0, "a:1", "b:1", "c:1")
+
+	counts := make(map[string]int)
+	for i := range 30000 {
+		node, _ := ring.Node("key" + strconv.Itoa(i))
+		counts[node]++
+	}
+
+	for node, count := range counts {
+		if count < 7000 || count > 13000 {
+			t.Fatal("uneven distribution", node, count)
+		}
+	}
+}
+
+func TestRingRebalance(t *testing.T) {
+	ring := NewRing(0, "a:1", "b:1", "c:1")
+
+	before := make(map[string]string)
+	for i := range 10000 {
+		key := "key" + strconv.Itoa(i)
+		before[key], _ = ring.Node(key)
+	}
+
+	ring.Add("d:1")
+
+	moved := 0
+	for key, node := range before {
+		after, _ := ring.Node(key)
+		if after == node {
+			continue
+		}
+		if after != "d:1" {
+			t.Fatal("key moved between existing nodes", key)
+		}
+		moved++
+	}
+	if moved == 0 || moved > 4000 {
+		t.Fatal("unexpected number of moved keys", moved)
+	}
+
+	ring.Remove("d:1")
+
+	for key, node := range before {
+		if after, _ := ring.Node(key); after != node {
+			t.Fatal("placement not restored", key)
+		}
+	}
+
+	if nodes := ring.Nodes(); len(nodes) != 3 {
+		t.Fatal("unexpected nodes", nodes)
+	}
+}
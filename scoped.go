@@ -0,0 +1,169 @@
+package pantry
+
+import (
+	"iter"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ScopedPantry is a view of a pantry that prefixes every key with its
+// namespace, letting several components share one store without collisions.
+// With WithKeyNormalizer, iterating and clearing a scope compare the keys
+// against the normalized prefix, so the normalizer must keep prefixes. With
+// WithKeyHasher, they find only the entries remembering their original key,
+// which needs verify.
+type ScopedPantry[T any] struct {
+	pantry *Pantry[T]
+	prefix string
+}
+
+func (pantry *Pantry[T]) Scoped(prefix string) ScopedPantry[T] {
+	return ScopedPantry[T]{
+		pantry: pantry,
+		prefix: prefix + ":",
+	}
+}
+
+// Namespace returns the view of the keys in the named namespace. It is the
+// same view as Scoped, with ClearNamespace to drop the whole namespace.
+func (pantry *Pantry[T]) Namespace(name string) ScopedPantry[T] {
+	return pantry.Scoped(name)
+}
+
+// ClearNamespace removes every entry in the named namespace under a single
+// write lock, including expired ones the cleanup has not removed yet.
+func (pantry *Pantry[T]) ClearNamespace(name string) {
+	pantry.Scoped(name).Clear()
+}
+
+func (scoped ScopedPantry[T]) Get(key string) (T, bool) {
+	return scoped.pantry.Get(scoped.prefix + key)
+}
+
+func (scoped ScopedPantry[T]) Set(key string, value T) {
+	scoped.pantry.Set(scoped.prefix+key, value)
+}
+
+func (scoped ScopedPantry[T]) Remove(key string) {
+	scoped.pantry.Remove(scoped.prefix + key)
+}
+
+func (scoped ScopedPantry[T]) Contains(key string) bool {
+	return scoped.pantry.Contains(scoped.prefix + key)
+}
+
+func (scoped ScopedPantry[T]) GetWithExpiration(key string) (T, time.Time, bool) {
+	return scoped.pantry.GetWithExpiration(scoped.prefix + key)
+}
+
+func (scoped ScopedPantry[T]) TTL(key string) (time.Duration, bool) {
+	return scoped.pantry.TTL(scoped.prefix + key)
+}
+
+func (scoped ScopedPantry[T]) SetWithTTL(key string, value T, ttl time.Duration) {
+	scoped.pantry.SetWithTTL(scoped.prefix+key, value, ttl)
+}
+
+func (scoped ScopedPantry[T]) Update(key string, fn func(old T, exists bool) (T, bool)) {
+	scoped.pantry.Update(scoped.prefix+key, fn)
+}
+
+func (scoped ScopedPantry[T]) Pop(key string) (T, bool) {
+	return scoped.pantry.Pop(scoped.prefix + key)
+}
+
+// Clear removes every entry in the scope, including expired ones the cleanup
+// has not removed yet.
+func (scoped ScopedPantry[T]) Clear() {
+	pantry := scoped.pantry
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	pantry.lockAll()
+	defer pantry.unlockAll()
+
+	now := pantry.clock.Now().UnixNano()
+	for key, item := range pantry.items() {
+		if _, found := scoped.cut(key, item); !found {
+			continue
+		}
+
+		pantry.drop(pantry.shardFor(key), key)
+		if pantry.isExpired(key, item, now) {
+			evictions = pantry.evict(evictions, key, item, now, Expired)
+			continue
+		}
+		evictions = pantry.evict(evictions, key, item, now, Removed)
+		evictions = pantry.writeBack(evictions, key, item.value, true)
+	}
+}
+
+// cut returns the key of a stored entry within the scope, comparing its
+// normalized key, or the original one when keys are hashed, against the
+// normalized prefix.
+func (scoped ScopedPantry[T]) cut(key string, item item[T]) (string, bool) {
+	if scoped.pantry.keyHasher != nil {
+		if item.original == "" {
+			return "", false
+		}
+		key = item.original
+	}
+	return strings.CutPrefix(key, scoped.pantry.canonical(scoped.prefix))
+}
+
+func (scoped ScopedPantry[T]) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for key := range scoped.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+func (scoped ScopedPantry[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, value := range scoped.All() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// All iterates over a snapshot of the live entries in the scope like
+// Pantry.All.
+func (scoped ScopedPantry[T]) All() iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		pantry := scoped.pantry
+		release := pantry.acquireIteration()
+
+		pantry.rlockAll()
+		now := pantry.clock.Now().UnixNano()
+		var entries []snapshotEntry[T]
+		for key, item := range pantry.items() {
+			if pantry.isExpired(key, item, now) {
+				continue
+			}
+			if key, found := scoped.cut(key, item); found {
+				entries = append(entries, snapshotEntry[T]{Key: key, Value: item.value})
+			}
+		}
+		pantry.runlockAll()
+		release()
+
+		if pantry.stableOrder {
+			slices.SortFunc(entries, func(a, b snapshotEntry[T]) int {
+				return strings.Compare(a.Key, b.Key)
+			})
+		}
+
+		for _, entry := range entries {
+			if !yield(entry.Key, pantry.clone(entry.Value)) {
+				return
+			}
+		}
+	}
+}
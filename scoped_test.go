@@ -0,0 +1,172 @@
+package pantry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScopedNoCollision(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	users := p.Scoped("users")
+	orders := p.Scoped("orders")
+
+	users.Set("1", 10)
+	orders.Set("1", 20)
+
+	if value, _ := users.Get("1"); value != 10 {
+		t.Fatal("users collided")
+	}
+
+	if value, _ := orders.Get("1"); value != 20 {
+		t.Fatal("orders collided")
+	}
+
+	if value, _ := p.Get("users:1"); value != 10 {
+		t.Fatal("not prefixed")
+	}
+
+	users.Remove("1")
+
+	if _, found := orders.Get("1"); !found {
+		t.Fatal("removed across scopes")
+	}
+}
+
+func TestScopedIteration(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	users := p.Scoped("users")
+
+	users.Set("1", 10)
+	users.Set("2", 20)
+	p.Scoped("orders").Set("1", 30)
+	p.Set("other", 40)
+
+	counter := 0
+
+	for key, value := range users.All() {
+		if key != "1" && key != "2" {
+			t.Fatal("prefix not stripped")
+		}
+
+		if value != 10 && value != 20 {
+			t.Fatal("outside namespace")
+		}
+
+		counter++
+	}
+
+	if counter != 2 {
+		t.Fatal("not 2 items")
+	}
+
+	for range users.Keys() {
+		break
+	}
+
+	for range users.Values() {
+		break
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	sessions := p.Namespace("sessions")
+
+	sessions.Set("a", 1)
+	sessions.SetWithTTL("b", 2, time.Minute)
+	p.Namespace("users").Set("a", 3)
+
+	if ttl, found := sessions.TTL("b"); !found || ttl > time.Minute {
+		t.Fatal("ttl not scoped")
+	}
+
+	sessions.Update("a", func(old int, exists bool) (int, bool) {
+		return old + 10, true
+	})
+
+	if value, _ := sessions.Pop("a"); value != 11 {
+		t.Fatal("update not scoped")
+	}
+
+	sessions.Set("c", 4)
+	p.ClearNamespace("sessions")
+
+	if _, found := sessions.Get("c"); found {
+		t.Fatal("namespace not cleared")
+	}
+
+	if value, _ := p.Namespace("users").Get("a"); value != 3 {
+		t.Fatal("cleared across namespaces")
+	}
+}
+
+func TestScopedContains(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	users := p.Scoped("users")
+	users.Set("1", 10)
+
+	if !users.Contains("1") || p.Scoped("orders").Contains("1") {
+		t.Fatal("contains not scoped")
+	}
+}
+
+func TestScopedKeyOptions(t *testing.T) {
+	hash := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+	cases := []struct {
+		name    string
+		options []Option[int]
+		keys    []string
+	}{
+		{"normalizer", []Option[int]{WithKeyNormalizer[int](strings.ToLower)}, []string{"a", "b"}},
+		{"hasher", []Option[int]{WithKeyHasher[int](hash, true)}, []string{"A", "b"}},
+		{"both", []Option[int]{WithKeyNormalizer[int](strings.ToLower), WithKeyHasher[int](hash, true)}, []string{"a", "b"}},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			p := New(context.Background(), time.Hour, test.options...)
+
+			users := p.Scoped("Users")
+			users.Set("A", 1)
+			users.Set("b", 2)
+			p.Scoped("orders").Set("A", 3)
+
+			keys := slices.Sorted(users.Keys())
+			if !slices.Equal(keys, test.keys) {
+				t.Fatal("unexpected keys", keys)
+			}
+
+			users.Clear()
+			if users.Contains("A") || users.Contains("b") || !p.Scoped("orders").Contains("A") {
+				t.Fatal("scope not cleared")
+			}
+		})
+	}
+}
+
+func TestClearNamespaceExpired(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithoutBackgroundCleanup[int]())
+
+	sessions := p.Namespace("sessions")
+	sessions.SetWithTTL("expired", 1, time.Millisecond)
+	sessions.Set("live", 2)
+
+	time.Sleep(5 * time.Millisecond)
+	p.ClearNamespace("sessions")
+
+	for key := range p.items() {
+		t.Fatal("entry left in the store", key)
+	}
+}
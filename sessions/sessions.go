@@ -0,0 +1,138 @@
+// Package sessions keeps HTTP sessions in a pantry. The session ID travels in
+// a cookie and the session expires once it has been idle for the timeout.
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+// idLength is the length of an encoded 256 bit session ID.
+var idLength = base64.RawURLEncoding.EncodedLen(32)
+
+type contextKey struct{}
+
+// Manager stores sessions of type T.
+type Manager[T any] struct {
+	// Cookie is the template of the session cookie. Its Value and MaxAge are
+	// set by the manager.
+	Cookie   http.Cookie
+	sessions *pantry.Pantry[T]
+}
+
+// New creates a manager whose sessions expire after being idle for
+// idleTimeout. Options are passed to the underlying pantry, which always
+// uses sliding expiration.
+func New[T any](ctx context.Context, idleTimeout time.Duration, opts ...pantry.Option[T]) *Manager[T] {
+	opts = append(opts[:len(opts):len(opts)], pantry.WithSlidingExpiration[T]())
+
+	return &Manager[T]{
+		Cookie: http.Cookie{
+			Name:     "session",
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+		},
+		sessions: pantry.New(ctx, idleTimeout, opts...),
+	}
+}
+
+// Get returns the session of the request, extending its lifetime.
+func (manager *Manager[T]) Get(r *http.Request) (T, bool) {
+	id, ok := manager.id(r)
+	if !ok {
+		return *new(T), false
+	}
+	return manager.sessions.Get(id)
+}
+
+// Save stores the session of the request, starting a new one with a fresh ID
+// and cookie if the request has none.
+func (manager *Manager[T]) Save(w http.ResponseWriter, r *http.Request, session T) error {
+	if id, ok := manager.id(r); ok && manager.sessions.Contains(id) {
+		manager.sessions.Set(id, session)
+		return nil
+	}
+	return manager.start(w, session)
+}
+
+// Renew moves the session to a fresh ID, which should be done whenever the
+// privileges of the session change, such as on login, to prevent session
+// fixation.
+func (manager *Manager[T]) Renew(w http.ResponseWriter, r *http.Request, session T) error {
+	if id, ok := manager.id(r); ok {
+		manager.sessions.Remove(id)
+	}
+	return manager.start(w, session)
+}
+
+// Destroy removes the session of the request and clears its cookie.
+func (manager *Manager[T]) Destroy(w http.ResponseWriter, r *http.Request) {
+	if id, ok := manager.id(r); ok {
+		manager.sessions.Remove(id)
+	}
+
+	cookie := manager.Cookie
+	cookie.MaxAge = -1
+	http.SetCookie(w, &cookie)
+}
+
+// Middleware loads the session of each request into its context, see
+// FromContext.
+func (manager *Manager[T]) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if session, ok := manager.Get(r); ok {
+			r = r.WithContext(context.WithValue(r.Context(), contextKey{}, session))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// FromContext returns the session loaded by Middleware.
+func FromContext[T any](ctx context.Context) (T, bool) {
+	session, ok := ctx.Value(contextKey{}).(T)
+	return session, ok
+}
+
+// Close stops the underlying pantry.
+func (manager *Manager[T]) Close() error {
+	return manager.sessions.Close()
+}
+
+func (manager *Manager[T]) start(w http.ResponseWriter, session T) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	manager.sessions.Set(id, session)
+
+	cookie := manager.Cookie
+	cookie.Value = id
+	http.SetCookie(w, &cookie)
+	return nil
+}
+
+// id returns the session ID of the request if it looks like one the manager
+// handed out.
+func (manager *Manager[T]) id(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(manager.Cookie.Name)
+	if err != nil || len(cookie.Value) != idLength {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+func newID() (string, error) {
+	var id [32]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(id[:]), nil
+}
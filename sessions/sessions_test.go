@@ -0,0 +1,87 @@
+package sessions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func TestManager(t *testing.T) {
+	manager := New[string](context.Background(), time.Hour)
+	defer manager.Close()
+
+	recorder := httptest.NewRecorder()
+	if err := manager.Save(recorder, httptest.NewRequest("GET", "/", nil), "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatal("unexpected cookies", cookies)
+	}
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.AddCookie(cookies[0])
+
+	var seen string
+	handler := manager.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = FromContext[string](r.Context())
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	if seen != "alice" {
+		t.Fatal("session not loaded", seen)
+	}
+
+	recorder = httptest.NewRecorder()
+	if err := manager.Renew(recorder, request, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manager.Get(request); ok {
+		t.Fatal("old session survived renewal")
+	}
+
+	renewed := httptest.NewRequest("GET", "/", nil)
+	renewed.AddCookie(recorder.Result().Cookies()[0])
+	if session, _ := manager.Get(renewed); session != "alice" {
+		t.Fatal("renewed session not found")
+	}
+
+	manager.Destroy(httptest.NewRecorder(), renewed)
+	if _, ok := manager.Get(renewed); ok {
+		t.Fatal("session not destroyed")
+	}
+}
+
+func TestManagerRejectsForeignIDs(t *testing.T) {
+	manager := New[string](context.Background(), time.Hour)
+	defer manager.Close()
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.AddCookie(&http.Cookie{Name: "session", Value: "chosen-by-attacker"})
+
+	recorder := httptest.NewRecorder()
+	if err := manager.Save(recorder, request, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value == "chosen-by-attacker" {
+		t.Fatal("foreign session ID accepted", cookies)
+	}
+}
+
+func TestNewKeepsCallerOptions(t *testing.T) {
+	opts := make([]pantry.Option[string], 1, 2)
+	opts[0] = pantry.WithShards[string](1)
+
+	manager := New(context.Background(), time.Hour, opts...)
+	defer manager.Close()
+
+	if opts[:2][1] != nil {
+		t.Fatal("option appended into the caller's slice")
+	}
+}
@@ -0,0 +1,74 @@
+package pantry
+
+import (
+	"hash/maphash"
+	"iter"
+	"sync/atomic"
+)
+
+const defaultShardCount = 16
+
+type shard[T any] struct {
+	mutex       shardMutex
+	store       map[string]item[T]
+	expirations expirationHeap
+	tags        map[string]map[string]struct{}
+	misses      map[string]int64
+	tombstones  map[string]tombstone[T]
+	filter      atomic.Pointer[bloomFilter]
+	filterStale int
+
+	snapshot      atomic.Pointer[map[string]item[T]]
+	snapshotReads atomic.Int64
+}
+
+func (pantry *Pantry[T]) shardFor(key string) *shard[T] {
+	return pantry.shards[maphash.String(pantry.seed, key)%uint64(len(pantry.shards))]
+}
+
+func (pantry *Pantry[T]) lockAll() {
+	for _, shard := range pantry.shards {
+		shard.mutex.Lock()
+	}
+}
+
+func (pantry *Pantry[T]) unlockAll() {
+	for _, shard := range pantry.shards {
+		shard.mutex.Unlock()
+	}
+}
+
+func (pantry *Pantry[T]) rlockAll() {
+	for _, shard := range pantry.shards {
+		shard.mutex.RLock()
+	}
+}
+
+func (pantry *Pantry[T]) runlockAll() {
+	for _, shard := range pantry.shards {
+		shard.mutex.RUnlock()
+	}
+}
+
+// items must be called with all shards locked.
+func (pantry *Pantry[T]) items() iter.Seq2[string, item[T]] {
+	return func(yield func(string, item[T]) bool) {
+		for _, shard := range pantry.shards {
+			for key, item := range shard.store {
+				if !yield(key, item) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (pantry *Pantry[T]) lookup(key string) (item[T], bool) {
+	shard := pantry.shardFor(key)
+
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	item, found := shard.store[key]
+	return item, found
+}
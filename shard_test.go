@@ -0,0 +1,67 @@
+package pantry
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestShards(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithShards[int](4))
+
+	if len(p.shards) != 4 {
+		t.Fatal("not 4 shards")
+	}
+
+	for i := 0; i < 100; i++ {
+		p.Set(strconv.Itoa(i), i)
+	}
+
+	counter := 0
+	for range p.All() {
+		counter++
+	}
+
+	if counter != 100 {
+		t.Fatal("not 100 items")
+	}
+
+	for i := 0; i < 100; i++ {
+		if value, _ := p.Get(strconv.Itoa(i)); value != i {
+			t.Fatal("wrong value")
+		}
+	}
+}
+
+func benchmarkParallelMixed(b *testing.B, shards int) {
+	p := New(context.Background(), time.Hour, WithShards[int](shards))
+
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		p.Set(keys[i], i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%4 == 0 {
+				p.Set(key, i)
+			} else {
+				p.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkParallelSingleShard(b *testing.B) {
+	benchmarkParallelMixed(b, 1)
+}
+
+func BenchmarkParallelSharded(b *testing.B) {
+	benchmarkParallelMixed(b, defaultShardCount)
+}
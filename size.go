@@ -0,0 +1,142 @@
+package pantry
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// WithSizeFunc makes ApproxMemoryUsage and Stats use size, the number of
+// bytes held by a value outside the pantry's own bookkeeping, instead of
+// estimating it by reflection.
+func WithSizeFunc[T any](size func(key string, value T) int64) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.sizeFn = size
+	}
+}
+
+// ApproxMemoryUsage estimates the bytes used by the stored entries, counting
+// the keys, the bookkeeping of each entry and the values. Without
+// WithSizeFunc the values are walked by reflection, following strings,
+// slices, maps, pointers and interfaces once each, which takes time
+// proportional to everything reachable from them.
+func (pantry *Pantry[T]) ApproxMemoryUsage() int64 {
+	var usage int64
+	for _, shard := range pantry.shards {
+		shard.mutex.RLock()
+		usage += pantry.shardMemoryUsage(shard)
+		shard.mutex.RUnlock()
+	}
+	return usage
+}
+
+// shardMemoryUsage must be called with the shard locked.
+func (pantry *Pantry[T]) shardMemoryUsage(shard *shard[T]) int64 {
+	var usage int64
+	overhead := int64(unsafe.Sizeof(item[T]{}))
+	for key, item := range shard.store {
+		usage += overhead + int64(len(key)) + pantry.valueSize(key, item.value)
+	}
+	return usage
+}
+
+func (pantry *Pantry[T]) valueSize(key string, value T) int64 {
+	if pantry.sizeFn != nil {
+		return pantry.sizeFn(key, value)
+	}
+	return referencedSize(reflect.ValueOf(&value).Elem(), make(map[uintptr]struct{}))
+}
+
+// referencedSize returns the bytes referenced by value beyond its own
+// storage. Pointers seen before are not counted again, which also stops
+// cycles.
+func referencedSize(value reflect.Value, seen map[uintptr]struct{}) int64 {
+	switch value.Kind() {
+	case reflect.String:
+		return int64(value.Len())
+
+	case reflect.Slice:
+		if value.IsNil() || !visit(value.Pointer(), seen) {
+			return 0
+		}
+		size := int64(value.Cap()) * int64(value.Type().Elem().Size())
+		if references(value.Type().Elem()) {
+			for i := range value.Len() {
+				size += referencedSize(value.Index(i), seen)
+			}
+		}
+		return size
+
+	case reflect.Array:
+		var size int64
+		if references(value.Type().Elem()) {
+			for i := range value.Len() {
+				size += referencedSize(value.Index(i), seen)
+			}
+		}
+		return size
+
+	case reflect.Map:
+		if value.IsNil() || !visit(value.Pointer(), seen) {
+			return 0
+		}
+		entry := int64(value.Type().Key().Size() + value.Type().Elem().Size())
+		size := int64(value.Len()) * entry
+		iter := value.MapRange()
+		for iter.Next() {
+			size += referencedSize(iter.Key(), seen) + referencedSize(iter.Value(), seen)
+		}
+		return size
+
+	case reflect.Pointer:
+		if value.IsNil() || !visit(value.Pointer(), seen) {
+			return 0
+		}
+		return int64(value.Type().Elem().Size()) + referencedSize(value.Elem(), seen)
+
+	case reflect.Interface:
+		if value.IsNil() {
+			return 0
+		}
+		return int64(value.Elem().Type().Size()) + referencedSize(value.Elem(), seen)
+
+	case reflect.Struct:
+		var size int64
+		for i := range value.NumField() {
+			size += referencedSize(value.Field(i), seen)
+		}
+		return size
+
+	default:
+		return 0
+	}
+}
+
+func visit(pointer uintptr, seen map[uintptr]struct{}) bool {
+	if _, found := seen[pointer]; found {
+		return false
+	}
+	seen[pointer] = struct{}{}
+	return true
+}
+
+// references reports whether values of t can refer to memory outside
+// themselves.
+func references(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Array:
+		return references(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if references(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
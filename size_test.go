@@ -0,0 +1,58 @@
+package pantry
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+type sizedNode struct {
+	name     string
+	values   []int64
+	next     *sizedNode
+	children map[string]int32
+}
+
+func TestReferencedSize(t *testing.T) {
+	node := &sizedNode{name: "abcd", values: make([]int64, 2, 4)}
+	node.next = node
+
+	want := int64(unsafe.Sizeof(sizedNode{})) + 4 + 4*8
+	if size := referencedSize(reflect.ValueOf(node), make(map[uintptr]struct{})); size != want {
+		t.Fatalf("expected %d, got %d", want, size)
+	}
+}
+
+func TestApproxMemoryUsage(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+
+	empty := p.ApproxMemoryUsage()
+	p.Set("key", strings.Repeat("x", 1000))
+
+	if usage := p.ApproxMemoryUsage(); usage-empty < 1003 {
+		t.Fatal("unexpected usage", usage)
+	}
+
+	if p.Stats().MemoryUsage != 0 {
+		t.Fatal("memory usage reported without a size function")
+	}
+}
+
+func TestSizeFunc(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithSizeFunc(func(key string, value []byte) int64 {
+		return int64(len(value))
+	}))
+
+	p.Set("key", make([]byte, 1000))
+
+	usage := p.ApproxMemoryUsage()
+	if usage < 1000 {
+		t.Fatal("unexpected usage", usage)
+	}
+	if p.Stats().MemoryUsage != usage {
+		t.Fatal("stats disagree", p.Stats().MemoryUsage, usage)
+	}
+}
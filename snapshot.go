@@ -0,0 +1,146 @@
+package pantry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+var ErrChecksumMismatch = errors.New("pantry: snapshot checksum mismatch")
+
+// snapshotEntry is written by ExportSnapshot and compaction. Key is the
+// stored key and Original the canonical key the entry remembers, if any.
+type snapshotEntry[T any] struct {
+	Key      string
+	Value    T
+	Expires  int64
+	Original string
+}
+
+// ExportSnapshot writes the live entries encoded by the codec and gzip
+// compressed, prefixed with the SHA-256 checksum of the compressed data.
+func (pantry *Pantry[T]) ExportSnapshot(w io.Writer) error {
+	pantry.rlockAll()
+	entries := pantry.snapshotEntries()
+	pantry.runlockAll()
+
+	return pantry.writeSnapshot(w, entries)
+}
+
+// snapshotEntries must be called with all shards locked.
+func (pantry *Pantry[T]) snapshotEntries() []snapshotEntry[T] {
+	now := pantry.clock.Now().UnixNano()
+	var entries []snapshotEntry[T]
+	for key, item := range pantry.items() {
+		if pantry.isExpired(key, item, now) {
+			continue
+		}
+		entries = append(entries, snapshotEntry[T]{
+			Key:      key,
+			Value:    item.value,
+			Expires:  item.expires,
+			Original: item.original,
+		})
+	}
+	return entries
+}
+
+func (pantry *Pantry[T]) writeSnapshot(w io.Writer, entries []snapshotEntry[T]) error {
+	data, err := pantry.codec.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	sealed, err := pantry.seal(compressed.Bytes())
+	if err != nil {
+		return err
+	}
+
+	checksum := sha256.Sum256(sealed)
+	if _, err := w.Write(checksum[:]); err != nil {
+		return err
+	}
+
+	_, err = w.Write(sealed)
+	return err
+}
+
+// ImportSnapshot verifies and loads a snapshot written by ExportSnapshot.
+// Entries keep their original expiration, already expired ones are skipped.
+func (pantry *Pantry[T]) ImportSnapshot(r io.Reader) error {
+	if pantry.closed.Load() {
+		return ErrClosed
+	}
+
+	entries, err := pantry.readSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	pantry.lockAll()
+	defer pantry.unlockAll()
+
+	now := pantry.clock.Now().UnixNano()
+	for _, entry := range entries {
+		if now > entry.Expires {
+			continue
+		}
+
+		key, canonical := pantry.restoredKey(entry.Key, entry.Original)
+		evictions = pantry.put(evictions, pantry.shardFor(key), key, pantry.newItem(canonical, entry.Value, entry.Expires), now)
+	}
+	return nil
+}
+
+// readSnapshot verifies and decodes a snapshot written by ExportSnapshot.
+func (pantry *Pantry[T]) readSnapshot(r io.Reader) ([]snapshotEntry[T], error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < sha256.Size {
+		return nil, ErrChecksumMismatch
+	}
+
+	checksum, sealed := data[:sha256.Size], data[sha256.Size:]
+	if sum := sha256.Sum256(sealed); !bytes.Equal(sum[:], checksum) {
+		return nil, ErrChecksumMismatch
+	}
+
+	compressed, err := pantry.open(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err = io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []snapshotEntry[T]
+	err = pantry.codec.Unmarshal(data, &entries)
+	return entries, err
+}
@@ -0,0 +1,57 @@
+package pantry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+
+	var buffer bytes.Buffer
+	if err := p.ExportSnapshot(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New[int](context.Background(), time.Hour)
+	if err := restored.ImportSnapshot(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != 1 {
+		t.Fatal("first not restored")
+	}
+
+	if value, _ := restored.Get("second"); value != 2 {
+		t.Fatal("second not restored")
+	}
+}
+
+func TestSnapshotCorrupted(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+
+	var buffer bytes.Buffer
+	if err := p.ExportSnapshot(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buffer.Bytes()
+	data[len(data)-1] ^= 0xff
+
+	restored := New[int](context.Background(), time.Hour)
+	if err := restored.ImportSnapshot(bytes.NewReader(data)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal("corruption not detected")
+	}
+
+	if !restored.IsEmpty() {
+		t.Fatal("not empty")
+	}
+}
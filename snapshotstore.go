@@ -0,0 +1,185 @@
+package pantry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SnapshotStore keeps named snapshots written by ExportSnapshot, for example
+// in object storage when there is no persistent disk.
+type SnapshotStore interface {
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Get returns an error matching fs.ErrNotExist if there is no snapshot
+	// named name.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names of the snapshots starting with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes the snapshot, succeeding if there is none.
+	Delete(ctx context.Context, name string) error
+}
+
+// fileSnapshotStore keeps snapshots as files in a directory, replacing them
+// atomically.
+type fileSnapshotStore struct {
+	dir string
+}
+
+// NewFileSnapshotStore returns a snapshot store keeping one file per
+// snapshot in dir, which is created if needed.
+func NewFileSnapshotStore(dir string) (SnapshotStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fileSnapshotStore{dir: dir}, nil
+}
+
+func (store *fileSnapshotStore) path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return "", errors.New("pantry: invalid snapshot name " + name)
+	}
+	return filepath.Join(store.dir, name), nil
+}
+
+func (store *fileSnapshotStore) Put(_ context.Context, name string, r io.Reader) error {
+	path, err := store.path(name)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+func (store *fileSnapshotStore) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	path, err := store.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (store *fileSnapshotStore) List(_ context.Context, prefix string) ([]string, error) {
+	files, err := os.ReadDir(store.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		name := file.Name()
+		if !file.IsDir() && strings.HasPrefix(name, prefix) && !strings.HasSuffix(name, ".tmp") {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (store *fileSnapshotStore) Delete(_ context.Context, name string) error {
+	path, err := store.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// autoSnapshotPrefix starts the names of the snapshots taken by
+// WithAutoSnapshot, followed by the time they were taken.
+const autoSnapshotPrefix = "snapshot-"
+
+type snapshotOutcome struct {
+	at  time.Time
+	err error
+}
+
+// WithAutoSnapshot uploads a snapshot to store every interval and once more
+// on Close while the context is not done yet, keeping the latest keep of
+// them. Snapshots are named after the time they are taken, and RestoreLatest
+// loads the newest one back. The outcome of the last upload shows up in
+// Stats and failures are reported to the logger. keep must be positive.
+func WithAutoSnapshot[T any](interval time.Duration, store SnapshotStore, keep int) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if keep <= 0 {
+			pantry.invalidOption("auto snapshots need keep %d > 0", keep)
+			return
+		}
+		pantry.snapshotStore = store
+		pantry.snapshotInterval = interval
+		pantry.snapshotKeep = keep
+	}
+}
+
+// SnapshotTo exports a snapshot and uploads it to store under name.
+func (pantry *Pantry[T]) SnapshotTo(ctx context.Context, store SnapshotStore, name string) error {
+	var buffer bytes.Buffer
+	if err := pantry.ExportSnapshot(&buffer); err != nil {
+		return err
+	}
+	return store.Put(ctx, name, &buffer)
+}
+
+// RestoreFrom imports the snapshot stored under name. It returns an error
+// matching fs.ErrNotExist if there is none yet.
+func (pantry *Pantry[T]) RestoreFrom(ctx context.Context, store SnapshotStore, name string) error {
+	snapshot, err := store.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	return pantry.ImportSnapshot(snapshot)
+}
+
+// RestoreLatest imports the newest snapshot taken by WithAutoSnapshot. It
+// returns an error matching fs.ErrNotExist if there is none yet.
+func (pantry *Pantry[T]) RestoreLatest(ctx context.Context, store SnapshotStore) error {
+	names, err := store.List(ctx, autoSnapshotPrefix)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("pantry: no snapshots: %w", fs.ErrNotExist)
+	}
+	return pantry.RestoreFrom(ctx, store, slices.Max(names))
+}
+
+// autoSnapshot uploads a new snapshot, deletes the ones beyond the kept
+// number and records the outcome.
+func (pantry *Pantry[T]) autoSnapshot(ctx context.Context) error {
+	now := pantry.clock.Now()
+	name := autoSnapshotPrefix + now.UTC().Format("20060102T150405.000000000Z")
+
+	err := pantry.SnapshotTo(ctx, pantry.snapshotStore, name)
+	if err == nil {
+		err = pantry.pruneSnapshots(ctx)
+	}
+
+	pantry.lastSnapshot.Store(&snapshotOutcome{at: now, err: err})
+	return err
+}
+
+func (pantry *Pantry[T]) pruneSnapshots(ctx context.Context) error {
+	names, err := pantry.snapshotStore.List(ctx, autoSnapshotPrefix)
+	if err != nil || len(names) <= pantry.snapshotKeep {
+		return err
+	}
+
+	slices.Sort(names)
+	var errs []error
+	for _, name := range names[:len(names)-pantry.snapshotKeep] {
+		errs = append(errs, pantry.snapshotStore.Delete(ctx, name))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,79 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"time"
+)
+
+func TestFileSnapshotStore(t *testing.T) {
+	store, err := NewFileSnapshotStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New[string](context.Background(), time.Hour)
+	if err := restored.RestoreFrom(context.Background(), store, "cache"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("unexpected error for a missing snapshot", err)
+	}
+
+	p := New[string](context.Background(), time.Hour)
+	p.Set("key", "value")
+	if err := p.SnapshotTo(context.Background(), store, "cache"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := restored.RestoreFrom(context.Background(), store, "cache"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := restored.Get("key"); value != "value" {
+		t.Fatal("unexpected value", value)
+	}
+
+	if err := store.Delete(context.Background(), "cache"); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := store.List(context.Background(), ""); len(names) != 0 {
+		t.Fatal("snapshot not deleted", names)
+	}
+
+	if err := p.SnapshotTo(context.Background(), store, "../escape"); err == nil {
+		t.Fatal("name outside the directory accepted")
+	}
+}
+
+func TestAutoSnapshot(t *testing.T) {
+	store, err := NewFileSnapshotStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := New(context.Background(), time.Hour, WithAutoSnapshot[string](5*time.Millisecond, store, 2))
+	p.Set("key", "value")
+
+	eventually(t, func() bool { return !p.Stats().LastSnapshotAt.IsZero() })
+	if err := p.Stats().LastSnapshotErr; err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	p.Set("last", "value")
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := store.List(context.Background(), autoSnapshotPrefix)
+	if err != nil || len(names) != 2 {
+		t.Fatal("old snapshots not pruned", names, err)
+	}
+
+	restored := New[string](context.Background(), time.Hour)
+	if err := restored.RestoreLatest(context.Background(), store); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Contains("last") {
+		t.Fatal("latest snapshot not restored")
+	}
+}
@@ -0,0 +1,64 @@
+package pantry
+
+// GetStale returns the value even if it has expired, as long as the cleanup
+// has not removed it yet. The last result reports whether the value is
+// stale, which with WithSoftTTL includes live entries past their soft TTL.
+// With WithStaleRefresh, reading a stale value starts a background refresh of
+// the entry.
+func (pantry *Pantry[T]) GetStale(key string) (T, bool, bool) {
+	canonical := pantry.canonical(key)
+	normalized := pantry.hash(canonical)
+
+	item, found := pantry.lookup(normalized)
+	if found && pantry.isExpired(normalized, item, pantry.clock.Now().UnixNano()) {
+		pantry.refreshStale(canonical, item.value)
+		return pantry.clone(item.value), true, true
+	}
+
+	item, found = pantry.get(key)
+	return item.value, found, found && pantry.isStale(item, pantry.clock.Now().UnixNano())
+}
+
+// isStale reports whether a live entry is past the soft TTL.
+func (pantry *Pantry[T]) isStale(item item[T], now int64) bool {
+	return pantry.softTTL > 0 && now-item.written >= int64(pantry.softTTL)
+}
+
+// refreshStale starts a refresh of a stale entry with the stale refresh
+// function. It takes the canonical key.
+func (pantry *Pantry[T]) refreshStale(key string, stale T) {
+	if pantry.staleRefresh == nil {
+		return
+	}
+
+	pantry.refreshInBackground(key, func() (T, error) {
+		return pantry.staleRefresh(key, stale)
+	})
+}
+
+// refreshInBackground runs at most one refresh per canonical key at a time
+// and stores the result unless refresh fails.
+func (pantry *Pantry[T]) refreshInBackground(key string, refresh func() (T, error)) {
+	pantry.callsMutex.Lock()
+	if _, found := pantry.refreshing[key]; found {
+		pantry.callsMutex.Unlock()
+		return
+	}
+	if pantry.refreshing == nil {
+		pantry.refreshing = make(map[string]struct{})
+	}
+	pantry.refreshing[key] = struct{}{}
+	pantry.callsMutex.Unlock()
+
+	go func() {
+		defer func() {
+			pantry.callsMutex.Lock()
+			delete(pantry.refreshing, key)
+			pantry.callsMutex.Unlock()
+		}()
+
+		if value, err := refresh(); err == nil {
+			pantry.Set(key, value)
+		}
+	}()
+}
@@ -0,0 +1,63 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetStale(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithoutBackgroundCleanup[int]())
+	defer p.Close()
+
+	p.Set("fresh", 1)
+	p.SetWithTTL("expired", 2, time.Millisecond)
+
+	time.Sleep(2 * time.Millisecond)
+
+	if value, found, stale := p.GetStale("fresh"); !found || stale || value != 1 {
+		t.Fatal("fresh entry", value, found, stale)
+	}
+
+	if value, found, stale := p.GetStale("expired"); !found || !stale || value != 2 {
+		t.Fatal("stale entry", value, found, stale)
+	}
+
+	if _, found, _ := p.GetStale("missing"); found {
+		t.Fatal("found missing")
+	}
+}
+
+func TestGetStaleRefresh(t *testing.T) {
+	refreshed := make(chan struct{})
+
+	p := New(context.Background(), time.Hour,
+		WithoutBackgroundCleanup[int](),
+		WithStaleRefresh(func(key string, stale int) (int, error) {
+			defer close(refreshed)
+			return stale + 1, nil
+		}),
+	)
+	defer p.Close()
+
+	p.SetWithTTL("key", 1, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+
+	if value, _, stale := p.GetStale("key"); !stale || value != 1 {
+		t.Fatal("not stale")
+	}
+
+	<-refreshed
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if value, found := p.Get("key"); found {
+			if value != 2 {
+				t.Fatal("unexpected refreshed value", value)
+			}
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("not refreshed")
+}
@@ -0,0 +1,210 @@
+package pantry
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds the counters and sizes of a pantry. Coalesced counts the reads
+// that waited for a backend load or loader run started by another caller
+// instead of starting their own. LastSnapshotAt and LastSnapshotErr tell when
+// the last snapshot of WithAutoSnapshot was taken and whether uploading it
+// failed. Shards holds the size of every shard, and with WithContentionStats
+// Locks and the shards' lock statistics tell how contended they are.
+// Items counts the live entries like Count, and MaxItems and MaxCost are the
+// capacity set by WithMaxItems and WithMaxCost, zero when unbounded.
+type Stats struct {
+	Hits                uint64        `json:"hits"`
+	Misses              uint64        `json:"misses"`
+	Expirations         uint64        `json:"expirations"`
+	Evictions           uint64        `json:"evictions"`
+	Coalesced           uint64        `json:"coalesced"`
+	Items               int           `json:"items"`
+	Cost                int64         `json:"cost"`
+	MemoryUsage         int64         `json:"memory_usage"`
+	MaxItems            int           `json:"max_items"`
+	MaxCost             int64         `json:"max_cost"`
+	LastCleanupDuration time.Duration `json:"last_cleanup_duration"`
+	LastSnapshotAt      time.Time     `json:"last_snapshot_at"`
+	LastSnapshotErr     error         `json:"-"`
+	Locks               LockStats     `json:"locks"`
+	Shards              []ShardStats  `json:"shards"`
+}
+
+// plainStats drops the MarshalJSON method of Stats.
+type plainStats Stats
+
+type encodedStats struct {
+	plainStats
+	LastSnapshotErr string `json:"last_snapshot_err,omitempty"`
+}
+
+// MarshalJSON encodes the durations in nanoseconds and LastSnapshotErr as
+// its message, omitted when there was no error.
+func (stats Stats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stats.encoded())
+}
+
+func (stats Stats) encoded() encodedStats {
+	encoded := encodedStats{plainStats: plainStats(stats)}
+	if stats.LastSnapshotErr != nil {
+		encoded.LastSnapshotErr = stats.LastSnapshotErr.Error()
+	}
+	return encoded
+}
+
+// savedStats are the counters written by Save with WithPersistStats.
+type savedStats struct {
+	Hits        uint64
+	Misses      uint64
+	Expirations uint64
+	Evictions   uint64
+	Coalesced   uint64
+}
+
+// WithPersistStats makes Save write the hit, miss, expiration, eviction and
+// coalescing counters along with the entries, and Load add the saved counters
+// to the current ones, so they keep counting across restarts.
+func WithPersistStats[T any]() Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.persistStats = true
+	}
+}
+
+func (pantry *Pantry[T]) savedStats() savedStats {
+	return savedStats{
+		Hits:        pantry.counters.hits.Load(),
+		Misses:      pantry.counters.misses.Load(),
+		Expirations: pantry.counters.expirations.Load(),
+		Evictions:   pantry.counters.evictions.Load(),
+		Coalesced:   pantry.counters.coalesced.Load(),
+	}
+}
+
+func (pantry *Pantry[T]) restoreStats(stats savedStats) {
+	pantry.counters.hits.Add(stats.Hits)
+	pantry.counters.misses.Add(stats.Misses)
+	pantry.counters.expirations.Add(stats.Expirations)
+	pantry.counters.evictions.Add(stats.Evictions)
+	pantry.counters.coalesced.Add(stats.Coalesced)
+}
+
+type counters struct {
+	hits                atomic.Uint64
+	misses              atomic.Uint64
+	expirations         atomic.Uint64
+	evictions           atomic.Uint64
+	coalesced           atomic.Uint64
+	lastCleanupDuration atomic.Int64
+}
+
+// Stats returns the counters and the current size. MemoryUsage is only
+// filled in with WithSizeFunc, as estimating it by reflection on every call
+// would be too slow; use ApproxMemoryUsage for that.
+func (pantry *Pantry[T]) Stats() Stats {
+	items := 0
+	var memoryUsage int64
+	var locks LockStats
+	shards := make([]ShardStats, len(pantry.shards))
+	now := pantry.clock.Now().UnixNano()
+	for i, shard := range pantry.shards {
+		shard.mutex.RLock()
+		for key, item := range shard.store {
+			if !pantry.isExpired(key, item, now) {
+				shards[i].Items++
+			}
+		}
+		if pantry.sizeFn != nil {
+			memoryUsage += pantry.shardMemoryUsage(shard)
+		}
+		shard.mutex.RUnlock()
+
+		items += shards[i].Items
+		shards[i].Locks = shard.mutex.contention.stats()
+		locks.add(shards[i].Locks)
+	}
+
+	stats := Stats{
+		Hits:                pantry.counters.hits.Load(),
+		Misses:              pantry.counters.misses.Load(),
+		Expirations:         pantry.counters.expirations.Load(),
+		Evictions:           pantry.counters.evictions.Load(),
+		Coalesced:           pantry.counters.coalesced.Load(),
+		Items:               items,
+		Cost:                pantry.cost.Load(),
+		MemoryUsage:         memoryUsage,
+		MaxItems:            pantry.maxItems,
+		MaxCost:             pantry.maxCost,
+		LastCleanupDuration: time.Duration(pantry.counters.lastCleanupDuration.Load()),
+		Locks:               locks,
+		Shards:              shards,
+	}
+
+	if outcome := pantry.lastSnapshot.Load(); outcome != nil {
+		stats.LastSnapshotAt, stats.LastSnapshotErr = outcome.at, outcome.err
+	}
+	return stats
+}
+
+// Ages returns the age of the oldest and the newest live entry, zero when
+// there are none. Unlike the counters of Stats, it has to look at every
+// entry, so it is kept out of Stats.
+func (pantry *Pantry[T]) Ages() (oldest, newest time.Duration) {
+	now := pantry.clock.Now().UnixNano()
+	var first, last int64
+	found := false
+	for _, shard := range pantry.shards {
+		shard.mutex.RLock()
+		for key, item := range shard.store {
+			if pantry.isExpired(key, item, now) {
+				continue
+			}
+			if !found || item.written < first {
+				first = item.written
+			}
+			if !found || item.written > last {
+				last = item.written
+			}
+			found = true
+		}
+		shard.mutex.RUnlock()
+	}
+
+	if !found {
+		return 0, 0
+	}
+	return time.Duration(now - first), time.Duration(now - last)
+}
+
+// StatsJSON returns Stats together with the ages of Ages as oldest_age and
+// newest_age encoded as JSON, for metrics pipelines ingesting JSON.
+func (pantry *Pantry[T]) StatsJSON() ([]byte, error) {
+	oldest, newest := pantry.Ages()
+	return json.Marshal(struct {
+		encodedStats
+		OldestAge time.Duration `json:"oldest_age"`
+		NewestAge time.Duration `json:"newest_age"`
+	}{pantry.Stats().encoded(), oldest, newest})
+}
+
+func (pantry *Pantry[T]) ResetStats() {
+	pantry.counters.hits.Store(0)
+	pantry.counters.misses.Store(0)
+	pantry.counters.expirations.Store(0)
+	pantry.counters.evictions.Store(0)
+	pantry.counters.coalesced.Store(0)
+	pantry.counters.lastCleanupDuration.Store(0)
+	for _, shard := range pantry.shards {
+		shard.mutex.contention.reset()
+	}
+}
+
+// PublishExpvar publishes the statistics under name in expvar, so they show up
+// on /debug/vars. Like expvar.Publish, it panics if name is already in use.
+func (pantry *Pantry[T]) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return pantry.Stats()
+	}))
+}
@@ -0,0 +1,170 @@
+package pantry
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithMaxItems[int](2))
+
+	p.Set("first", 1)
+	p.Set("second", 2)
+	p.Set("third", 3)
+	p.SetWithTTL("fourth", 4, time.Millisecond)
+
+	p.Get("third")
+	p.Get("missing")
+
+	time.Sleep(5 * time.Millisecond)
+	p.removeExpired()
+
+	stats := p.Stats()
+
+	if stats.Hits != 1 {
+		t.Fatal("not 1 hit")
+	}
+
+	if stats.Misses != 1 {
+		t.Fatal("not 1 miss")
+	}
+
+	if stats.Evictions != 2 {
+		t.Log(stats)
+		t.Fatal("not 2 evictions")
+	}
+
+	if stats.Expirations != 1 {
+		t.Fatal("not 1 expiration")
+	}
+
+	if stats.Items != 1 {
+		t.Fatal("not 1 item")
+	}
+
+	if stats.LastCleanupDuration <= 0 {
+		t.Fatal("cleanup not measured")
+	}
+}
+
+func TestStatsItemsSkipExpired(t *testing.T) {
+	clock := &testClock{}
+	clock.now.Store(time.Now().UnixNano())
+	p := New(context.Background(), time.Hour, WithClock[int](clock), WithoutBackgroundCleanup[int]())
+	defer p.Close()
+
+	p.Set("live", 1)
+	p.SetWithTTL("expired", 2, time.Minute)
+	clock.advance(2 * time.Minute)
+
+	if stats := p.Stats(); stats.Items != p.Count() || stats.Items != 1 {
+		t.Fatal("unexpected items", stats.Items, p.Count())
+	}
+}
+
+func TestAges(t *testing.T) {
+	clock := &testClock{}
+	clock.now.Store(time.Now().UnixNano())
+	p := New(context.Background(), time.Hour, WithClock[int](clock), WithoutBackgroundCleanup[int]())
+	defer p.Close()
+
+	if oldest, newest := p.Ages(); oldest != 0 || newest != 0 {
+		t.Fatal("ages without entries", oldest, newest)
+	}
+
+	p.SetWithTTL("expired", 0, time.Second)
+	clock.advance(time.Minute)
+	p.Set("first", 1)
+	clock.advance(time.Minute)
+	p.Set("second", 2)
+	clock.advance(time.Second)
+
+	if oldest, newest := p.Ages(); oldest != time.Minute+time.Second || newest != time.Second {
+		t.Fatal("unexpected ages", oldest, newest)
+	}
+}
+
+func TestResetStats(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+	p.Get("first")
+	p.Get("missing")
+
+	p.ResetStats()
+
+	stats := p.Stats()
+	if stats.Hits != 0 || stats.Misses != 0 {
+		t.Fatal("not reset")
+	}
+
+	if stats.Items != 1 {
+		t.Fatal("items reset")
+	}
+}
+
+func TestPublishExpvar(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("key", 1)
+	p.Get("key")
+	p.Get("missing")
+
+	p.PublishExpvar("pantry_test")
+
+	var stats Stats
+	if err := json.Unmarshal([]byte(expvar.Get("pantry_test").String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Items != 1 || stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatal("unexpected stats", stats)
+	}
+}
+
+func TestStatsJSON(t *testing.T) {
+	clock := &testClock{}
+	clock.now.Store(time.Now().UnixNano())
+	p := New(context.Background(), time.Hour, WithClock[int](clock), WithMaxItems[int](2))
+	defer p.Close()
+
+	p.Set("first", 1)
+	clock.advance(time.Minute)
+	p.Set("second", 2)
+	clock.advance(time.Second)
+	p.Set("third", 3)
+	p.Get("third")
+	p.Get("third")
+	p.Get("missing")
+
+	data, err := p.StatsJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]float64{
+		"hits":       2,
+		"misses":     1,
+		"evictions":  1,
+		"items":      2,
+		"max_items":  2,
+		"oldest_age": float64(time.Second),
+		"newest_age": 0,
+	}
+	for name, value := range expected {
+		if fields[name] != value {
+			t.Fatal("unexpected field", name, fields[name])
+		}
+	}
+	if _, ok := fields["last_snapshot_err"]; ok {
+		t.Fatal("empty snapshot error encoded")
+	}
+}
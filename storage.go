@@ -0,0 +1,142 @@
+package pantry
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage keeps the entries of a persistent pantry. Entries are stored under
+// their keys as encoded, and possibly compressed and encrypted, records.
+type Storage interface {
+	// Load calls fn for every stored entry. The data is only valid during
+	// the call.
+	Load(fn func(key string, data []byte) error) error
+	// Write applies the batch of changes at once. A nil value deletes the
+	// key.
+	Write(batch map[string][]byte) error
+	Close() error
+}
+
+// dirStorage keeps one file per key in a directory. Keys are escaped, so
+// those containing path separators stay inside it, and files are replaced
+// atomically, so a crash leaves either the old or the new entry.
+type dirStorage struct {
+	dir       string
+	extension string
+}
+
+func newDirStorage(dir, extension string) (*dirStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &dirStorage{dir: dir, extension: extension}, nil
+}
+
+func (storage *dirStorage) path(key string) string {
+	return filepath.Join(storage.dir, url.PathEscape(key)+storage.extension)
+}
+
+// Load skips files that cannot be read and reports them in the returned
+// error.
+func (storage *dirStorage) Load(fn func(key string, data []byte) error) error {
+	files, err := os.ReadDir(storage.dir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != storage.extension {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(storage.dir, file.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		key, err := url.PathUnescape(strings.TrimSuffix(file.Name(), storage.extension))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := fn(key, data); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (storage *dirStorage) Write(batch map[string][]byte) error {
+	var errs []error
+	for key, data := range batch {
+		path := storage.path(key)
+		if data == nil {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		errs = append(errs, writeFileAtomic(path, func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+func (storage *dirStorage) Close() error {
+	return nil
+}
+
+// writeFileAtomic writes to a temporary file next to path, syncs it and
+// renames it over path.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	temporary := file.Name()
+
+	if err := write(file); err != nil {
+		file.Close()
+		os.Remove(temporary)
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(temporary)
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(temporary)
+		return err
+	}
+
+	if err := os.Rename(temporary, path); err != nil {
+		os.Remove(temporary)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir makes a rename in dir durable. Not every platform supports syncing
+// a directory, so failing to do so is not an error.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	file.Sync()
+	return nil
+}
@@ -0,0 +1,144 @@
+package pantry
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+)
+
+// streamChunkSize is the number of entries read under one lock and written
+// as one chunk by SaveStream.
+const streamChunkSize = 1024
+
+// maxStreamChunk bounds the size of a chunk accepted by LoadStream.
+const maxStreamChunk = 1 << 30
+
+var errStreamTruncated = errors.New("pantry: stream ends before its last chunk")
+
+// SaveStream writes the live entries like Save, but shard by shard in chunks
+// of up to 1024 entries, each encoded, compressed and sealed on its own. Only
+// one shard is locked at a time and only while a chunk is copied, so writers
+// are not blocked for the whole save. In turn the result is not a point in
+// time snapshot: writes made while saving may or may not be included.
+func (pantry *Pantry[T]) SaveStream(w io.Writer) error {
+	for _, shard := range pantry.shards {
+		shard.mutex.RLock()
+		keys := make([]string, 0, len(shard.store))
+		for key := range shard.store {
+			keys = append(keys, key)
+		}
+		shard.mutex.RUnlock()
+
+		for chunk := range slices.Chunk(keys, streamChunkSize) {
+			entries := make([]savedEntry[T], 0, len(chunk))
+
+			shard.mutex.RLock()
+			now := pantry.clock.Now().UnixNano()
+			for _, key := range chunk {
+				if item, found := shard.store[key]; found && !pantry.isExpired(key, item, now) {
+					entries = append(entries, saved(key, item, now))
+				}
+			}
+			shard.mutex.RUnlock()
+
+			if len(entries) == 0 {
+				continue
+			}
+			if err := pantry.writeChunk(w, entries); err != nil {
+				return err
+			}
+		}
+	}
+
+	// A chunk of length zero marks the end, so a cut off stream is detected.
+	_, err := w.Write(binary.AppendUvarint(nil, 0))
+	return err
+}
+
+func (pantry *Pantry[T]) writeChunk(w io.Writer, entries []savedEntry[T]) error {
+	data, err := pantry.codec.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	if data, err = pantry.compress(data); err != nil {
+		return err
+	}
+
+	if data, err = pantry.seal(data); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(data)))); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// LoadStream reads entries written by SaveStream chunk by chunk, locking
+// only the shard of each entry while storing it. Entries of the chunks read
+// before an error stay loaded.
+func (pantry *Pantry[T]) LoadStream(r io.Reader) error {
+	if pantry.closed.Load() {
+		return ErrClosed
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		length, err := binary.ReadUvarint(reader)
+		if errors.Is(err, io.EOF) {
+			return errStreamTruncated
+		}
+		if err != nil {
+			return err
+		}
+		if length == 0 {
+			return nil
+		}
+		if length > maxStreamChunk {
+			return errors.New("pantry: stream chunk too large")
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return errStreamTruncated
+			}
+			return err
+		}
+
+		if data, err = pantry.open(data); err != nil {
+			return err
+		}
+
+		if data, err = decompress(data); err != nil {
+			return err
+		}
+
+		var entries []savedEntry[T]
+		if err := pantry.codec.Unmarshal(data, &entries); err != nil {
+			return err
+		}
+
+		pantry.loadChunk(entries)
+	}
+}
+
+func (pantry *Pantry[T]) loadChunk(entries []savedEntry[T]) {
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	for _, entry := range entries {
+		key, _ := pantry.restoredKey(entry.Key, entry.Original)
+		shard := pantry.shardFor(key)
+
+		shard.mutex.Lock()
+		evictions = pantry.putSaved(evictions, entry, pantry.clock.Now().UnixNano())
+		shard.mutex.Unlock()
+	}
+}
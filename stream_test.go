@@ -0,0 +1,68 @@
+package pantry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSaveStream(t *testing.T) {
+	p := New[int](context.Background(), time.Hour, WithShards[int](2))
+	for i := range 5000 {
+		p.Set(strconv.Itoa(i), i)
+	}
+	p.SetWithTTL("forever", -1, NoExpiration)
+	p.SetWithTTL("expired", -2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	var buffer bytes.Buffer
+	if err := p.SaveStream(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New[int](context.Background(), time.Hour)
+	if err := restored.LoadStream(bytes.NewReader(buffer.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Count() != 5001 {
+		t.Fatal("unexpected count", restored.Count())
+	}
+	if value, _ := restored.Get("4999"); value != 4999 {
+		t.Fatal("unexpected value", value)
+	}
+	if ttl, _ := restored.TTL("forever"); ttl != NoExpiration {
+		t.Fatal("expiration lost", ttl)
+	}
+
+	truncated := New[int](context.Background(), time.Hour)
+	err := truncated.LoadStream(bytes.NewReader(buffer.Bytes()[:buffer.Len()-1]))
+	if !errors.Is(err, errStreamTruncated) {
+		t.Fatal("truncation not detected", err)
+	}
+}
+
+func TestSaveStreamEncrypted(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	p := New(context.Background(), time.Hour, WithEncryption[string](key))
+	p.Set("key", "secret")
+
+	var buffer bytes.Buffer
+	if err := p.SaveStream(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buffer.Bytes(), []byte("secret")) {
+		t.Fatal("value not encrypted")
+	}
+
+	restored := New(context.Background(), time.Hour, WithEncryption[string](key))
+	if err := restored.LoadStream(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := restored.Get("key"); value != "secret" {
+		t.Fatal("unexpected value", value)
+	}
+}
@@ -0,0 +1,113 @@
+package pantry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepable is a pantry of any value type.
+type sweepable interface {
+	removeExpired() int
+}
+
+// Sweeper cleans up many pantries from a single goroutine and ticker, see
+// WithSweeper.
+type Sweeper struct {
+	mutex    sync.Mutex
+	pantries map[sweepable]struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+	interval time.Duration
+}
+
+// NewSweeper starts a sweeper cleaning up its pantries every interval until
+// ctx is done or Close is called. The interval must be positive; a sweeper
+// with an invalid one never starts and WithSweeper rejects it like an
+// invalid option.
+func NewSweeper(ctx context.Context, interval time.Duration) *Sweeper {
+	sweeper := &Sweeper{
+		pantries: make(map[sweepable]struct{}),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		interval: interval,
+	}
+
+	if interval <= 0 {
+		close(sweeper.stopped)
+		return sweeper
+	}
+
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer close(sweeper.stopped)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				sweeper.mutex.Lock()
+				pantries := make([]sweepable, 0, len(sweeper.pantries))
+				for pantry := range sweeper.pantries {
+					pantries = append(pantries, pantry)
+				}
+				sweeper.mutex.Unlock()
+
+				for _, pantry := range pantries {
+					pantry.removeExpired()
+				}
+			case <-ctx.Done():
+				return
+			case <-sweeper.done:
+				return
+			}
+		}
+	}()
+
+	return sweeper
+}
+
+// WithSweeper leaves the cleanup to sweeper instead of a ticker of the
+// pantry's own. A pantry without write-behind then needs no goroutine at all.
+// The pantry leaves the sweeper once it is closed or its context is done.
+func WithSweeper[T any](sweeper *Sweeper) Option[T] {
+	return func(pantry *Pantry[T]) {
+		if sweeper.interval <= 0 {
+			pantry.invalidOption("sweeper interval %v must be positive", sweeper.interval)
+			return
+		}
+		pantry.sweeper = sweeper
+		pantry.cleanupInterval = 0
+	}
+}
+
+// Close stops the sweeper without closing its pantries. Closing more than
+// once is a no-op.
+func (sweeper *Sweeper) Close() {
+	sweeper.once.Do(func() { close(sweeper.done) })
+	<-sweeper.stopped
+}
+
+// Len returns the number of pantries the sweeper cleans up.
+func (sweeper *Sweeper) Len() int {
+	sweeper.mutex.Lock()
+	defer sweeper.mutex.Unlock()
+
+	return len(sweeper.pantries)
+}
+
+func (sweeper *Sweeper) add(pantry sweepable) {
+	sweeper.mutex.Lock()
+	defer sweeper.mutex.Unlock()
+
+	sweeper.pantries[pantry] = struct{}{}
+}
+
+func (sweeper *Sweeper) remove(pantry sweepable) {
+	sweeper.mutex.Lock()
+	defer sweeper.mutex.Unlock()
+
+	delete(sweeper.pantries, pantry)
+}
@@ -0,0 +1,35 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSweeper(t *testing.T) {
+	sweeper := NewSweeper(context.Background(), 10*time.Millisecond)
+	defer sweeper.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := New(ctx, time.Hour, WithSweeper[int](sweeper))
+	second := New(context.Background(), time.Hour, WithSweeper[string](sweeper))
+
+	if sweeper.Len() != 2 {
+		t.Fatal("pantries not registered", sweeper.Len())
+	}
+
+	first.SetWithTTL("key", 1, time.Millisecond)
+	second.SetWithTTL("key", "value", time.Millisecond)
+
+	eventually(t, func() bool { return first.CountRaw() == 0 && second.CountRaw() == 0 })
+
+	cancel()
+	<-first.stopped
+	if err := second.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if sweeper.Len() != 0 {
+		t.Fatal("pantries not removed", sweeper.Len())
+	}
+}
@@ -0,0 +1,74 @@
+package pantry
+
+// SetTagged stores value with the default expiration and attaches tags to
+// it, so it can be removed together with other entries by InvalidateTag.
+// Tags belong to this write; a later write to the key replaces them.
+func (pantry *Pantry[T]) SetTagged(key string, value T, tags ...string) {
+	if pantry.closed.Load() {
+		return
+	}
+
+	canonical := pantry.canonical(key)
+	key = pantry.hash(canonical)
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	stored := pantry.newItem(canonical, value, pantry.expiresAt(now, pantry.defaultTTL(key, value)))
+	stored.tags = tags
+	evictions = pantry.put(evictions, shard, key, stored, now.UnixNano())
+	evictions = pantry.writeBack(evictions, key, value, false)
+}
+
+// InvalidateTag removes every entry tagged with tag and returns how many
+// live entries were removed. Invalidation only affects the cache, not the
+// backend.
+func (pantry *Pantry[T]) InvalidateTag(tag string) int {
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	removed := 0
+	for _, shard := range pantry.shards {
+		shard.mutex.Lock()
+		now := pantry.clock.Now().UnixNano()
+		for key := range shard.tags[tag] {
+			item := shard.store[key]
+			if !pantry.isExpired(key, item, now) {
+				removed++
+			}
+			pantry.drop(shard, key)
+			evictions = pantry.evict(evictions, key, item, now, Removed)
+		}
+		shard.mutex.Unlock()
+	}
+	return removed
+}
+
+// tag and untag must be called with the shard's write lock held.
+func (shard *shard[T]) tag(key string, tags []string) {
+	for _, tag := range tags {
+		if shard.tags == nil {
+			shard.tags = make(map[string]map[string]struct{})
+		}
+		if shard.tags[tag] == nil {
+			shard.tags[tag] = make(map[string]struct{})
+		}
+		shard.tags[tag][key] = struct{}{}
+	}
+}
+
+func (shard *shard[T]) untag(key string, tags []string) {
+	for _, tag := range tags {
+		delete(shard.tags[tag], key)
+		if len(shard.tags[tag]) == 0 {
+			delete(shard.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.SetTagged("user:1", 1, "users", "admins")
+	p.SetTagged("user:2", 2, "users")
+	p.SetTagged("order:1", 3, "orders")
+
+	if removed := p.InvalidateTag("users"); removed != 2 {
+		t.Fatal("unexpected removed count", removed)
+	}
+
+	if _, found := p.Get("user:1"); found {
+		t.Fatal("user:1 not invalidated")
+	}
+
+	if _, found := p.Get("order:1"); !found {
+		t.Fatal("order:1 invalidated")
+	}
+
+	if removed := p.InvalidateTag("admins"); removed != 0 {
+		t.Fatal("stale index entry", removed)
+	}
+}
+
+func TestTagsReplaced(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.SetTagged("key", 1, "old")
+	p.Set("key", 2)
+
+	if removed := p.InvalidateTag("old"); removed != 0 {
+		t.Fatal("replaced entry still tagged")
+	}
+
+	p.SetTagged("other", 1, "tag")
+	p.Remove("other")
+
+	for _, shard := range p.shards {
+		if len(shard.tags) != 0 {
+			t.Fatal("index not cleaned up", shard.tags)
+		}
+	}
+}
+
+func TestTagsEvicted(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithMaxItems[int](1))
+	defer p.Close()
+
+	p.SetTagged("first", 1, "tag")
+	p.SetTagged("second", 2, "tag")
+
+	if removed := p.InvalidateTag("tag"); removed != 1 {
+		t.Fatal("evicted entry still tagged", removed)
+	}
+}
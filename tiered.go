@@ -0,0 +1,53 @@
+package pantry
+
+// Tiered puts a pantry in front of a slower store. Reads check the pantry
+// first and promote values found in the store, writes go to both.
+type Tiered[T any] struct {
+	l1 *Pantry[T]
+	l2 Backend[T]
+}
+
+// NewTiered composes l1 in front of l2. The pantry should not be configured
+// with a backend of its own.
+func NewTiered[T any](l1 *Pantry[T], l2 Backend[T]) *Tiered[T] {
+	return &Tiered[T]{l1: l1, l2: l2}
+}
+
+// Get returns the value from the first tier holding it, caching values
+// loaded from the second tier in the first one.
+func (tiered *Tiered[T]) Get(key string) (T, bool, error) {
+	if value, found := tiered.l1.Get(key); found {
+		return value, true, nil
+	}
+
+	value, found, err := tiered.l2.Load(key)
+	if err != nil || !found {
+		return value, false, err
+	}
+
+	tiered.l1.Set(key, value)
+	return value, true, nil
+}
+
+// Set stores the value in the second tier and, once that succeeds, in the
+// first one.
+func (tiered *Tiered[T]) Set(key string, value T) error {
+	if err := tiered.l2.Store(key, value); err != nil {
+		return err
+	}
+
+	tiered.l1.Set(key, value)
+	return nil
+}
+
+// Remove deletes the key from both tiers. The first tier is cleared even if
+// the second one fails, so it never serves a value the caller tried to drop.
+func (tiered *Tiered[T]) Remove(key string) error {
+	tiered.l1.Remove(key)
+	return tiered.l2.Delete(key)
+}
+
+// L1 returns the pantry in front.
+func (tiered *Tiered[T]) L1() *Pantry[T] {
+	return tiered.l1
+}
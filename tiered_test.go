@@ -0,0 +1,66 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTieredPromotes(t *testing.T) {
+	backend := newMapBackend()
+	backend.values["key"] = "stored"
+
+	tiered := NewTiered(New[string](context.Background(), time.Hour), backend)
+
+	if value, found, err := tiered.Get("key"); err != nil || !found || value != "stored" {
+		t.Fatal("not loaded", value, found, err)
+	}
+
+	if value, found := tiered.L1().Get("key"); !found || value != "stored" {
+		t.Fatal("not promoted")
+	}
+
+	if _, _, err := tiered.Get("key"); err != nil || backend.loads.Load() != 1 {
+		t.Fatal("second tier read again", backend.loads.Load())
+	}
+}
+
+func TestTieredWrites(t *testing.T) {
+	backend := newMapBackend()
+	tiered := NewTiered(New[string](context.Background(), time.Hour), backend)
+
+	if err := tiered.Set("key", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := tiered.L1().Get("key"); !found {
+		t.Fatal("first tier not written")
+	}
+
+	if backend.values["key"] != "value" {
+		t.Fatal("second tier not written")
+	}
+
+	if err := tiered.Remove("key"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found, _ := tiered.Get("key"); found {
+		t.Fatal("found after remove")
+	}
+}
+
+func TestTieredStoreError(t *testing.T) {
+	backend := newMapBackend()
+	backend.err = errors.New("unavailable")
+	tiered := NewTiered(New[string](context.Background(), time.Hour), backend)
+
+	if err := tiered.Set("key", "value"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if _, found := tiered.L1().Get("key"); found {
+		t.Fatal("first tier written despite error")
+	}
+}
@@ -0,0 +1,77 @@
+// Package tokencache caches OAuth 2.0 access tokens per scope in a pantry.
+// A token is kept until shortly before it expires, and concurrent requests
+// for a scope without a token share a single fetch.
+package tokencache
+
+import (
+	"context"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+// expiryDelta is how long before its expiry a token is dropped, so it is not
+// sent just as it runs out.
+const expiryDelta = 10 * time.Second
+
+// Token is an access token as returned by the token endpoint.
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type,omitempty"`
+	ExpiresIn   int64  `json:"expires_in,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+	// Expiry is when the token expires. If it is zero when the token is
+	// fetched, it is set from ExpiresIn. Tokens without either are kept until
+	// invalidated.
+	Expiry time.Time `json:"-"`
+}
+
+// FetchFunc requests a new token for scope from the token endpoint.
+type FetchFunc func(ctx context.Context, scope string) (Token, error)
+
+// Cache hands out cached tokens, fetching new ones as needed.
+type Cache struct {
+	tokens *pantry.Pantry[Token]
+}
+
+// New creates a cache fetching tokens with fetch. Options are passed to the
+// underlying pantry, e.g. to inject a clock.
+func New(ctx context.Context, fetch FetchFunc, opts ...pantry.Option[Token]) *Cache {
+	opts = append([]pantry.Option[Token]{
+		pantry.WithLoader(func(ctx context.Context, scope string) (Token, error) {
+			token, err := fetch(ctx, scope)
+			if err != nil {
+				return Token{}, err
+			}
+			if token.Expiry.IsZero() && token.ExpiresIn > 0 {
+				token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+			}
+			return token, nil
+		}),
+		pantry.WithTTLPolicy(func(_ string, token Token) time.Duration {
+			if token.Expiry.IsZero() {
+				return pantry.NoExpiration
+			}
+			return time.Until(token.Expiry) - expiryDelta
+		}),
+	}, opts...)
+
+	return &Cache{tokens: pantry.New(ctx, pantry.NoExpiration, opts...)}
+}
+
+// Token returns a valid token for scope, fetching one if there is none.
+// Fetch errors are returned as is.
+func (cache *Cache) Token(ctx context.Context, scope string) (Token, error) {
+	return cache.tokens.GetContext(ctx, scope)
+}
+
+// Invalidate drops the token of scope, for example after the server rejected
+// it, so the next call to Token fetches a new one.
+func (cache *Cache) Invalidate(scope string) {
+	cache.tokens.Remove(scope)
+}
+
+// Close stops the underlying pantry.
+func (cache *Cache) Close() error {
+	return cache.tokens.Close()
+}
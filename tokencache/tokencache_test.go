@@ -0,0 +1,65 @@
+package tokencache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	var fetches atomic.Int32
+	cache := New(context.Background(), func(_ context.Context, scope string) (Token, error) {
+		n := fetches.Add(1)
+		return Token{AccessToken: scope + strconv.Itoa(int(n)), ExpiresIn: 3600}, nil
+	})
+	defer cache.Close()
+
+	token, err := cache.Token(context.Background(), "read")
+	if err != nil || token.AccessToken != "read1" {
+		t.Fatal("unexpected token", token, err)
+	}
+	if until := time.Until(token.Expiry); until < 59*time.Minute || until > time.Hour {
+		t.Fatal("expiry not set from expires_in", token.Expiry)
+	}
+
+	if token, _ := cache.Token(context.Background(), "read"); token.AccessToken != "read1" {
+		t.Fatal("token not cached", token)
+	}
+
+	cache.Invalidate("read")
+	if token, _ := cache.Token(context.Background(), "read"); token.AccessToken != "read2" {
+		t.Fatal("token not fetched after invalidation", token)
+	}
+}
+
+func TestCacheShortLivedToken(t *testing.T) {
+	var fetches atomic.Int32
+	cache := New(context.Background(), func(context.Context, string) (Token, error) {
+		fetches.Add(1)
+		return Token{AccessToken: "token", ExpiresIn: 5}, nil
+	})
+	defer cache.Close()
+
+	cache.Token(context.Background(), "read")
+	cache.Token(context.Background(), "read")
+
+	// Tokens expiring within the safety margin are never cached.
+	if n := fetches.Load(); n != 2 {
+		t.Fatal("unexpected fetches", n)
+	}
+}
+
+func TestCacheFetchError(t *testing.T) {
+	failure := errors.New("unauthorized")
+	cache := New(context.Background(), func(context.Context, string) (Token, error) {
+		return Token{}, failure
+	})
+	defer cache.Close()
+
+	if _, err := cache.Token(context.Background(), "read"); !errors.Is(err, failure) {
+		t.Fatal("unexpected error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package pantry
+
+import "time"
+
+type tombstone[T any] struct {
+	item    item[T]
+	purgeAt int64
+}
+
+// WithTombstones makes Remove keep removed entries as tombstones for grace,
+// during which Restore brings them back. Tombstones are invisible to reads,
+// dropped when the key is set again and purged by the cleanup.
+func WithTombstones[T any](grace time.Duration) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.tombstoneGrace = grace
+	}
+}
+
+// Restore brings back an entry removed by Remove within the grace period of
+// WithTombstones, with its original value and expiration. It reports false
+// if there is no tombstone for key or the entry expired in the meantime.
+func (pantry *Pantry[T]) Restore(key string) bool {
+	if pantry.closed.Load() {
+		return false
+	}
+
+	key = pantry.normalize(key)
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now().UnixNano()
+	buried, found := shard.tombstones[key]
+	if !found || now > buried.purgeAt {
+		return false
+	}
+	delete(shard.tombstones, key)
+
+	if pantry.isExpired(key, buried.item, now) {
+		return false
+	}
+
+	buried.item.accessed = pantry.nextAccess()
+	evictions = pantry.put(evictions, shard, key, buried.item, now)
+	evictions = pantry.writeBack(evictions, key, buried.item.value, false)
+	return true
+}
+
+// bury must be called with the shard's write lock held.
+func (shard *shard[T]) bury(key string, item item[T], purgeAt int64) {
+	if shard.tombstones == nil {
+		shard.tombstones = make(map[string]tombstone[T])
+	}
+	shard.tombstones[key] = tombstone[T]{item: item, purgeAt: purgeAt}
+}
+
+// purgeTombstones must be called with the shard's write lock held.
+func (shard *shard[T]) purgeTombstones(now int64) {
+	for key, buried := range shard.tombstones {
+		if now > buried.purgeAt {
+			delete(shard.tombstones, key)
+		}
+	}
+}
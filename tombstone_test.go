@@ -0,0 +1,63 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRestore(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithTombstones[int](time.Hour))
+
+	p.Set("first", 1)
+	p.Remove("first")
+
+	if _, found := p.Get("first"); found {
+		t.Fatal("removed entry still visible")
+	}
+	if p.Count() != 0 {
+		t.Fatal("tombstone counted")
+	}
+
+	if !p.Restore("first") {
+		t.Fatal("not restored")
+	}
+	if value, _ := p.Get("first"); value != 1 {
+		t.Fatal("unexpected value", value)
+	}
+
+	if p.Restore("first") {
+		t.Fatal("restored twice")
+	}
+
+	p.Remove("first")
+	p.Set("first", 2)
+	if p.Restore("first") {
+		t.Fatal("restored over a newer value")
+	}
+}
+
+func TestRestoreAfterGrace(t *testing.T) {
+	p := New(context.Background(), time.Hour, WithTombstones[int](time.Millisecond))
+
+	p.Set("first", 1)
+	p.Remove("first")
+
+	time.Sleep(5 * time.Millisecond)
+	p.PurgeExpired()
+
+	if p.Restore("first") {
+		t.Fatal("restored after the grace period")
+	}
+}
+
+func TestRestoreWithoutTombstones(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("first", 1)
+	p.Remove("first")
+
+	if p.Restore("first") {
+		t.Fatal("restored without tombstones")
+	}
+}
@@ -0,0 +1,80 @@
+package pantry
+
+import (
+	"container/heap"
+	"slices"
+)
+
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// topHeap is a min-heap whose root is the lowest ranked entry, ties broken by
+// the lexically larger key ranking lower.
+type topHeap[N Number] []Entry[N]
+
+func (h topHeap[N]) Len() int           { return len(h) }
+func (h topHeap[N]) Less(i, j int) bool { return lowerRanked(h[i], h[j]) }
+func (h topHeap[N]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *topHeap[N]) Push(x any) {
+	*h = append(*h, x.(Entry[N]))
+}
+
+func (h *topHeap[N]) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+func lowerRanked[N Number](a, b Entry[N]) bool {
+	if a.Value != b.Value {
+		return a.Value < b.Value
+	}
+	return a.Key > b.Key
+}
+
+// TopK returns the k highest valued live entries in descending order. Equal
+// values are ordered by key.
+func TopK[N Number](p *Pantry[N], k int) []Entry[N] {
+	if k <= 0 {
+		return nil
+	}
+
+	p.rlockAll()
+	now := p.clock.Now().UnixNano()
+	top := make(topHeap[N], 0, k)
+	for key, item := range p.items() {
+		if p.isExpired(key, item, now) {
+			continue
+		}
+
+		entry := p.entry(key, item)
+
+		if len(top) < k {
+			heap.Push(&top, entry)
+			continue
+		}
+
+		if lowerRanked(top[0], entry) {
+			top[0] = entry
+			heap.Fix(&top, 0)
+		}
+	}
+	p.runlockAll()
+
+	entries := []Entry[N](top)
+	slices.SortFunc(entries, func(a, b Entry[N]) int {
+		if lowerRanked(b, a) {
+			return -1
+		}
+		if lowerRanked(a, b) {
+			return 1
+		}
+		return 0
+	})
+	return entries
+}
@@ -0,0 +1,58 @@
+package pantry
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTopK(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	for i := 0; i < 100; i++ {
+		p.Set(strconv.Itoa(i), i)
+	}
+
+	entries := TopK(p, 3)
+	if len(entries) != 3 {
+		t.Fatal("not 3 entries")
+	}
+
+	for i, expected := range []int{99, 98, 97} {
+		if entries[i].Value != expected {
+			t.Log(entries)
+			t.Fatal("wrong order")
+		}
+	}
+}
+
+func TestTopKTies(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	p.Set("c", 1)
+	p.Set("b", 1)
+	p.Set("a", 1)
+	p.Set("d", 2)
+
+	entries := TopK(p, 3)
+
+	for i, expected := range []string{"d", "a", "b"} {
+		if entries[i].Key != expected {
+			t.Log(entries)
+			t.Fatal("not deterministic")
+		}
+	}
+}
+
+func TestTopKIgnoreExpired(t *testing.T) {
+	p := New[float64](context.Background(), 10*time.Millisecond)
+
+	p.Set("first", 1.5)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if len(TopK(p, 1)) != 0 {
+		t.Fatal("not ignored")
+	}
+}
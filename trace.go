@@ -0,0 +1,33 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+)
+
+// Tracer observes the lookups of Get and its variants, the writes of Set,
+// SetWithTTL and SetWithExpireAt, and Remove, e.g. to record them as spans.
+// Start is called before the operation, "get", "set" or "remove", with the
+// context passed to GetContext, SetContext or RemoveContext, or
+// context.Background for the other methods. The returned function is called
+// once the operation is done. hit reports whether a get found the key and is
+// always false for the other operations, err is set when a backend or loader
+// failed.
+type Tracer interface {
+	Start(ctx context.Context, operation string, key string) (end func(hit bool, err error))
+}
+
+// WithTracer reports the operations to tracer.
+func WithTracer[T any](tracer Tracer) Option[T] {
+	return func(pantry *Pantry[T]) {
+		pantry.tracer = tracer
+	}
+}
+
+// operationError drops the errors reporting a plain miss.
+func operationError(err error) error {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrExpired) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package pantry
+
+import "time"
+
+// Txn buffers the reads and writes of a transaction started by Pantry.Txn.
+// Reads see the pantry as it was when the transaction started, overlaid with
+// the transaction's own writes.
+type Txn[T any] struct {
+	pantry *Pantry[T]
+	now    time.Time
+	writes map[string]txnWrite[T]
+}
+
+type txnWrite[T any] struct {
+	canonical string
+	value     T
+	ttl       time.Duration
+	removed   bool
+}
+
+// Txn runs fn with all shards locked and applies the writes it buffered in
+// one step once it returns nil, so no reader sees only part of them. If fn
+// returns an error, nothing is written and the error is returned. fn must
+// not call other methods of the pantry, as they would wait for the lock.
+func (pantry *Pantry[T]) Txn(fn func(tx *Txn[T]) error) error {
+	if pantry.closed.Load() {
+		return ErrClosed
+	}
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	pantry.lockAll()
+	defer pantry.unlockAll()
+
+	tx := &Txn[T]{
+		pantry: pantry,
+		now:    pantry.clock.Now(),
+		writes: make(map[string]txnWrite[T]),
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	evictions = tx.commit(evictions)
+	return nil
+}
+
+// Get returns the value of a live entry, or the value set earlier in the
+// transaction.
+func (tx *Txn[T]) Get(key string) (T, bool) {
+	canonical := tx.pantry.canonical(key)
+	key = tx.pantry.hash(canonical)
+
+	if write, found := tx.writes[key]; found {
+		return tx.pantry.clone(write.value), !write.removed
+	}
+
+	stored, found := tx.pantry.shardFor(key).store[key]
+	if !found || tx.pantry.isExpired(key, stored, tx.now.UnixNano()) {
+		return *new(T), false
+	}
+	if tx.pantry.verifyKeys && stored.original != "" && stored.original != canonical {
+		return *new(T), false
+	}
+	return tx.pantry.clone(stored.value), true
+}
+
+// Set stores the value with the default expiration on commit.
+func (tx *Txn[T]) Set(key string, value T) {
+	tx.SetWithTTL(key, value, defaultTTL)
+}
+
+// SetWithTTL stores the value with ttl on commit.
+func (tx *Txn[T]) SetWithTTL(key string, value T, ttl time.Duration) {
+	canonical := tx.pantry.canonical(key)
+	tx.writes[tx.pantry.hash(canonical)] = txnWrite[T]{canonical: canonical, value: value, ttl: ttl}
+}
+
+// Remove deletes the entry on commit.
+func (tx *Txn[T]) Remove(key string) {
+	tx.writes[tx.pantry.normalize(key)] = txnWrite[T]{removed: true}
+}
+
+// commit must be called with all shards locked.
+func (tx *Txn[T]) commit(evictions []eviction[T]) []eviction[T] {
+	pantry := tx.pantry
+	now := tx.now.UnixNano()
+
+	for key, write := range tx.writes {
+		shard := pantry.shardFor(key)
+
+		if write.removed {
+			if stored, found := shard.store[key]; found {
+				pantry.drop(shard, key)
+				if pantry.tombstoneGrace > 0 {
+					shard.bury(key, stored, now+int64(pantry.tombstoneGrace))
+				}
+				evictions = pantry.evict(evictions, key, stored, now, Removed)
+			}
+			evictions = pantry.writeBack(evictions, key, *new(T), true)
+			continue
+		}
+
+		ttl := write.ttl
+		if ttl == defaultTTL {
+			ttl = pantry.defaultTTL(key, write.value)
+		}
+
+		evictions = pantry.put(evictions, shard, key, pantry.newItem(write.canonical, write.value, pantry.expiresAt(tx.now, ttl)), now)
+		evictions = pantry.writeBack(evictions, key, write.value, false)
+	}
+	return evictions
+}
@@ -0,0 +1,125 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTxn(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.Set("a", 10)
+
+	err := p.Txn(func(tx *Txn[int]) error {
+		value, found := tx.Get("a")
+		if !found {
+			return ErrNotFound
+		}
+		tx.Remove("a")
+		tx.SetWithTTL("b", value, time.Minute)
+
+		if _, found := tx.Get("a"); found {
+			t.Error("removed key visible in the transaction")
+		}
+		if value, _ := tx.Get("b"); value != 10 {
+			t.Error("write not visible in the transaction", value)
+		}
+		if _, found := p.shardFor("b").store["b"]; found {
+			t.Error("write applied before commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := p.Get("a"); found {
+		t.Fatal("a not removed")
+	}
+	if value, found := p.Get("b"); !found || value != 10 {
+		t.Fatal("b not set", value, found)
+	}
+	if ttl, _ := p.TTL("b"); ttl > time.Minute {
+		t.Fatal("ttl ignored", ttl)
+	}
+}
+
+func TestTxnAbort(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.Set("a", 10)
+
+	abort := errors.New("abort")
+	err := p.Txn(func(tx *Txn[int]) error {
+		tx.Remove("a")
+		tx.Set("b", 10)
+		return abort
+	})
+	if !errors.Is(err, abort) {
+		t.Fatal("unexpected error", err)
+	}
+
+	if _, found := p.Get("a"); !found {
+		t.Fatal("aborted removal applied")
+	}
+	if _, found := p.Get("b"); found {
+		t.Fatal("aborted write applied")
+	}
+
+	p.Close()
+	if err := p.Txn(func(tx *Txn[int]) error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Fatal("unexpected error after close", err)
+	}
+}
+
+func TestTxnConcurrentMoves(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+	defer p.Close()
+
+	p.Set("a", 100)
+	p.Set("b", 100)
+
+	move := func(from, to string) {
+		p.Txn(func(tx *Txn[int]) error {
+			source, _ := tx.Get(from)
+			target, _ := tx.Get(to)
+			tx.Set(from, source-1)
+			tx.Set(to, target+1)
+			return nil
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				move("a", "b")
+			} else {
+				move("b", "a")
+			}
+
+			p.Txn(func(tx *Txn[int]) error {
+				a, _ := tx.Get("a")
+				b, _ := tx.Get("b")
+				if a+b != 200 {
+					t.Error("partial transaction visible", a, b)
+				}
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	a, _ := p.Get("a")
+	b, _ := p.Get("b")
+	if a+b != 200 || a != 100 {
+		t.Fatal("invariant broken", a, b)
+	}
+}
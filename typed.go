@@ -0,0 +1,127 @@
+package pantry
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// TypedEntry is what a TypedPantry stores for each key, keeping the original
+// key next to the value so iteration can yield it.
+type TypedEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// TypedPantry is a pantry keyed by any comparable type instead of strings.
+// It wraps a Pantry storing TypedEntry values under a string form of the
+// key, so expiration, the clock, capacity limits and the other options work
+// the same way.
+type TypedPantry[K comparable, V any] struct {
+	entries *Pantry[TypedEntry[K, V]]
+	// dynamic is set when K is an interface, whose keys of different
+	// dynamic types may share a string form.
+	dynamic bool
+}
+
+// NewTyped creates a typed pantry whose entries expire after expiration
+// unless set with another TTL. The options configure the wrapped pantry.
+func NewTyped[K comparable, V any](ctx context.Context, expiration time.Duration, options ...Option[TypedEntry[K, V]]) *TypedPantry[K, V] {
+	return &TypedPantry[K, V]{
+		entries: New(ctx, expiration, options...),
+		dynamic: reflect.TypeFor[K]().Kind() == reflect.Interface,
+	}
+}
+
+// key returns the string form of key. Strings and integers are used as they
+// are, other types in their Go syntax representation, which tells different
+// values apart. When K is an interface, the dynamic type is prepended, so the
+// string "1" and the int 1 stay different keys.
+func (pantry *TypedPantry[K, V]) key(key K) string {
+	if pantry.dynamic {
+		return fmt.Sprintf("%T:", key) + typedKey(key)
+	}
+	return typedKey(key)
+}
+
+func typedKey[K comparable](key K) string {
+	switch key := any(key).(type) {
+	case string:
+		return key
+	case int:
+		return strconv.Itoa(key)
+	case int64:
+		return strconv.FormatInt(key, 10)
+	case uint64:
+		return strconv.FormatUint(key, 10)
+	default:
+		return fmt.Sprintf("%#v", key)
+	}
+}
+
+func (pantry *TypedPantry[K, V]) Get(key K) (V, bool) {
+	entry, found := pantry.entries.Get(pantry.key(key))
+	return entry.Value, found
+}
+
+func (pantry *TypedPantry[K, V]) Set(key K, value V) {
+	pantry.entries.Set(pantry.key(key), TypedEntry[K, V]{Key: key, Value: value})
+}
+
+func (pantry *TypedPantry[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	pantry.entries.SetWithTTL(pantry.key(key), TypedEntry[K, V]{Key: key, Value: value}, ttl)
+}
+
+func (pantry *TypedPantry[K, V]) TTL(key K) (time.Duration, bool) {
+	return pantry.entries.TTL(pantry.key(key))
+}
+
+func (pantry *TypedPantry[K, V]) Remove(key K) {
+	pantry.entries.Remove(pantry.key(key))
+}
+
+func (pantry *TypedPantry[K, V]) Count() int {
+	return pantry.entries.Count()
+}
+
+func (pantry *TypedPantry[K, V]) IsEmpty() bool {
+	return pantry.entries.IsEmpty()
+}
+
+func (pantry *TypedPantry[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range pantry.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+func (pantry *TypedPantry[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, value := range pantry.All() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// All iterates over a snapshot of the live entries like Pantry.All.
+func (pantry *TypedPantry[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, entry := range pantry.entries.All() {
+			if !yield(entry.Key, entry.Value) {
+				return
+			}
+		}
+	}
+}
+
+func (pantry *TypedPantry[K, V]) Close() error {
+	return pantry.entries.Close()
+}
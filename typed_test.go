@@ -0,0 +1,126 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTypedIntKeys(t *testing.T) {
+	p := NewTyped[int64, string](context.Background(), time.Hour)
+
+	p.Set(42, "hello")
+
+	if value, found := p.Get(42); !found || value != "hello" {
+		t.Fatal("not found")
+	}
+
+	p.Remove(42)
+
+	if !p.IsEmpty() {
+		t.Fatal("not removed")
+	}
+}
+
+func TestTypedStructKeys(t *testing.T) {
+	type key struct {
+		tenant string
+		id     int
+	}
+
+	p := NewTyped[key, int](context.Background(), time.Hour)
+
+	p.Set(key{"a", 1}, 1)
+	p.Set(key{"b", 1}, 2)
+
+	if value, _ := p.Get(key{"b", 1}); value != 2 {
+		t.Fatal("wrong value")
+	}
+
+	counter := 0
+	for range p.All() {
+		counter++
+	}
+
+	if counter != 2 {
+		t.Fatal("not 2 items")
+	}
+
+	for range p.Keys() {
+		break
+	}
+
+	for range p.Values() {
+		break
+	}
+}
+
+func TestTypedInterfaceKeys(t *testing.T) {
+	p := NewTyped[any, string](context.Background(), time.Hour)
+
+	p.Set("1", "string key")
+	if _, found := p.Get(1); found {
+		t.Fatal("int key found the string key")
+	}
+
+	p.Set(1, "int key")
+	p.Set(int64(1), "int64 key")
+	for key, expected := range map[any]string{"1": "string key", 1: "int key", int64(1): "int64 key"} {
+		if value, _ := p.Get(key); value != expected {
+			t.Fatal("unexpected value", key, value)
+		}
+	}
+	if p.Count() != 3 {
+		t.Fatal("keys merged", p.Count())
+	}
+}
+
+func TestTypedExpiration(t *testing.T) {
+	p := NewTyped[int, int](context.Background(), time.Hour)
+
+	p.SetWithTTL(1, 1, time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, found := p.Get(1); found {
+		t.Fatal("found")
+	}
+
+	p.entries.PurgeExpired()
+
+	if !p.IsEmpty() {
+		t.Fatal("not cleaned")
+	}
+
+	p.SetWithTTL(2, 2, NoExpiration)
+	if _, found := p.Get(2); !found {
+		t.Fatal("entry without expiration expired")
+	}
+}
+
+func TestTypedOptions(t *testing.T) {
+	type key struct {
+		tenant string
+		id     int
+	}
+
+	clock := &testClock{}
+	p := NewTyped(context.Background(), time.Minute,
+		WithClock[TypedEntry[key, string]](clock),
+		WithMaxItems[TypedEntry[key, string]](2),
+	)
+	defer p.Close()
+
+	p.Set(key{"a", 1}, "first")
+	p.Set(key{"a", 2}, "second")
+	p.Set(key{"a:1", 0}, "third")
+
+	if count := p.Count(); count != 2 {
+		t.Fatal("capacity not enforced", count)
+	}
+
+	clock.advance(2 * time.Minute)
+	if !p.IsEmpty() {
+		t.Fatal("clock ignored")
+	}
+}
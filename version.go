@@ -0,0 +1,63 @@
+package pantry
+
+import "time"
+
+// GetWithVersion is like Get, also returning the version of the entry. Every
+// write gives the entry a new version, greater than any version handed out
+// before, so a key removed and set again never gets an old version back.
+func (pantry *Pantry[T]) GetWithVersion(key string) (T, uint64, bool) {
+	item, found := pantry.get(key)
+	return item.value, item.version, found
+}
+
+// SetIfVersion stores value with the default expiration only if the live
+// entry still has the given version, or if there is no live entry when
+// version is zero, and reports whether it did. It lets writers read,
+// modify and write back without overwriting each other.
+func (pantry *Pantry[T]) SetIfVersion(key string, value T, version uint64) bool {
+	return pantry.setIfVersion(key, value, defaultTTL, version)
+}
+
+// SetIfVersionWithTTL is like SetIfVersion, storing value with ttl.
+func (pantry *Pantry[T]) SetIfVersionWithTTL(key string, value T, ttl time.Duration, version uint64) bool {
+	return pantry.setIfVersion(key, value, ttl, version)
+}
+
+func (pantry *Pantry[T]) setIfVersion(key string, value T, ttl time.Duration, version uint64) bool {
+	if pantry.closed.Load() {
+		return false
+	}
+
+	canonical := pantry.canonical(key)
+	key = pantry.hash(canonical)
+	shard := pantry.shardFor(key)
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	current, found := shard.store[key]
+	live := found && !pantry.isExpired(key, current, now.UnixNano())
+
+	switch {
+	case version == 0 && live:
+		return false
+	case version != 0 && (!live || current.version != version):
+		return false
+	case version != 0 && pantry.verifyKeys && current.original != "" && current.original != canonical:
+		return false
+	}
+
+	if ttl == defaultTTL {
+		ttl = pantry.defaultTTL(key, value)
+	}
+
+	evictions = pantry.put(evictions, shard, key, pantry.newItem(canonical, value, pantry.expiresAt(now, ttl)), now.UnixNano())
+	evictions = pantry.writeBack(evictions, key, value, false)
+	return true
+}
@@ -0,0 +1,53 @@
+package pantry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetIfVersion(t *testing.T) {
+	p := New[string](context.Background(), time.Hour)
+	defer p.Close()
+
+	if !p.SetIfVersion("key", "first", 0) {
+		t.Fatal("not stored on an absent key")
+	}
+	if p.SetIfVersion("key", "again", 0) {
+		t.Fatal("stored over a live entry with version zero")
+	}
+
+	value, version, found := p.GetWithVersion("key")
+	if !found || value != "first" || version == 0 {
+		t.Fatal("unexpected entry", value, version, found)
+	}
+
+	if !p.SetIfVersion("key", "second", version) {
+		t.Fatal("not stored with the current version")
+	}
+	if p.SetIfVersion("key", "stale", version) {
+		t.Fatal("stored with a stale version")
+	}
+
+	value, next, _ := p.GetWithVersion("key")
+	if value != "second" || next <= version {
+		t.Fatal("version not advanced", value, version, next)
+	}
+
+	p.Remove("key")
+	if p.SetIfVersion("key", "gone", next) {
+		t.Fatal("stored on a removed entry")
+	}
+
+	p.Set("key", "third")
+	if _, latest, _ := p.GetWithVersion("key"); latest <= next {
+		t.Fatal("version reused after remove", next, latest)
+	}
+
+	if !p.SetIfVersionWithTTL("other", "value", time.Minute, 0) {
+		t.Fatal("not stored with ttl")
+	}
+	if ttl, _ := p.TTL("other"); ttl > time.Minute {
+		t.Fatal("ttl not applied", ttl)
+	}
+}
@@ -0,0 +1,358 @@
+package pantry
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type walOp uint8
+
+const (
+	walSet walOp = iota
+	walRemove
+	walClear
+)
+
+// walRecord holds the stored key and, for writes, the canonical key the
+// entry remembers, if any, as in persistedItem.
+type walRecord[T any] struct {
+	Op       walOp
+	Key      string
+	Value    T
+	Expires  int64
+	Original string
+}
+
+type writeAheadLog[T any] struct {
+	mutex   sync.Mutex
+	path    string
+	file    *os.File
+	encoder *gob.Encoder
+	aead    cipher.AEAD
+	closed  bool
+	err     error
+}
+
+const defaultCompactionInterval = time.Minute
+
+// NewWithWAL creates a pantry that appends every write and removal to the log
+// at path. The state found at path is recovered first, and the log is
+// periodically compacted into a snapshot next to it.
+func NewWithWAL[T any](ctx context.Context, expiration time.Duration, path string, options ...Option[T]) (*Pantry[T], error) {
+	pantry := New(ctx, expiration, options...)
+
+	if err := pantry.Recover(path); err != nil {
+		return pantry, err
+	}
+
+	// Nothing is logged yet, so the recovered state can replace the old
+	// snapshot and logs before the new log is started.
+	pantry.rlockAll()
+	entries := pantry.snapshotEntries()
+	pantry.runlockAll()
+
+	if err := pantry.writeSnapshotFile(path, entries); err != nil {
+		return pantry, err
+	}
+
+	if err := os.Remove(rotatedPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return pantry, err
+	}
+
+	wal := &writeAheadLog[T]{path: path, aead: pantry.aead}
+	if err := wal.open(); err != nil {
+		return pantry, err
+	}
+
+	pantry.lockAll()
+	pantry.wal = wal
+	pantry.unlockAll()
+
+	interval := pantry.compactionInterval
+	if interval <= 0 {
+		interval = defaultCompactionInterval
+	}
+
+	ticker := pantry.clock.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C():
+				if err := pantry.Compact(); err != nil {
+					pantry.warn("pantry: compaction failed", err)
+				}
+			case <-pantry.stopped:
+				return
+			}
+		}
+	}()
+
+	return pantry, nil
+}
+
+// Recover loads the snapshot written next to path by compaction and replays
+// the log at path on top of it. A record cut short by a crash ends the
+// replay without an error.
+func (pantry *Pantry[T]) Recover(path string) error {
+	snapshot, err := os.Open(snapshotPath(path))
+	switch {
+	case err == nil:
+		err = pantry.ImportSnapshot(snapshot)
+		snapshot.Close()
+		if err != nil {
+			return err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	for _, log := range []string{rotatedPath(path), path} {
+		if err := pantry.replay(log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pantry *Pantry[T]) replay(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if pantry.aead != nil {
+		reader = &openingReader{r: file, aead: pantry.aead}
+	}
+
+	defer pantry.enforceCapacity()
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	decoder := gob.NewDecoder(reader)
+	for {
+		var record walRecord[T]
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("pantry: replaying %s: %w", path, err)
+		}
+
+		switch record.Op {
+		case walSet, walRemove:
+			evictions = pantry.replayRecord(evictions, record)
+		case walClear:
+			notify(evictions)
+			evictions = nil
+			pantry.Clear()
+		}
+	}
+}
+
+// replayRecord applies a logged write or removal as it was logged. Writes
+// keep their logged expiration instead of getting the TTL options applied
+// again, and nothing is written back, as the log only replays what the
+// pantry already did.
+func (pantry *Pantry[T]) replayRecord(evictions []eviction[T], record walRecord[T]) []eviction[T] {
+	key, canonical := pantry.restoredKey(record.Key, record.Original)
+	shard := pantry.shardFor(key)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now().UnixNano()
+	if record.Op == walSet && now <= record.Expires {
+		return pantry.put(evictions, shard, key, pantry.newItem(canonical, record.Value, record.Expires), now)
+	}
+
+	if item, found := shard.store[key]; found {
+		pantry.drop(shard, key)
+		evictions = pantry.evict(evictions, key, item, now, Removed)
+	}
+	return evictions
+}
+
+// Compact writes the current state to the snapshot next to the log and
+// starts a new log. Writes are only blocked while the log is rotated.
+//
+// Replaying a log over a snapshot taken at any point after the log started
+// yields the same state, because every record overwrites what came before.
+// The previous log is therefore kept until the snapshot is written.
+func (pantry *Pantry[T]) Compact() error {
+	if pantry.wal == nil {
+		return nil
+	}
+
+	wal := pantry.wal
+
+	pantry.lockAll()
+	entries := pantry.snapshotEntries()
+	err := wal.rotate()
+	pantry.unlockAll()
+
+	if err != nil {
+		return err
+	}
+
+	if err := pantry.writeSnapshotFile(wal.path, entries); err != nil {
+		return err
+	}
+
+	if err := os.Remove(rotatedPath(wal.path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// rotate must be called with all shards locked. When the previous log is
+// still around because its snapshot failed, the current log is kept going
+// instead so both stay available for recovery.
+func (wal *writeAheadLog[T]) rotate() error {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	if wal.closed {
+		return ErrClosed
+	}
+
+	if _, err := os.Stat(rotatedPath(wal.path)); err == nil {
+		return nil
+	}
+
+	if wal.file != nil {
+		wal.file.Close()
+		wal.file, wal.encoder = nil, nil
+	}
+
+	if err := os.Rename(wal.path, rotatedPath(wal.path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return wal.open()
+}
+
+// open starts a new log, as a gob stream cannot be appended to once closed.
+func (wal *writeAheadLog[T]) open() error {
+	file, err := os.OpenFile(wal.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		wal.file, wal.encoder = nil, nil
+		return err
+	}
+
+	var writer io.Writer = file
+	if wal.aead != nil {
+		writer = &sealingWriter{w: file, aead: wal.aead}
+	}
+
+	wal.file = file
+	wal.encoder = gob.NewEncoder(writer)
+	return nil
+}
+
+// append keeps the first error to return it from close, and returns it when
+// it happens. A gob stream cannot be trusted after a failed encode, so
+// nothing is appended after that until compaction starts a new log.
+func (wal *writeAheadLog[T]) append(record walRecord[T]) error {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	if wal.encoder == nil {
+		return nil
+	}
+
+	err := wal.encoder.Encode(record)
+	if err != nil {
+		wal.encoder = nil
+		if wal.err == nil {
+			wal.err = err
+		}
+	}
+	return err
+}
+
+// sync commits the log to disk.
+func (wal *writeAheadLog[T]) sync() error {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	if wal.file == nil {
+		return nil
+	}
+	return wal.file.Sync()
+}
+
+func (wal *writeAheadLog[T]) close() error {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	wal.closed = true
+
+	var err error
+	if wal.file != nil {
+		err = wal.file.Close()
+		wal.file, wal.encoder = nil, nil
+	}
+	return errors.Join(wal.err, err)
+}
+
+// logSet, logRemove and logClear must be called with the affected shards
+// locked so the log follows the order of the writes.
+func (pantry *Pantry[T]) logSet(key string, item item[T]) {
+	if pantry.wal != nil {
+		pantry.logAppend(walRecord[T]{
+			Op:       walSet,
+			Key:      key,
+			Value:    item.value,
+			Expires:  item.expires,
+			Original: item.original,
+		})
+	}
+}
+
+func (pantry *Pantry[T]) logRemove(key string) {
+	if pantry.wal != nil {
+		pantry.logAppend(walRecord[T]{Op: walRemove, Key: key})
+	}
+}
+
+func (pantry *Pantry[T]) logClear() {
+	if pantry.wal != nil {
+		pantry.logAppend(walRecord[T]{Op: walClear})
+	}
+}
+
+func (pantry *Pantry[T]) logAppend(record walRecord[T]) {
+	if err := pantry.wal.append(record); err != nil {
+		pantry.warn("pantry: write-ahead log append failed", err)
+	}
+}
+
+func snapshotPath(path string) string {
+	return path + ".snapshot"
+}
+
+func rotatedPath(path string) string {
+	return path + ".old"
+}
+
+func (pantry *Pantry[T]) writeSnapshotFile(path string, entries []snapshotEntry[T]) error {
+	return writeFileAtomic(snapshotPath(path), func(w io.Writer) error {
+		return pantry.writeSnapshot(w, entries)
+	})
+}
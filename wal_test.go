@@ -0,0 +1,243 @@
+package pantry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pantry.wal")
+
+	p, err := NewWithWAL[string](context.Background(), time.Hour, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Set("first", "hello")
+	p.Set("second", "world")
+	p.Set("third", "gone")
+	p.Remove("third")
+	p.SetWithTTL("short", "value", time.Millisecond)
+
+	// Simulate a crash by recovering without closing.
+	restored := New[string](context.Background(), time.Hour)
+	defer restored.Close()
+
+	time.Sleep(2 * time.Millisecond)
+
+	if err := restored.Recover(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != "hello" {
+		t.Fatal("first not recovered")
+	}
+
+	if value, _ := restored.Get("second"); value != "world" {
+		t.Fatal("second not recovered")
+	}
+
+	if _, found := restored.Get("third"); found {
+		t.Fatal("removal not recovered")
+	}
+
+	if _, found := restored.Get("short"); found {
+		t.Fatal("expired entry recovered")
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWALCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pantry.wal")
+
+	p, err := NewWithWAL[int](context.Background(), time.Hour, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 100 {
+		p.Set("counter", i)
+	}
+
+	before, _ := os.Stat(path)
+
+	if err := p.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Fatal("log not compacted", before.Size(), after.Size())
+	}
+
+	p.Clear()
+	p.Set("other", 1)
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewWithWAL[int](context.Background(), time.Hour, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+
+	if _, found := restored.Get("counter"); found {
+		t.Fatal("clear not recovered")
+	}
+
+	if value, _ := restored.Get("other"); value != 1 {
+		t.Fatal("other not recovered")
+	}
+}
+
+func TestWALTruncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pantry.wal")
+
+	p, err := NewWithWAL[string](context.Background(), time.Hour, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Set("first", "hello")
+	p.Set("second", "world")
+	p.Close()
+
+	info, _ := os.Stat(path)
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New[string](context.Background(), time.Hour)
+	defer restored.Close()
+
+	if err := restored.Recover(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != "hello" {
+		t.Fatal("first not recovered")
+	}
+}
+
+func TestWALWithKeyHasher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pantry.wal")
+	hasher := WithKeyHasher[string](func(key string) string { return "#" + key }, false)
+
+	p, err := NewWithWAL(context.Background(), time.Hour, path, hasher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Set("first", "hello")
+	p.Set("second", "world")
+	p.Remove("second")
+
+	restored := New(context.Background(), time.Hour, hasher)
+	defer restored.Close()
+
+	if err := restored.Recover(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := restored.Get("first"); value != "hello" {
+		t.Fatal("first not recovered")
+	}
+	if _, found := restored.Get("second"); found {
+		t.Fatal("removed entry recovered")
+	}
+}
+
+func TestWALTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pantry.wal")
+
+	p, err := NewWithWAL[string](context.Background(), time.Hour, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Set("key", "old")
+	p.Transaction(func(store map[string]string) {
+		store["key"] = "new"
+	})
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New[string](context.Background(), time.Hour)
+	defer restored.Close()
+	if err := restored.Recover(path); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := restored.Get("key"); value != "new" {
+		t.Fatal("transaction not recovered", value)
+	}
+}
+
+func TestWALReplayKeepsLoggedState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pantry.wal")
+
+	p, err := NewWithWAL[string](context.Background(), time.Hour, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetWithTTL("first", "hello", 10*time.Hour)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := newMapBackend()
+	restored := New(context.Background(), time.Hour,
+		WithTTLBounds[string](time.Minute, 2*time.Hour),
+		WithTTLJitter[string](0.5),
+		WithWriteThrough[string](backend),
+	)
+	defer restored.Close()
+
+	if err := restored.Recover(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl, _ := restored.TTL("first"); ttl <= 9*time.Hour {
+		t.Fatal("logged expiration not kept", ttl)
+	}
+	if len(backend.values) != 0 {
+		t.Fatal("replay written back", backend.values)
+	}
+}
+
+func TestWALStopsAfterEncodeError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pantry.wal")
+
+	p, err := NewWithWAL[any](context.Background(), time.Hour, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Set("first", "hello")
+	p.Set("broken", func() {})
+	p.Set("second", "world")
+	if err := p.Close(); err == nil {
+		t.Fatal("encode error not reported")
+	}
+
+	restored := New[any](context.Background(), time.Hour)
+	defer restored.Close()
+
+	if err := restored.Recover(path); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := restored.Get("first"); value != "hello" {
+		t.Fatal("first not recovered")
+	}
+	if _, found := restored.Get("second"); found {
+		t.Fatal("appended after the encode error")
+	}
+}
@@ -0,0 +1,96 @@
+package pantry
+
+import (
+	"context"
+	"iter"
+	"os"
+)
+
+// warmProgressInterval is the number of entries between two progress reports.
+const warmProgressInterval = 1000
+
+// OnWarmProgress registers a hook called with the number of entries loaded
+// so far every 1000 entries during Warm and once when it ends.
+func (pantry *Pantry[T]) OnWarmProgress(fn func(loaded int)) {
+	if fn == nil {
+		pantry.onWarmProgress.Store(nil)
+		return
+	}
+	pantry.onWarmProgress.Store(&fn)
+}
+
+// Warm stores every entry of seed with the default expiration, for example
+// before the pantry is handed to traffic so a cold start does not hit the
+// backend. The entries are not written to the backend. Warm stops early
+// with ctx.Err() once ctx is done, keeping what it loaded, and returns the
+// number of entries loaded.
+func (pantry *Pantry[T]) Warm(ctx context.Context, seed iter.Seq2[string, T]) (int, error) {
+	if pantry.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	defer pantry.enforceCapacity()
+
+	loaded := 0
+	defer pantry.reportWarmProgress(&loaded)
+
+	for key, value := range seed {
+		if err := ctx.Err(); err != nil {
+			return loaded, err
+		}
+
+		pantry.warm(key, value)
+		loaded++
+
+		if loaded%warmProgressInterval == 0 {
+			pantry.reportWarmProgress(&loaded)
+		}
+	}
+	return loaded, nil
+}
+
+// WarmFromSnapshot warms the pantry with the entries of the snapshot file at
+// path written by ExportSnapshot. The entries get the default expiration
+// instead of their saved one, entries that already expired are skipped.
+func (pantry *Pantry[T]) WarmFromSnapshot(ctx context.Context, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	entries, err := pantry.readSnapshot(file)
+	if err != nil {
+		return 0, err
+	}
+
+	now := pantry.clock.Now().UnixNano()
+	return pantry.Warm(ctx, func(yield func(string, T) bool) {
+		for _, entry := range entries {
+			if now <= entry.Expires && !yield(entry.Key, entry.Value) {
+				return
+			}
+		}
+	})
+}
+
+func (pantry *Pantry[T]) warm(key string, value T) {
+	canonical := pantry.canonical(key)
+	key = pantry.hash(canonical)
+	shard := pantry.shardFor(key)
+
+	var evictions []eviction[T]
+	defer func() { notify(evictions) }()
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	now := pantry.clock.Now()
+	evictions = pantry.put(evictions, shard, key, pantry.newItem(canonical, value, pantry.expiresAt(now, pantry.defaultTTL(key, value))), now.UnixNano())
+}
+
+func (pantry *Pantry[T]) reportWarmProgress(loaded *int) {
+	if progress := pantry.onWarmProgress.Load(); progress != nil {
+		(*progress)(*loaded)
+	}
+}
@@ -0,0 +1,85 @@
+package pantry
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWarm(t *testing.T) {
+	backend := newMapBackend()
+	p := New(context.Background(), time.Hour, WithWriteThrough[string](backend))
+
+	var reports []int
+	p.OnWarmProgress(func(loaded int) { reports = append(reports, loaded) })
+
+	seed := make(map[string]string)
+	for i := range 2500 {
+		seed[strconv.Itoa(i)] = "value"
+	}
+
+	loaded, err := p.Warm(context.Background(), maps.All(seed))
+	if err != nil || loaded != 2500 {
+		t.Fatal("unexpected result", loaded, err)
+	}
+	if p.Count() != 2500 {
+		t.Fatal("unexpected count", p.Count())
+	}
+	if ttl, _ := p.TTL("42"); ttl < 59*time.Minute {
+		t.Fatal("default expiration not applied", ttl)
+	}
+	if len(backend.values) != 0 {
+		t.Fatal("warmed entries written to the backend")
+	}
+	if len(reports) != 3 || reports[0] != 1000 || reports[2] != 2500 {
+		t.Fatal("unexpected progress reports", reports)
+	}
+}
+
+func TestWarmCancel(t *testing.T) {
+	p := New[int](context.Background(), time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	loaded, err := p.Warm(ctx, func(yield func(string, int) bool) {
+		for i := 0; ; i++ {
+			if i == 10 {
+				cancel()
+			}
+			if !yield(strconv.Itoa(i), i) {
+				return
+			}
+		}
+	})
+	if !errors.Is(err, context.Canceled) || loaded != 10 || p.Count() != 10 {
+		t.Fatal("unexpected result", loaded, err, p.Count())
+	}
+}
+
+func TestWarmFromSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot")
+
+	source := New[string](context.Background(), time.Hour)
+	source.SetWithTTL("key", "value", time.Minute)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.ExportSnapshot(file); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	p := New[string](context.Background(), time.Hour)
+	loaded, err := p.WarmFromSnapshot(context.Background(), path)
+	if err != nil || loaded != 1 {
+		t.Fatal("unexpected result", loaded, err)
+	}
+	if ttl, _ := p.TTL("key"); ttl < 59*time.Minute {
+		t.Fatal("saved expiration kept", ttl)
+	}
+}
@@ -0,0 +1,44 @@
+// Package zstdcodec provides a Zstandard pantry.Compression. Importing it
+// registers the compression, so data compressed with it can be read by any
+// pantry.
+package zstdcodec
+
+import (
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/webermarci/pantry"
+)
+
+func init() {
+	pantry.RegisterCompression(Compression{})
+}
+
+// The encoder and decoder are safe for concurrent use of EncodeAll and
+// DecodeAll, and costly to create.
+var (
+	encoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	decoder = sync.OnceValues(func() (*zstd.Decoder, error) { return zstd.NewReader(nil) })
+)
+
+type Compression struct{}
+
+func (Compression) Magic() []byte {
+	return []byte{0x28, 0xb5, 0x2f, 0xfd}
+}
+
+func (Compression) Compress(data []byte) ([]byte, error) {
+	encoder, err := encoder()
+	if err != nil {
+		return nil, err
+	}
+	return encoder.EncodeAll(data, nil), nil
+}
+
+func (Compression) Decompress(data []byte) ([]byte, error) {
+	decoder, err := decoder()
+	if err != nil {
+		return nil, err
+	}
+	return decoder.DecodeAll(data, nil)
+}
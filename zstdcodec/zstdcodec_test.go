@@ -0,0 +1,37 @@
+package zstdcodec
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webermarci/pantry"
+)
+
+func TestCompression(t *testing.T) {
+	value := strings.Repeat("compressible ", 1000)
+
+	p := pantry.New(context.Background(), time.Hour, pantry.WithCompression[string](Compression{}))
+	defer p.Close()
+	p.Set("first", value)
+
+	var buffer bytes.Buffer
+	if err := p.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if buffer.Len() >= len(value) {
+		t.Fatal("not compressed", buffer.Len())
+	}
+
+	// Reading detects the compression regardless of the setting.
+	restored := pantry.New[string](context.Background(), time.Hour)
+	defer restored.Close()
+	if err := restored.Load(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := restored.Get("first"); stored != value {
+		t.Fatal("not restored")
+	}
+}